@@ -0,0 +1,83 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+type aboutFlags struct {
+	List bool `flag:"--list  only list the names of the third-party notices"`
+}
+
+// NewAboutCmd returns a sub command that displays the program's license
+// followed by the third-party notices found in notices.  Each regular file in
+// notices is a single notice.  Normally notices is an embed.FS:
+//
+//	//go:embed LICENSE
+//	var license string
+//
+//	//go:embed notices
+//	var notices embed.FS
+//
+//	var aboutCmd = commander.NewAboutCmd(license, notices)
+//
+// Either license or notices may be empty.
+func NewAboutCmd(license string, notices fs.FS) *Command {
+	return &Command{
+		Name:     "about",
+		Help:     "display the license and third-party notices",
+		MaxArgs:  NoArgs,
+		Defaults: &aboutFlags{},
+		Func: func(ctx context.Context, c *Command, _ []string, _ ...any) error {
+			opts := c.Flags.(*aboutFlags)
+			w := c.stdout()
+			var names []string
+			if notices != nil {
+				err := fs.WalkDir(notices, ".", func(path string, d fs.DirEntry, err error) error {
+					if err == nil && d.Type().IsRegular() {
+						names = append(names, path)
+					}
+					return err
+				})
+				if err != nil {
+					return err
+				}
+			}
+			if opts.List {
+				for _, name := range names {
+					fmt.Fprintln(w, name)
+				}
+				return nil
+			}
+			sep := ""
+			if license = strings.TrimSpace(license); license != "" {
+				fmt.Fprintf(w, "%s\n", license)
+				sep = "\n"
+			}
+			for _, name := range names {
+				data, err := fs.ReadFile(notices, name)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(w, "%s==> %s <==\n%s\n", sep, name, strings.TrimSpace(string(data)))
+				sep = "\n"
+			}
+			return nil
+		},
+	}
+}
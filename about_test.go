@@ -0,0 +1,55 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"testing/fstest"
+)
+
+func TestAbout(t *testing.T) {
+	notices := fstest.MapFS{
+		"notices/flags/LICENSE":  {Data: []byte("flags license\n")},
+		"notices/indent/LICENSE": {Data: []byte("indent license\n")},
+	}
+	var out bytes.Buffer
+	root := &Command{
+		Name:        "prog",
+		Stdout:      &out,
+		SubCommands: []*Command{NewAboutCmd("\nMy license\n", notices)},
+	}
+	if err := root.Run(context.Background(), []string{"about"}); err != nil {
+		t.Fatal(err)
+	}
+	want := `
+My license
+
+==> notices/flags/LICENSE <==
+flags license
+
+==> notices/indent/LICENSE <==
+indent license
+`[1:]
+	if got := out.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	out.Reset()
+	root.Run(context.Background(), []string{"about", "--list"})
+	want = "notices/flags/LICENSE\nnotices/indent/LICENSE\n"
+	if got := out.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
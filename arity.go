@@ -0,0 +1,173 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseArity parses an arity specification, as used by the Arity field of a
+// Command, and returns the minimum and maximum number of positional
+// parameters it allows.  A maximum of -1 means there is no upper limit.  The
+// forms of an arity specification are:
+//
+//	N     - exactly N parameters
+//	N..M  - between N and M parameters, inclusive
+//	N..   - at least N parameters
+func ParseArity(s string) (min, max int, err error) {
+	lo, hi, isRange := strings.Cut(s, "..")
+	if min, err = parseArityBound(lo); err != nil {
+		return 0, 0, fmt.Errorf("invalid arity %q", s)
+	}
+	switch {
+	case !isRange:
+		return min, min, nil
+	case hi == "":
+		return min, -1, nil
+	}
+	if max, err = parseArityBound(hi); err != nil || max < min {
+		return 0, 0, fmt.Errorf("invalid arity %q", s)
+	}
+	return min, max, nil
+}
+
+// parseArityBound parses a single non-negative bound of an arity.
+func parseArityBound(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 || s[0] == '+' {
+		return 0, strconv.ErrSyntax
+	}
+	return n, nil
+}
+
+// arity returns the minimum and maximum number of positional parameters c
+// accepts, taken from Arity if set and otherwise from MinArgs and MaxArgs.  A
+// maximum of -1 means there is no upper limit.
+func (c *Command) arity() (min, max int, err error) {
+	if c.Arity != "" {
+		return ParseArity(c.Arity)
+	}
+	switch c.MaxArgs {
+	case NoArgs:
+		return c.MinArgs, 0, nil
+	case 0:
+		return c.MinArgs, -1, nil
+	}
+	return c.MinArgs, c.MaxArgs, nil
+}
+
+// checkArgs returns a *UsageError if args does not have an acceptable number
+// of positional parameters for c.  If c declares its Parameters the error
+// includes them.
+func (c *Command) checkArgs(args []string) error {
+	var err error
+	if c.Arity == "" {
+		err = c.checkMinMaxArgs(args)
+	} else {
+		err = c.checkArity(args)
+	}
+	ue, ok := err.(*UsageError)
+	if !ok || c.Parameters == "" {
+		return err
+	}
+	ue.Err = c.errorf("%v; usage: %s %s", ue.Err, c.Name, c.Parameters)
+	return ue
+}
+
+// checkArity implements checkArgs for commands that use Arity.
+func (c *Command) checkArity(args []string) error {
+	min, max, err := c.arity()
+	if err != nil {
+		return &UsageError{C: c, Err: err}
+	}
+	n := len(args)
+	if n >= min && (max < 0 || n <= max) {
+		return nil
+	}
+	got := gotArgs(args)
+	switch {
+	case max == 0:
+		err = c.errorf("expected no arguments, got %s", got)
+	case min == max:
+		err = c.errorf("expected %d arguments, got %s", min, got)
+	case max < 0:
+		err = c.errorf("expected at least %d arguments, got %s", min, got)
+	case min == 0:
+		err = c.errorf("expected at most %d arguments, got %s", max, got)
+	default:
+		err = c.errorf("expected between %d and %d arguments, got %s", min, max, got)
+	}
+	return &UsageError{C: c, Err: err}
+}
+
+// checkMinMaxArgs implements checkArgs for commands that use MinArgs and
+// MaxArgs rather than Arity.
+func (c *Command) checkMinMaxArgs(args []string) error {
+	if c.MaxArgs == NoArgs && len(args) != 0 {
+		return &UsageError{
+			C:   c,
+			Err: c.errorf("takes no arguments, got %s", gotArgs(args)),
+		}
+	}
+	if len(args) < c.MinArgs {
+		return &UsageError{
+			C:   c,
+			Err: c.errorf("requires at least %d arguments, got %s", c.MinArgs, gotArgs(args)),
+		}
+	}
+	if c.MaxArgs > 0 && len(args) > c.MaxArgs {
+		return &UsageError{
+			C:   c,
+			Err: c.errorf("takes no more than %d arguments, got %s", c.MaxArgs, gotArgs(args)),
+		}
+	}
+	return nil
+}
+
+// Limits used by gotArgs to keep error messages readable.
+const (
+	maxShownArgs   = 4  // maximum number of arguments shown
+	maxShownArgLen = 20 // maximum length of a shown argument, in runes
+)
+
+// gotArgs returns the number of args followed by a summary of args, such as
+// 2: ["a" "b"], for use in error messages.  Long lists and long arguments
+// are truncated.
+func gotArgs(args []string) string {
+	if len(args) == 0 {
+		return "0"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d: [", len(args))
+	for i, arg := range args {
+		if i == maxShownArgs {
+			b.WriteString(" ...")
+			break
+		}
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		if r := []rune(arg); len(r) > maxShownArgLen {
+			arg = string(r[:maxShownArgLen-3]) + "..."
+		}
+		b.WriteString(strconv.Quote(arg))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
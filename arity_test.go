@@ -0,0 +1,145 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParseArity(t *testing.T) {
+	for _, tt := range []struct {
+		in       string
+		min, max int
+		err      bool
+	}{
+		{in: "0", min: 0, max: 0},
+		{in: "2", min: 2, max: 2},
+		{in: "1..3", min: 1, max: 3},
+		{in: "2..", min: 2, max: -1},
+		{in: "0..0", min: 0, max: 0},
+		{in: "", err: true},
+		{in: "..3", err: true},
+		{in: "3..1", err: true},
+		{in: "-1", err: true},
+		{in: "+1", err: true},
+		{in: "1..x", err: true},
+		{in: "one", err: true},
+	} {
+		min, max, err := ParseArity(tt.in)
+		switch {
+		case tt.err && err == nil:
+			t.Errorf("ParseArity(%q) did not fail", tt.in)
+		case !tt.err && err != nil:
+			t.Errorf("ParseArity(%q): %v", tt.in, err)
+		case !tt.err && (min != tt.min || max != tt.max):
+			t.Errorf("ParseArity(%q) got %d, %d, want %d, %d", tt.in, min, max, tt.min, tt.max)
+		}
+	}
+}
+
+func TestArity(t *testing.T) {
+	for _, tt := range []struct {
+		arity  string
+		args   []string
+		err    string
+		params string
+	}{
+		{arity: "0", args: nil},
+		{arity: "0", args: []string{"a"}, err: `test: expected no arguments, got 1: ["a"]`},
+		{arity: "2", args: []string{"a", "b"}, params: "arg0 arg1"},
+		{arity: "2", args: []string{"a"}, err: `test: expected 2 arguments, got 1: ["a"]`},
+		{arity: "1..3", args: []string{"a", "b", "c"}, params: "arg0 ..."},
+		{arity: "1..3", args: []string{"a", "b", "c", "d", "e"}, err: `test: expected between 1 and 3 arguments, got 5: ["a" "b" "c" "d" ...]`},
+		{arity: "0..2", args: []string{"a", "b", "c"}, err: `test: expected at most 2 arguments, got 3: ["a" "b" "c"]`},
+		{arity: "2..", args: []string{"a"}, err: `test: expected at least 2 arguments, got 1: ["a"]`},
+		{arity: "2..", args: []string{"a", "b", "c", "d"}, params: "arg0 arg1 ..."},
+		{arity: "x", args: nil, err: `test: invalid arity "x"`},
+	} {
+		cmd := &Command{
+			Name:   "test",
+			Arity:  tt.arity,
+			Stderr: &output,
+			Func:   func(context.Context, *Command, []string, ...any) error { return nil },
+		}
+		err := cmd.Run(context.Background(), tt.args)
+		switch _, usage := err.(*UsageError); {
+		case tt.err == "" && err != nil:
+			t.Errorf("%s %q: %v", tt.arity, tt.args, err)
+		case tt.err != "" && (err == nil || err.Error() != tt.err):
+			t.Errorf("%s %q: got error %v, want %s", tt.arity, tt.args, err, tt.err)
+		case tt.err != "" && !usage:
+			t.Errorf("%s %q: got %T, want a *UsageError", tt.arity, tt.args, err)
+		}
+		if got := cmd.parameters(); tt.params != "" && got != tt.params {
+			t.Errorf("%s: got parameters %q, want %q", tt.arity, got, tt.params)
+		}
+	}
+}
+
+func TestMinMaxArgsParameters(t *testing.T) {
+	for _, tt := range []struct {
+		min, max int
+		want     string
+	}{
+		{0, NoArgs, ""},
+		{0, 0, "..."},
+		{1, 1, "arg0"},
+		{1, 3, "arg0"},
+		{2, 0, "arg0 arg1 ..."},
+		{2, 1, "arg0 arg1 ..."},
+	} {
+		cmd := &Command{MinArgs: tt.min, MaxArgs: tt.max}
+		if got := cmd.parameters(); got != tt.want {
+			t.Errorf("MinArgs %d MaxArgs %d: got %q, want %q", tt.min, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestGotArgs(t *testing.T) {
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{nil, "0"},
+		{[]string{""}, `1: [""]`},
+		{[]string{"a b", `"`}, `2: ["a b" "\""]`},
+		{[]string{"1", "2", "3", "4", "5", "6"}, `6: ["1" "2" "3" "4" ...]`},
+		{[]string{"abcdefghijklmnopqrstuvwxyz"}, `1: ["abcdefghijklmnopq..."]`},
+	} {
+		if got := gotArgs(tt.args); got != tt.want {
+			t.Errorf("gotArgs(%q) got %s, want %s", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestArgsUsage(t *testing.T) {
+	cmd := &Command{
+		Name:       "cp",
+		Parameters: "SRC DST",
+		Arity:      "2",
+		Stderr:     &output,
+		Func:       func(context.Context, *Command, []string, ...any) error { return nil },
+	}
+	err := cmd.Run(context.Background(), []string{"a"})
+	if want := `cp: expected 2 arguments, got 1: ["a"]; usage: cp SRC DST`; err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %s", err, want)
+	}
+	cmd.Arity = ""
+	cmd.MinArgs = 2
+	err = cmd.Run(context.Background(), []string{"a"})
+	if want := `cp: requires at least 2 arguments, got 1: ["a"]; usage: cp SRC DST`; err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %s", err, want)
+	}
+}
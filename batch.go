@@ -0,0 +1,99 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"io"
+)
+
+// A BatchSummary is the result of running a batch of commands with RunBatch.
+type BatchSummary struct {
+	Ran       int // number of commands run
+	Succeeded int // number of commands that succeeded
+	Failed    int // number of commands that failed
+}
+
+// RunBatch runs each line read from r as the arguments to c.Run, following
+// the same rules as RunScript, and returns a summary of the commands run.
+// Each command is isolated from the others: the flags of the tree rooted at c
+// are restored to their values when RunBatch was called before each command
+// is run (see SnapshotFlags).
+//
+// If keepGoing is false RunBatch stops at the first command that fails and
+// returns its error, prefixed by the line number.  Otherwise the error is
+// displayed on c's Stderr and the next command is run.  RunBatch stops if ctx
+// is canceled.
+func (c *Command) RunBatch(ctx context.Context, r io.Reader, keepGoing bool) (BatchSummary, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var sum BatchSummary
+	fs := c.SnapshotFlags()
+	defer c.RestoreFlags(fs)
+	err := scanScript(r, func(n int, line string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		c.RestoreFlags(fs)
+		sum.Ran++
+		words, err := splitLine(line)
+		if err == nil {
+			err = c.runScriptLine(ctx, words)
+		}
+		if err == nil {
+			sum.Succeeded++
+			return nil
+		}
+		sum.Failed++
+		if !keepGoing {
+			return err
+		}
+		c.printf("line %d: %v\n", n, err)
+		return nil
+	})
+	return sum, err
+}
+
+type batchFlags struct {
+	KeepGoing bool `flag:"--keep-going  run the remaining commands after a command fails"`
+}
+
+// BatchCmd is a sub command that calls the Batch function.
+var BatchCmd = &Command{
+	Name:     "batch",
+	Help:     "run commands read from standard input",
+	Arity:    "0",
+	Defaults: &batchFlags{},
+	Func:     Batch,
+}
+
+// Batch implements the batch command.
+//
+//	Usage: batch [--keep-going]
+//
+// Batch runs the commands read from Stdin, one per line, with the root
+// command as RunBatch does.  A summary of the commands run is displayed on
+// Stderr when done.  Batch returns an *ExitError with a code of 1 if any
+// command failed.
+func Batch(ctx context.Context, c *Command, args []string, extra ...any) error {
+	opts, _ := c.Flags.(*batchFlags)
+	keepGoing := opts != nil && opts.KeepGoing
+	sum, err := c.Root().RunBatch(ctx, c.stdin(), keepGoing)
+	c.printf("ran %d, succeeded %d, failed %d\n", sum.Ran, sum.Succeeded, sum.Failed)
+	if err == nil && sum.Failed > 0 {
+		err = &ExitError{Code: 1, Err: c.errorf("%d of %d commands failed", sum.Failed, sum.Ran)}
+	}
+	return err
+}
@@ -0,0 +1,83 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRunBatch(t *testing.T) {
+	type setFlags struct {
+		Name string `flag:"--name=NAME the name"`
+		Up   bool   `flag:"--up        use upper case"`
+	}
+	var stderr bytes.Buffer
+	var got []string
+	set := &Command{
+		Name:  "set",
+		Flags: &setFlags{Name: "default"},
+		Func: func(_ context.Context, c *Command, _ []string, _ ...any) error {
+			opts := c.Flags.(*setFlags)
+			if opts.Up {
+				opts.Name = strings.ToUpper(opts.Name)
+			}
+			got = append(got, opts.Name)
+			return nil
+		},
+	}
+	root := &Command{
+		Name:   "prog",
+		Stderr: &stderr,
+		SubCommands: []*Command{
+			set,
+			{Name: "fail", Func: func(context.Context, *Command, []string, ...any) error {
+				return errors.New("failed")
+			}},
+			BatchCmd,
+		},
+	}
+	script := "set --name=a --up\nset\nfail\nset --name=b\n"
+	ctx := context.Background()
+
+	sum, err := root.RunBatch(ctx, strings.NewReader(script), false)
+	if err == nil || err.Error() != "line 3: failed" {
+		t.Errorf("got error %v, want line 3: failed", err)
+	}
+	if want := (BatchSummary{Ran: 3, Succeeded: 2, Failed: 1}); sum != want {
+		t.Errorf("got %+v, want %+v", sum, want)
+	}
+	if want := "A,default"; strings.Join(got, ",") != want {
+		t.Errorf("got %s, want %s", strings.Join(got, ","), want)
+	}
+	if opts := set.Flags.(*setFlags); opts.Name != "default" || opts.Up {
+		t.Errorf("flags not restored: %+v", opts)
+	}
+
+	got = nil
+	root.Stdin = strings.NewReader(script)
+	err = root.Run(ctx, []string{"batch", "--keep-going"})
+	if ExitCode(err) != 1 || err.Error() != "1 of 4 commands failed" {
+		t.Errorf("got error %v, want 1 of 4 commands failed", err)
+	}
+	if want := "A,default,b"; strings.Join(got, ",") != want {
+		t.Errorf("got %s, want %s", strings.Join(got, ","), want)
+	}
+	if want := "line 3: failed\nran 4, succeeded 3, failed 1\n"; stderr.String() != want {
+		t.Errorf("got stderr %q, want %q", stderr.String(), want)
+	}
+}
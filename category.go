@@ -0,0 +1,87 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import "sort"
+
+// A category is a group of sub commands displayed under a single heading.
+type category struct {
+	name string // "" for commands without a Category
+	cmds []*Command
+}
+
+// categories returns the sub commands of c that are neither hidden nor help
+// topics grouped by their Category.  Sub commands without a Category are
+// returned first, followed by the categories listed in c.Categories, followed
+// by any remaining categories in alphabetical order.  The commands in each
+// category are sorted by c.sortSubCommands.  Empty categories are not
+// returned.
+func (c *Command) categories() []category {
+	byName := map[string][]*Command{}
+	var names []string
+	for _, sc := range c.SubCommands {
+		if sc.hidden() || sc.isTopic() {
+			continue
+		}
+		if _, ok := byName[sc.Category]; !ok && sc.Category != "" {
+			names = append(names, sc.Category)
+		}
+		byName[sc.Category] = append(byName[sc.Category], sc)
+	}
+	order := map[string]int{}
+	for i, name := range c.Categories {
+		if _, ok := order[name]; !ok {
+			order[name] = i
+		}
+	}
+	sort.SliceStable(names, func(i, j int) bool {
+		oi, iok := order[names[i]]
+		oj, jok := order[names[j]]
+		switch {
+		case iok && jok:
+			return oi < oj
+		case iok || jok:
+			return iok
+		}
+		return names[i] < names[j]
+	})
+	names = append([]string{""}, names...)
+
+	var cats []category
+	for _, name := range names {
+		cmds := byName[name]
+		if len(cmds) == 0 {
+			continue
+		}
+		c.sortSubCommands(cmds)
+		cats = append(cats, category{name: name, cmds: cmds})
+	}
+	return cats
+}
+
+// DeclarationOrder is a SubCommandOrder that lists sub commands in the order
+// they are declared in SubCommands.
+func DeclarationOrder(a, b *Command) bool { return false }
+
+// sortSubCommands sorts cmds, which are sub commands of c, in the order they
+// are listed by help.  cmds is sorted by c.SubCommandOrder, if set, otherwise
+// by name.  The sort is stable so commands that are neither before nor after
+// each other keep their declared order.
+func (c *Command) sortSubCommands(cmds []*Command) {
+	less := c.SubCommandOrder
+	if less == nil {
+		less = func(a, b *Command) bool { return a.Name < b.Name }
+	}
+	sort.SliceStable(cmds, func(i, j int) bool { return less(cmds[i], cmds[j]) })
+}
@@ -0,0 +1,130 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestCategories(t *testing.T) {
+	noop := func(context.Context, *Command, []string, ...any) error { return nil }
+	root := &Command{
+		Name:       "prog",
+		Categories: []string{"Management Commands"},
+		SubCommands: []*Command{
+			{Name: "trace", Help: "trace calls", Category: "Debug Commands", Func: noop},
+			{Name: "version", Help: "display the version", Func: noop},
+			{Name: "rm", Help: "remove a thing", Category: "Management Commands", Func: noop},
+			{Name: "add", Help: "add a thing", Category: "Management Commands", Func: noop},
+			{Name: "dump", Help: "dump state", Category: "Debug Commands", Hidden: true, Func: noop},
+		},
+	}
+
+	var buf bytes.Buffer
+	root.PrintUsage(&buf)
+	want := `Usage: prog subcommand ...
+Known sub commands:
+
+   version  display the version
+
+Management Commands:
+   add  add a thing
+   rm  remove a thing
+
+Debug Commands:
+   trace  trace calls
+`
+	if got := buf.String(); got != want {
+		t.Errorf("PrintUsage got:\n%s\nwant:\n%s", got, want)
+	}
+
+	buf.Reset()
+	root.Stderr = &buf
+	if err := Help(context.Background(), root, nil); err != nil {
+		t.Fatal(err)
+	}
+	want = `Usage: prog subcommand [...]
+
+Available sub commands:
+  version ...
+    display the version
+
+Management Commands:
+  add ...
+    add a thing
+
+  rm ...
+    remove a thing
+
+Debug Commands:
+  trace ...
+    trace calls
+`
+	if got := buf.String(); got != want {
+		t.Errorf("Help got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSubCommandOrder(t *testing.T) {
+	noop := func(context.Context, *Command, []string, ...any) error { return nil }
+	newRoot := func(order func(a, b *Command) bool) *Command {
+		return &Command{
+			Name:            "prog",
+			SubCommandOrder: order,
+			SubCommands: []*Command{
+				{Name: "start", Func: noop},
+				{Name: "stop", Func: noop},
+				{Name: "config", Func: noop, Category: "Other"},
+				{Name: "status", Func: noop},
+				{Name: "about", Func: noop, Category: "Other"},
+			},
+		}
+	}
+	priority := map[string]int{"status": 1}
+	for _, tt := range []struct {
+		name  string
+		order func(a, b *Command) bool
+		want  string
+		cats  []string
+	}{
+		{"default", nil, "[about config start status stop]", []string{"[start status stop]", "[about config]"}},
+		{"declared", DeclarationOrder, "[start stop config status about]", []string{"[start stop status]", "[config about]"}},
+		{"priority", func(a, b *Command) bool { return priority[a.Name] > priority[b.Name] },
+			"[status start stop config about]", []string{"[status start stop]", "[config about]"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			root := newRoot(tt.order)
+			if got := fmt.Sprint(root.subCommands()); got != tt.want {
+				t.Errorf("subCommands got %s, want %s", got, tt.want)
+			}
+			var cats []string
+			for _, cat := range root.categories() {
+				var names []string
+				for _, sc := range cat.cmds {
+					names = append(names, sc.Name)
+				}
+				cats = append(cats, fmt.Sprint(names))
+			}
+			if got, want := fmt.Sprint(cats), fmt.Sprint(tt.cats); got != want {
+				t.Errorf("categories got %s, want %s", got, want)
+			}
+			if got := root.SubCommands[0].Name; got != "start" {
+				t.Errorf("SubCommands reordered, first is %s", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCleanupOrder(t *testing.T) {
+	var order []string
+	record := func(format string, a ...any) {
+		order = append(order, fmt.Sprintf(format, a...))
+	}
+	fail := errors.New("failed")
+	sub := &Command{
+		Name: "sub",
+		Func: func(ctx context.Context, c *Command, args []string, _ ...any) error {
+			c.Go(func(ctx context.Context) error {
+				<-ctx.Done()
+				record("go done")
+				return nil
+			})
+			switch args[0] {
+			case "fail":
+				return fail
+			case "interrupt":
+				<-ctx.Done()
+				return ctx.Err()
+			}
+			c.Go(func(context.Context) error { return errors.New("stop") })
+			return nil
+		},
+		PostRun: func(ctx context.Context, c *Command, args []string, err error) error {
+			record("post run %v canceled=%v", err, ctx.Err() != nil)
+			return nil
+		},
+	}
+	root := &Command{
+		Name:        "prog",
+		SubCommands: []*Command{sub},
+		OnError: func(c *Command, _ []string, _ []any, err error) error {
+			record("on error %s %v", c.Name, err)
+			return err
+		},
+		OnShutdown: func(c *Command, err error) {
+			record("shutdown %v", err)
+		},
+	}
+
+	for _, tt := range []struct {
+		arg  string
+		want string
+	}{
+		{"ok", "go done|post run stop canceled=false|on error sub stop|on error prog stop|shutdown stop"},
+		{"fail", "go done|post run failed canceled=false|on error sub failed|on error prog failed|shutdown failed"},
+		{"interrupt", "go done|post run context canceled canceled=true|on error sub context canceled|on error prog context canceled|shutdown context canceled"},
+	} {
+		order = nil
+		ctx, cancel := context.WithCancel(context.Background())
+		if tt.arg == "interrupt" {
+			cancel()
+		}
+		root.Run(ctx, []string{"sub", tt.arg})
+		cancel()
+		if got := strings.Join(order, "|"); got != tt.want {
+			t.Errorf("%s: got %s\nwant %s", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestPostRunError(t *testing.T) {
+	postErr := errors.New("post run failed")
+	root := &Command{
+		Name:    "prog",
+		Func:    func(context.Context, *Command, []string, ...any) error { return nil },
+		PostRun: func(context.Context, *Command, []string, error) error { return postErr },
+	}
+	if err := root.Run(context.Background(), nil); err != postErr {
+		t.Errorf("got error %v, want %v", err, postErr)
+	}
+}
+
+func TestRunSubcommandsCleanup(t *testing.T) {
+	var order []string
+	root := &Command{
+		Name: "prog",
+		SubCommands: []*Command{{
+			Name: "sub",
+			Func: func(_ context.Context, c *Command, _ []string, _ ...any) error {
+				c.Parent().Defer(func() { order = append(order, "deferred") })
+				return nil
+			},
+		}},
+		OnShutdown: func(c *Command, err error) {
+			order = append(order, fmt.Sprintf("shutdown %v", err))
+		},
+	}
+	if err := root.RunSubcommands(context.Background(), []string{"sub"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(order, "|"), "deferred|shutdown <nil>"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	defer func(os, arch string) { goos, goarch = os, arch }(goos, goarch)
+	goos, goarch = "windows", "amd64"
+	root.Platforms = []string{"linux"}
+	if err := root.RunSubcommands(context.Background(), []string{"sub"}); err == nil {
+		t.Errorf("RunSubcommands ran on an unsupported platform")
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pborman/flags"
+)
+
+// A ColorMode determines when Color styles text.
+type ColorMode int
+
+const (
+	// ColorAuto styles text only if Stdout is a terminal, the NO_COLOR
+	// environment variable is not set, and TERM is not "dumb".
+	ColorAuto   ColorMode = iota
+	ColorAlways           // always style text
+	ColorNever            // never style text
+)
+
+// A Theme maps the styles provided by Color to ANSI SGR parameters, such as
+// "32" for green or "1;31" for bold red.  An empty parameter leaves text of
+// that style unstyled.
+type Theme struct {
+	Success string
+	Warn    string
+	Error   string
+	Emph    string
+}
+
+// DefaultTheme is the theme used when the root command does not set one.
+var DefaultTheme = Theme{
+	Success: "32", // green
+	Warn:    "33", // yellow
+	Error:   "31", // red
+	Emph:    "1",  // bold
+}
+
+// A Styler styles text for display.  It is returned by Color.  The zero
+// value does not style text.
+type Styler struct {
+	enabled bool
+	theme   Theme
+}
+
+// Color returns a Styler for text written to c's Stdout.  The Styler follows
+// the ColorMode and Theme of the root command.
+func (c *Command) Color() Styler {
+	return c.ColorFor(c.stdout())
+}
+
+// ColorFor returns a Styler for text written to w, such as c's Stderr or a
+// progress display.  The Styler follows the color mode of the program, the
+// standard --color flag if it was given or else the ColorMode of the root
+// command, and the Theme of the root command.  Commander uses the same
+// policy for the output it produces, such as help, errors, and the header
+// of tables.
+func (c *Command) ColorFor(w io.Writer) Styler {
+	r := c.Root()
+	theme := DefaultTheme
+	if r.Theme != nil {
+		theme = *r.Theme
+	}
+	mode := r.ColorMode
+	if r.colorSet {
+		mode = r.color
+	}
+	return Styler{
+		enabled: colorEnabled(mode, w),
+		theme:   theme,
+	}
+}
+
+// colorModes are the values of the standard --color flag.
+var colorModes = map[string]ColorMode{
+	"auto":   ColorAuto,
+	"always": ColorAlways,
+	"never":  ColorNever,
+}
+
+// colorFlagHelp returns the help for the standard --color and --no-color
+// flags c accepts.
+func (c *Command) colorFlagHelp() []standardFlag {
+	var sfs []standardFlag
+	if c.acceptsColor("color") {
+		sfs = append(sfs, standardFlag{"--color=WHEN", "use color WHEN {always, auto, never}"})
+	}
+	if c.acceptsColor("no-color") {
+		sfs = append(sfs, standardFlag{"--no-color", "do not use color"})
+	}
+	return sfs
+}
+
+// acceptsColor returns true if c accepts the standard color flag named
+// name.  Only a root command with ColorFlag set accepts them, and only if it
+// does not declare its own flag with the same name.
+func (c *Command) acceptsColor(name string) bool {
+	if c.parent != nil || !c.ColorFlag {
+		return false
+	}
+	return lookupFlagField(c.getFlags(), name) == nil
+}
+
+// addColorFlags adds the standard color flags c accepts to set.  The
+// returned function, which is nil if no flag was added, must be called after
+// set is parsed.
+func (c *Command) addColorFlags(set flags.FlagSet) func() error {
+	if c.parent != nil || !c.ColorFlag {
+		return nil
+	}
+	var when string
+	var noColor bool
+	if c.acceptsColor("color") {
+		set.StringVar(&when, "color", "", "use color WHEN")
+	}
+	if c.acceptsColor("no-color") {
+		set.BoolVar(&noColor, "no-color", false, "do not use color")
+	}
+	return func() error {
+		c.colorSet = false
+		switch {
+		case noColor:
+			c.color, c.colorSet = ColorNever, true
+		case when != "":
+			mode, ok := colorModes[when]
+			if !ok {
+				return &UsageError{C: c, Err: c.errorf("invalid color mode %q {always, auto, never}", when)}
+			}
+			c.color, c.colorSet = mode, true
+		}
+		return nil
+	}
+}
+
+// heading writes the formatted heading to w emphasized.  Leading and
+// trailing newlines are not styled.
+func (c *Command) heading(w io.Writer, format string, a ...any) {
+	s := c.sprintf(format, a...)
+	text := strings.Trim(s, "\n")
+	i := strings.Index(s, text)
+	io.WriteString(w, s[:i]+c.ColorFor(w).Emph(text)+s[i+len(text):])
+}
+
+// printError displays err on c's Stderr styled as an error.
+func (c *Command) printError(err error) {
+	w := c.stderr()
+	io.WriteString(w, c.ColorFor(w).Error(err.Error())+"\n")
+}
+
+// colorEnabled returns true if text written to w should be styled in mode.
+func colorEnabled(mode ColorMode, w io.Writer) bool {
+	switch mode {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// Enabled returns true if s styles text.
+func (s Styler) Enabled() bool { return s.enabled }
+
+// Success returns text styled to indicate success.
+func (s Styler) Success(text string) string { return s.style(s.theme.Success, text) }
+
+// Warn returns text styled as a warning.
+func (s Styler) Warn(text string) string { return s.style(s.theme.Warn, text) }
+
+// Error returns text styled as an error.
+func (s Styler) Error(text string) string { return s.style(s.theme.Error, text) }
+
+// Emph returns text styled for emphasis.
+func (s Styler) Emph(text string) string { return s.style(s.theme.Emph, text) }
+
+func (s Styler) style(sgr, text string) string {
+	if !s.enabled || sgr == "" || text == "" {
+		return text
+	}
+	return "\x1b[" + sgr + "m" + text + "\x1b[0m"
+}
@@ -0,0 +1,140 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestColor(t *testing.T) {
+	var buf bytes.Buffer
+	sub := &Command{Name: "sub"}
+	root := &Command{Name: "prog", Stdout: &buf, SubCommands: []*Command{sub}}
+	sub.parent = root
+
+	// A buffer is not a terminal.
+	if s := sub.Color(); s.Enabled() || s.Error("x") != "x" {
+		t.Errorf("ColorAuto styled output to a buffer")
+	}
+	root.ColorMode = ColorAlways
+	s := sub.Color()
+	for _, tt := range []struct {
+		got, want string
+	}{
+		{s.Success("ok"), "\x1b[32mok\x1b[0m"},
+		{s.Warn("hmm"), "\x1b[33mhmm\x1b[0m"},
+		{s.Error("bad"), "\x1b[31mbad\x1b[0m"},
+		{s.Emph("look"), "\x1b[1mlook\x1b[0m"},
+		{s.Emph(""), ""},
+	} {
+		if tt.got != tt.want {
+			t.Errorf("got %q, want %q", tt.got, tt.want)
+		}
+	}
+	root.Theme = &Theme{Error: "1;35"}
+	s = sub.Color()
+	if got, want := s.Error("bad"), "\x1b[1;35mbad\x1b[0m"; got != want {
+		t.Errorf("themed got %q, want %q", got, want)
+	}
+	if got := s.Success("ok"); got != "ok" {
+		t.Errorf("empty style got %q, want ok", got)
+	}
+	root.ColorMode = ColorNever
+	if sub.Color().Enabled() {
+		t.Errorf("ColorNever is enabled")
+	}
+	if (Styler{}).Enabled() {
+		t.Errorf("zero Styler is enabled")
+	}
+}
+
+func TestColorEnabled(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+	if colorEnabled(ColorAuto, os.Stdout) {
+		t.Errorf("ColorAuto enabled with NO_COLOR set")
+	}
+	if !colorEnabled(ColorAlways, os.Stdout) {
+		t.Errorf("ColorAlways disabled with NO_COLOR set")
+	}
+}
+
+func TestColorFlag(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	fail := errors.New("failed")
+	root := &Command{
+		Name:       "prog",
+		Stdout:     &stdout,
+		Stderr:     &stderr,
+		ColorFlag:  true,
+		OutputFlag: true,
+		OnError:    ContinueOnError,
+		Examples:   []string{"prog --color=never"},
+		Func: func(_ context.Context, c *Command, args []string, _ ...any) error {
+			if len(args) > 0 {
+				return fail
+			}
+			sink := c.NewSink()
+			sink.Write(map[string]string{"name": "a", "size": "10"})
+			return sink.Flush()
+		},
+	}
+	ctx := context.Background()
+	run := func(args ...string) {
+		t.Helper()
+		stdout.Reset()
+		stderr.Reset()
+		if err := root.Run(ctx, args); err != nil {
+			t.Errorf("%v: %v", args, err)
+		}
+	}
+
+	run("--color=always")
+	if got, want := stdout.String(), "\x1b[1mNAME  SIZE\x1b[0m\na     10\n"; got != want {
+		t.Errorf("table got %q, want %q", got, want)
+	}
+	run("--color=always", "x")
+	if got, want := stderr.String(), "\x1b[31mfailed\x1b[0m\n"; got != want {
+		t.Errorf("error got %q, want %q", got, want)
+	}
+	run("--color=always", "--help")
+	if got := stderr.String(); !strings.Contains(got, "\x1b[1mExamples:\x1b[0m\n") {
+		t.Errorf("help headings not styled:\n%q", got)
+	}
+
+	// --no-color and --color=never override ColorMode.
+	root.ColorMode = ColorAlways
+	for _, flag := range []string{"--no-color", "--color=never"} {
+		run(flag, "x")
+		if got, want := stderr.String(), "failed\n"; got != want {
+			t.Errorf("%s: got %q, want %q", flag, got, want)
+		}
+	}
+	run("x")
+	if got, want := stderr.String(), "\x1b[31mfailed\x1b[0m\n"; got != want {
+		t.Errorf("ColorAlways got %q, want %q", got, want)
+	}
+
+	err := root.Run(ctx, []string{"--color=rainbow"})
+	if err != nil {
+		t.Errorf("ContinueOnError returned %v", err)
+	}
+	if got, want := stderr.String(), `prog: invalid color mode "rainbow" {always, auto, never}`; !strings.Contains(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
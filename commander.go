@@ -48,6 +48,20 @@
 // a copy of the flags.  This is useful if the command might be executed more
 // than once as each invocation will have a fresh set of flags.  If the Flags
 // field is set no copies are made and the values will persist between invocations.
+// A Flags structure must not be shared with another command, see Validate.
+//
+// A flag can be bound to an environment variable with an env tag.  The
+// variable, if set, provides the value of the flag when it is not given on
+// the command line.  Adding ",secret" keeps EnvCmd from displaying the value:
+//
+//	Token string `flag:"--token=TOKEN API token" env:"MYCMD_TOKEN,secret"`
+//
+// Help displays the value of a flag that is not the zero value as its
+// default.  A default tag replaces the displayed default, or hides it if it
+// is "-":
+//
+//	Wait  time.Duration  `flag:"--wait=D how long to wait" default:"-"`
+//	Shell string         `flag:"--shell=PROG shell to run" default:"$SHELL"`
 //
 // For example:
 //
@@ -89,15 +103,56 @@
 //		Parameters: "[cmd [cmd ...]",
 //	}
 //
+// The predefined WhichCmd displays which command a command line would run,
+// and how it was chosen, without running it.
+//
+// A sub command with a Description but no Func, SubCommands, SubCommandsFunc,
+// or Dispatch is a help topic, such as:
+//
+//	&commander.Command{
+//		Name:        "environment",
+//		Help:        "environment variables",
+//		Description: "...",
+//	}
+//
+// Help topics are listed by help under "Additional help topics" and
+// "help environment" displays the Description.  A help topic cannot be run.
+//
+// When a command finishes, whether it succeeded, failed, or returned early
+// because its context was canceled, such as by an interrupt in Main, cleanup
+// is always done in the same order:
+//
+//  1. the functions the Func started with Go are canceled and waited for
+//  2. the PostRun of the command is called, followed by the
+//     PersistentPostRun of the command and of each of its ancestors,
+//     child first
+//  3. the functions registered with Defer on the command are called, most
+//     recent first, and then the OnError, or OnRunError, in effect for the
+//     command, if any, handles the error
+//  4. step 3 is repeated for each ancestor of the command, from its parent
+//     to the root; the update notice, telemetry, and statistics of the root
+//     command are handled after its deferred functions and before its
+//     OnError
+//  5. the OnShutdown of the root command is called
+//
+// If OnError ends the program, as ExitOnError does, the later steps are
+// skipped.  A canceled context is passed unchanged to PostRun, so PostRun
+// should not use it for work that must complete.
+//
 // There are also optional fields to help with parsing the command.
 //
-// The MinArgs and MaxArgs fields specify the minimum and maximum number of
-// position parameters for the command.  If MaxArgs is 0 there is no upper limit.
-// If MaxArgs is set to commander.NoArgs then the command takes no positional parameters.
+// The Arity field specifies the number of positional parameters for the
+// command, such as "0", "1", "1..3", or "2.." (see ParseArity).  The older
+// MinArgs and MaxArgs fields specify the minimum and maximum number of
+// position parameters for the command and are ignored if Arity is set.  If
+// MaxArgs is 0 there is no upper limit.  If MaxArgs is set to commander.NoArgs
+// then the command takes no positional parameters.
 //
 // The Stderr field specifies where commandeer should send output (usage or help).
 // If Stderr is not specified it defaults to os.Stderr.  All sub commands that do
-// not specify Stderr will inherit the main command's Stderr.
+// not specify Stderr will inherit the main command's Stderr.  The Stdin and
+// Stdout fields are inherited the same way.  When DashIsArg is set the
+// OpenInput and OpenOutput methods treat "-" as Stdin and Stdout.
 //
 // OnError, when specified, is set to a function to be called when a usage error is encountered.
 // There are two pre-defined OnError functions:
@@ -105,24 +160,41 @@
 // ExitOnError - Display the message on Stderr and call os.Exit(1)
 // ContinueOnError - Display the message on Stderr and return nil
 //
-// If OnError is nil, the default, then the error is returned.
+// If OnError is nil, the default, then the error is returned.  When
+// OnRunError is set it is called instead of OnError for errors that are not
+// usage errors, such as an error returned by Func.  Either may call
+// ErrorContext to learn which command failed and at what stage, or be
+// created by WithErrorContext.
+//
+// Output piped to a program that exits early, such as head, normally causes
+// a confusing EPIPE error.  Setting QuietBrokenPipe ends the program quietly
+// instead.
+//
+// Building with the commander_nonet build tag leaves out the parts of the
+// package that use the network, RemoteDefaults and NewUpdateCmd, so programs
+// that do not need them do not link in net/http.  The RemoteDefaults field of
+// a Command only requires a DefaultsSource.
 package commander
 
 import (
 	"bytes"
 	"context"
-	"errors"
 	"fmt"
 	"io"
+	"math/rand"
 	"os"
+	"runtime/debug"
 	"sort"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/pborman/flags"
 	"github.com/pborman/indent"
 )
 
-// If MaxArgs is set to NoArgs then the command takes no arguments.
+// If MaxArgs is set to NoArgs then the command takes no arguments.  Setting
+// Arity to "0" is clearer.
 const NoArgs = -1
 
 // A Command can either be a function and/or a list of subcommands.  A Command
@@ -146,36 +218,431 @@ type Command struct {
 	Help        string // Short description of this command
 	Description string // Long description displayed by help
 	Parameters  string // Parameters to go at the end of the usage line
+	Arity       string // Number of arguments, see ParseArity
 	MinArgs     int    // The command must have at least this many arguments
 	MaxArgs     int    // Maximum number of arguments.  0 means no limit
 	Defaults    any    // An options struct as defined by the flags package
 	Flags       any    // See above for Defaults vs Flags
 	Func        func(context.Context, *Command, []string, ...any) error
 	SubCommands []*Command // Sub-Commands -- Ignored if Func is set
+	Aliases     []string   // Alternate names for this command
+	Hidden      bool       // Not listed by help, but may still be run
+
+	// Platforms, if set, lists the platforms the command runs on, either
+	// an operating system, such as "linux", or an operating system and
+	// architecture, such as "darwin/arm64".  On other platforms the
+	// command is not listed by help and running it returns an error.
+	Platforms []string
+
+	// Annotations holds arbitrary metadata about the command for use by
+	// packages built on commander, such as documentation generators or
+	// authorization wrappers.  Commander itself does not use it.  Keys
+	// should be prefixed by the name of the package that uses them, e.g.,
+	// "authz.role", to avoid collisions.
+	Annotations map[string]string
+
+	// SubCommandsFunc, if set, returns the sub commands of c when
+	// SubCommands is nil.  It is called the first time the sub commands
+	// are needed to run a sub command, display help, or complete a
+	// command line and the result is saved in SubCommands.  This is
+	// useful when the sub commands are expensive to determine, e.g.,
+	// they are found from plugins.  Functions that walk the entire tree,
+	// such as Validate, only see sub commands that have been loaded.
+	SubCommandsFunc func(context.Context) []*Command
+
+	// SuggestFor lists names, such as synonyms, that the command is
+	// suggested for when they are mistakenly used as a sub command name,
+	// e.g., "delete" for a command named "remove".
+	SuggestFor []string
+
+	// If ParseKnownFlags is set then only the flags c declares are parsed
+	// from its arguments.  All other arguments, including other flags,
+	// are left, in order, for c's sub command or Func.  Arguments after
+	// "--" are not parsed.  This lets a wrapper accept the flags of the
+	// command it wraps mixed with its own, e.g., if retry declares
+	// --attempts then
+	//
+	//	prog retry --region=x --attempts=3 deploy
+	//
+	// passes [--region=x deploy] to the Func of retry.  Without
+	// ParseKnownFlags an unknown flag is an error and parsing stops at
+	// the first argument that is not a flag.
+	ParseKnownFlags bool
+
+	// NormalizeFlagName, if set, is used to compare flag names given on
+	// the command line with the names of the flags of c and of all of
+	// its sub commands that do not set their own NormalizeFlagName.  A
+	// flag is accepted if both names normalize to the same name.  For
+	// example, UnderscoresToDashes accepts --dry_run for --dry-run.
+	NormalizeFlagName func(name string) string
+
+	// NormalizeArgs, if set, is called with the arguments of c before
+	// they are parsed and returns the arguments to use instead.  It is
+	// typically set on the root command, where it sees the entire command
+	// line, to translate legacy spellings or expand shorthands.
+	NormalizeArgs func([]string) []string
+
+	// DefaultSubCommand, if set, is the name of the sub command run when
+	// c is run without arguments and c has no Func.
+	DefaultSubCommand string
+
+	// OnUnknownCommand, if set, is called when the first argument of c
+	// does not name a sub command, rather than returning a *UsageError.
+	// It is passed the name and the arguments that follow it.  This
+	// allows names to be treated as dynamic resources, such as host
+	// names.
+	OnUnknownCommand func(ctx context.Context, c *Command, name string, args []string) error
+
+	// Dispatch, if set, is called by Run with the positional arguments
+	// once the flags of c are parsed.  If handled is true Run returns err.
+	// Otherwise Run follows its normal rule: a sub command is run if c has
+	// SubCommands and there are arguments, else Func is called.  Dispatch
+	// lets a command with both Func and SubCommands decide, for example,
+	// to only run a sub command when the first argument names one.
+	// Such a Dispatch calls RunFunc when it handles the arguments itself
+	// and returns false to let Run run the sub command.
+	Dispatch func(ctx context.Context, c *Command, args []string, extra ...any) (handled bool, err error)
+
+	// PostRun, if set, is called after Func returns, whether Func
+	// succeeded, failed, or returned because ctx was canceled.  It is
+	// passed the error Func returned and its own error is returned in
+	// place of a nil error from Func.  PostRun is not called if Func was
+	// not called.  See the package documentation for the order of
+	// cleanup.
+	PostRun func(ctx context.Context, c *Command, args []string, err error) error
+
+	// PersistentPreRun and PersistentPostRun, if set, are called when c,
+	// or any of its descendants, runs its Func.  They are passed the
+	// command that is running, not c.  The PersistentPreRun of each
+	// command from the root to the running command is called, parent
+	// first, before its Func.  If one fails Func is not called.  Once Func
+	// and PostRun return, the PersistentPostRun of each command from the
+	// running command to the root is called, child first, with the error
+	// so far.  If a PersistentPreRun failed only the commands above the
+	// one that failed are included.  As with PostRun, the error of
+	// PersistentPostRun is only returned in place of a nil error.  These
+	// hooks are the place to set up, and tear down, state shared by a
+	// whole tree, such as logging.
+	PersistentPreRun  func(ctx context.Context, c *Command, args []string) error
+	PersistentPostRun func(ctx context.Context, c *Command, args []string, err error) error
+
+	middleware []func(next CommandFunc) CommandFunc // added by Use
+	deferred   *deferList                           // registered by Defer
+
+	// Category, if not empty, is the heading, such as "Management
+	// Commands", the command is listed under by help and PrintUsage.
+	// Commands without a Category are listed first.  Categories are
+	// displayed in the order given by the parent's Categories followed by
+	// any remaining categories in alphabetical order.
+	Category   string
+	Categories []string
+
+	// SubCommandOrder, if not nil, reports whether sub command a is listed
+	// before sub command b by help and PrintUsage.  By default sub
+	// commands are listed in alphabetical order.  Set SubCommandOrder to
+	// DeclarationOrder to list them in the order they appear in
+	// SubCommands.
+	SubCommandOrder func(a, b *Command) bool
+
+	// Deprecated, if not empty, marks the command as deprecated.  It
+	// should say what to use instead.  A warning that includes Deprecated
+	// is displayed each time the command is run and help marks the
+	// command as deprecated.
+	Deprecated string
+
+	// Requires lists what the command needs to run, such as "root" or
+	// "network".  Before Func is called each requirement of the command
+	// and its parents is checked with the root command's
+	// RequirementChecker, or DefaultRequirementChecker if nil.  Help
+	// displays the requirements.
+	Requires           []string
+	RequirementChecker func(ctx context.Context, c *Command, requirement string) error
+
+	// If AllowPrefixMatch is set on the root command then a sub command
+	// may be named by any prefix of its name, or of one of its aliases,
+	// that is not a prefix of another sub command, e.g., "stat" for
+	// "status".  Hidden sub commands must be named in full.
+	AllowPrefixMatch bool
+
+	// If AllowLeafMatch is set on the root command then a leaf command,
+	// a command without sub commands, may be named directly from any
+	// command above it if no other leaf below that command has the same
+	// name or alias.  For example, "prog add" runs "prog node add" if add
+	// is the only leaf named add.  Hidden leaves must be named in full.
+	AllowLeafMatch bool
+
+	// SuggestionsMinimumDistance, when set on the root command, is the
+	// maximum number of edits between a mistyped sub command name and the
+	// name of a sub command for the sub command to be suggested in the
+	// error.  It defaults to 2.  A negative value disables suggestions.
+	SuggestionsMinimumDistance int
+
+	// If ShowAliases is set on the root command then help displays the
+	// Aliases of commands.
+	ShowAliases bool
+
+	// Examples are complete command lines, starting with the name of the
+	// root command, that demonstrate the use of the command.  They are
+	// displayed by help and can be verified with CheckExamples.  Quoting
+	// follows the rules of SplitLine.  An example may use placeholders,
+	// upper case names in angle brackets such as <FILE>, for values the
+	// user supplies.  Shell completion offers the arguments of such an
+	// example as a template when no argument of the command has been
+	// typed.  On Windows help requotes examples for Windows shells.
+	Examples []string
+
+	// ReadOnly declares that the command has no side effects, it only
+	// queries state.  Commander does not use ReadOnly itself, it is
+	// metadata for policies layered on top of commander (e.g., only
+	// asking for confirmation before running commands that are not
+	// read only).
+	ReadOnly bool
+
+	// MaxConcurrent, if positive, limits how many executions of the
+	// command may be in progress at once.  A Command cannot be run
+	// concurrently with itself, so in a server or REPL the executions in
+	// progress are those that re-enter it, such as a command that is run
+	// again from a REPL or script it started.  MinInterval,
+	// if positive, is the minimum time between the start of two
+	// invocations.  Run and RunSubcommands return a *BusyError if either
+	// limit would be exceeded.  These are useful when commands are run
+	// repeatedly from a REPL or script.
+	MaxConcurrent int
+	MinInterval   time.Duration
+	limits        *limiter
 
 	// Errors are displayed to Stderr (defaults to os.Stderr).
 	// If not nil, OnError is called when there is a usage error
 	// running a command.  If these values are nil then
-	// their parent's values are used.
+	// their parent's values are used.  Each is inherited
+	// independently of the other, e.g., a sub tree may set Stderr to
+	// io.Discard while still using its parent's OnError.
+	// EffectiveStderr and EffectiveOnError return the values that are
+	// used.
 	Stderr  io.Writer
 	OnError func(*Command, []string, []any, error) error
+
+	// OnRunError, if set, is called in place of OnError for errors that
+	// are not usage errors, that is, errors Classify does not report as
+	// UsageClass.  This lets OnError display help for usage errors while
+	// failures of the command itself are handled differently, e.g.,
+	// logged or given a different exit code.  OnRunError is inherited
+	// like OnError, independently of it.  If no OnRunError is in effect
+	// OnError handles every error.
+	OnRunError func(*Command, []string, []any, error) error
+
+	// OnPanic, if set, is called when Func, or a function it started with
+	// Go, panics.  The panic is recovered and the error OnPanic returns is
+	// handled as if Func had returned it, so it is reported through
+	// OnError.  OnPanic is inherited like OnError.  ReportPanic is a
+	// predefined OnPanic.  Without an OnPanic a panic is not recovered.
+	OnPanic func(c *Command, v any) error
+
+	// OnHelp, if set, is called each time help for the command, or a
+	// descendant that does not set its own OnHelp, is displayed by Help
+	// or HelpText.  It is passed the command the help is for and the
+	// writer the help was written to, so it may append text, such as
+	// values computed when the program runs, or record that help was
+	// displayed.
+	OnHelp func(c *Command, w io.Writer)
+
+	// Stdin and Stdout are the standard input and output of the command
+	// (they default to os.Stdin and os.Stdout).  If nil their parent's
+	// values are used.
+	Stdin  io.Reader
+	Stdout io.Writer
+
+	// If BufferOutput is set on a command, or any of its parents, then
+	// output written to the Stdout returned by EffectiveStdout while Func
+	// is running is held until Func returns.  The output is written to
+	// Stdout only if Func succeeds.  If Func fails the output is written
+	// to Stderr, labeled as coming from a failed command, so programs
+	// consuming the output never see partial results.
+	BufferOutput bool
+	outbuf       *syncBuffer // output held while Func is running
+
+	// If QuietBrokenPipe is set on the root command then writing to a pipe
+	// whose reader has gone away, such as when the output is piped to
+	// head, quietly and successfully ends the program rather than
+	// reporting an EPIPE error.  See IsBrokenPipe for details.
+	QuietBrokenPipe bool
+
+	// If DashIsArg is set then OpenInput and OpenOutput treat the
+	// argument "-" as meaning Stdin or Stdout rather than a file named
+	// "-".  DashIsArg is inherited by all sub commands.
+	DashIsArg bool
+
+	// ConfigFile, if set on the root command, is the path to a
+	// configuration file that provides default values for the flags of
+	// the command tree.  See Config for the format of the file.  It is not
+	// an error for the file to not exist.
+	ConfigFile string
+	config     *Config // the loaded ConfigFile
+	configErr  error   // error loading ConfigFile
+
+	// RemoteDefaults, if set on the root command, provides flag defaults
+	// with a lower precedence than ConfigFile, normally a *RemoteDefaults
+	// fetched from a remote server.
+	RemoteDefaults DefaultsSource
+	remote         *Config // the fetched RemoteDefaults
+
+	// If Interpolate is set on the root command then references in values
+	// read from the configuration file and in string flags provided on the
+	// command line are expanded.  The references are:
+	//
+	//	${env:NAME}      the value of the environment variable NAME
+	//	${config:KEY}    the value of KEY in the configuration file
+	//	${cmdoutput:CMD} the output of running CMD (with arguments)
+	//
+	// Use $${ to include a literal ${.  Default values declared in
+	// Defaults or Flags, values of environment variables bound to flags,
+	// and values from RemoteDefaults are not expanded, nor can
+	// ${config:KEY} refer to RemoteDefaults.
+	//
+	// ${cmdoutput:CMD} is only expanded in values provided on the command
+	// line if InterpolateCmdOutput is also set on the root command, as
+	// otherwise anyone who can pass arguments to the program can run
+	// commands.  Without it such a value is a usage error.
+	Interpolate          bool
+	InterpolateCmdOutput bool
+
+	// If VerbosityFlags is set on the root command then the root command
+	// accepts the standard -q/--quiet, -v/--verbose, and --debug flags,
+	// unless it declares flags with the same names.  The flags set the
+	// Level used by Printf, Verbosef, Debugf, and ShowProgress.  If more
+	// than one is given the last one on the command line wins.
+	VerbosityFlags bool
+	level          Level   // verbosity of the current invocation
+	timeline       []phase // phases of the current invocation
+	timing         bool    // phases are being recorded
+
+	// Version, if set on the root command, is the version of the
+	// program.  The root command then accepts the standard -V and
+	// --version flags, unless it declares flags with the same names.  The
+	// flags display the name of the root command and Version on Stdout
+	// and return without running any command.
+	Version     string
+	showVersion bool // a version flag was given
+
+	// VersionInfo, if set on the root command, returns additional
+	// information, such as the build host, that Version displays after
+	// the build information.
+	VersionInfo func() []debug.BuildSetting
+
+	// If NonInteractiveFlag is set on the root command then the root
+	// command accepts the standard --non-interactive flag, unless it
+	// declares its own, and honors the CI environment variable.  Either
+	// makes the prompt helpers, such as Prompt, fail rather than wait for
+	// an answer.  See Interactive.
+	NonInteractiveFlag bool
+	nonInteractive     bool // --non-interactive was given
+
+	// If SeedFlag is set on the root command then the root command
+	// accepts the standard --seed=N flag, unless it declares its own seed
+	// flag.  The flag seeds the source of random numbers returned by Rand.
+	SeedFlag bool
+	seed     int64      // seed for rng
+	seeded   bool       // seed has been set
+	rng      *rand.Rand // returned by Rand
+
+	// If ParallelFlag is set on the root command then the root command
+	// accepts the standard --parallel=N flag, unless it declares its own
+	// parallel flag.  The flag limits the number of functions started by
+	// Go that run at once.  By default there is no limit.
+	ParallelFlag bool
+	parallel     int    // value of --parallel, 0 for no limit
+	group        *group // functions started by Go
+
+	// If OutputFlag is set on the root command then the root command
+	// accepts the standard --output=FORMAT, --columns=NAMES (or
+	// --fields=NAMES), and --no-header flags, except for any it declares
+	// itself.  They control the Sink returned by NewSink.  Sinks adds
+	// formats, or replaces the standard ones, csv, jsonl, and table.  If
+	// Querier is also set the root command accepts the standard
+	// --query=EXPR flag, whose expression is evaluated by Querier.
+	OutputFlag bool
+	Sinks      map[string]func(w io.Writer) Sink
+	Querier    Querier
+	output     string   // --output was given
+	columns    []string // --columns was given
+	noHeader   bool     // --no-header was given
+	query      string   // --query was given
+
+	// StatsFile, if set on the root command, is the path to a file
+	// where the number of times each command is run, and how long it
+	// took, is recorded.  The statistics never leave the local machine,
+	// they are displayed by StatsCmd.
+	StatsFile string
+
+	// Telemetry, if set on the root command, is sent a report each time
+	// the command is run, but only if the user has turned telemetry on
+	// with TelemetryCmd.
+	Telemetry TelemetrySender
+	ran       *Command // the command whose Func was called
+
+	// UpdateNotifier, if set on the root command, is asked after each run
+	// if a newer version of the program is available.  See UpdateNotifier.
+	UpdateNotifier UpdateNotifier
+
+	// OnShutdown, if set on the root command, is called once each time
+	// Run of the root command returns, after all other cleanup, with the
+	// error Run returns.  It is the place to release resources
+	// held by the whole program.  OnShutdown is called even if the run
+	// was interrupted.
+	OnShutdown func(c *Command, err error)
+
+	// Printer, if set on the root command, is used to format all messages
+	// displayed by commander.  See Printer for details.
+	Printer Printer
+
+	// Prompter, if set on the root command, is used by Prompt, Confirm,
+	// Select, and MultiSelect to ask the user questions.  The default is a
+	// TerminalPrompter.
+	Prompter Prompter
+	terminal *TerminalPrompter // the default Prompter
+
+	// ColorMode and Theme, when set on the root command, determine how
+	// text is styled by the Styler returned by Color, and the output
+	// commander produces.  A nil Theme uses DefaultTheme.  If ColorFlag
+	// is set on the root command then the root command accepts the
+	// standard --color=WHEN and --no-color flags, unless it declares its
+	// own, which override ColorMode.
+	ColorMode ColorMode
+	Theme     *Theme
+	ColorFlag bool
+	color     ColorMode // set by --color or --no-color
+	colorSet  bool      // --color or --no-color was given
+
+	// UsageLineFunc, if set, returns the usage line displayed by help for
+	// a command, such as "cmd [--verbose] file ...".  It is used for c and
+	// all of its sub commands that do not set their own UsageLineFunc.
+	// When UsageLineFunc is nil the usage line is generated by
+	// flags.UsageLine.
+	UsageLineFunc func(*Command) string
+
+	checks []healthCheck               // registered with RegisterCheck
+	inv    *atomic.Pointer[invocation] // the most recent invocation
+
+	stage   ErrorStage    // the stage Run of the command has reached
+	errCtx  *ErrorContext // the error of the current run, see ErrorContext
+	runArgs []string      // the arguments passed to Run of the root command
 }
 
 // Exit can be overriden by tests.
 var Exit = os.Exit
 
 // ExitOnError is an OnError func that displays the error and exits
-// with a return code of 1.
+// with the code returned by ExitCode, normally 1.
 func ExitOnError(c *Command, _ []string, _ []any, err error) error {
-	c.printf("%v\n", err)
-	Exit(1)
+	c.printError(err)
+	Exit(ExitCode(err))
 	return nil
 }
 
 // ContinueOnError is on OnError func that displays the error and
 // returns no error.
 func ContinueOnError(c *Command, _ []string, _ []any, err error) error {
-	c.printf("%v\n", err)
+	c.printError(err)
 	return nil
 }
 
@@ -201,20 +668,58 @@ func (c *Command) Command() string {
 	return c.Name
 }
 
-// Tests can override this
-var stderr io.Writer = os.Stderr
+// Parent returns the parent of c, or nil if c is the root command.  The
+// parent of a command is set when the command is reached by Run, Resolve, or
+// Walk, or when it is added with AddCommand or Mount.
+func (c *Command) Parent() *Command {
+	return c.parent
+}
+
+// Root returns the root of the command tree c is in.
+func (c *Command) Root() *Command {
+	for c.parent != nil {
+		c = c.parent
+	}
+	return c
+}
+
+// Path returns the commands from the root of the command tree to c,
+// inclusive.
+func (c *Command) Path() []*Command {
+	var path []*Command
+	for ; c != nil; c = c.parent {
+		path = append(path, c)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// Tests can override these
+var (
+	stdin  io.Reader = os.Stdin
+	stdout io.Writer = os.Stdout
+	stderr io.Writer = os.Stderr
+)
 
 func (c *Command) printf(format string, v ...any) {
-	fmt.Fprintf(c.stderr(), format, v...)
+	c.fprintf(c.stderr(), format, v...)
 }
 
 func (c *Command) subCommands() []string {
-	var cmds []string
+	var cmds []*Command
 	for _, sc := range c.SubCommands {
-		cmds = append(cmds, sc.Name)
+		if !sc.hidden() && !sc.isTopic() {
+			cmds = append(cmds, sc)
+		}
 	}
-	sort.Strings(cmds)
-	return cmds
+	c.sortSubCommands(cmds)
+	names := make([]string, len(cmds))
+	for i, sc := range cmds {
+		names[i] = sc.Name
+	}
+	return names
 }
 
 // Run runs the command with the provided arguments after parsing any flags.
@@ -227,112 +732,262 @@ func (c *Command) subCommands() []string {
 // If the command has both Func and SubCommands then Func is called if there
 // are no positional parameters otherwise the first argument is used to find
 // the sub command listed in SubCommands.
-func (c *Command) Run(ctx context.Context, args []string, extra ...any) (err error) {
+func (c *Command) Run(ctx context.Context, args []string, extra ...any) error {
+	return c.run(ctx, args, extra, func(args []string) error {
+		c.discover(ctx)
+		if c.Dispatch != nil {
+			if handled, err := c.Dispatch(ctx, c, args, extra...); handled {
+				return err
+			}
+		}
+		if c.SubCommands != nil && (len(args) > 0 || c.Func == nil && c.DefaultSubCommand != "") {
+			return c.runsub(ctx, args, extra...)
+		}
+		return c.RunFunc(ctx, args, extra...)
+	})
+}
+
+// run does the work shared by Run and RunSubcommands.  It checks c's limits
+// and platform, parses the flags and arguments in args, and then calls
+// dispatch with the remaining arguments.  When c is the root command run also
+// loads the configuration, handles statistics, telemetry, and the update
+// notice, and calls OnShutdown.  Errors are handled by the OnError in effect
+// for c.
+func (c *Command) run(ctx context.Context, args []string, extra []any, dispatch func(args []string) error) (err error) {
+	if c.parent == nil {
+		c.errCtx, c.runArgs = nil, args
+		c.resetLevel()
+		defer c.endTimeline(c.startTimeline())
+	}
+	c.stage = DispatchStage
+	if c.parent == nil && c.OnShutdown != nil {
+		defer func() {
+			defer c.startPhase("cleanup")()
+			c.OnShutdown(c, err)
+		}()
+	}
 	defer func() {
-		if c.onError(err) == nil {
-			return
+		c.noteError(err)
+		if onError := c.onError(err); onError != nil {
+			endOnError := c.startPhase("on error")
+			err = onError(c, args, extra, err)
+			endOnError()
+		}
+		if err == nil {
+			// The error, if any, was handled so there is
+			// nothing for ErrorContext to report.
+			c.Root().errCtx = nil
 		}
-		err = c.onError(err)(c, args, extra, err)
 	}()
-	args, err = c.parse(args)
+	release, err := c.acquire()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		defer c.startPhase("cleanup")()
+		release()
+	}()
+	if c.parent == nil {
+		c.loadConfig(ctx)
+		c.ran = nil
+		c.resetRand()
+		if c.StatsFile != "" {
+			defer func(start time.Time) {
+				if c.completing() {
+					return
+				}
+				defer c.startPhase("cleanup")()
+				c.recordStats(start, &err)
+			}(now())
+		}
+		if c.Telemetry != nil {
+			defer func(start time.Time) {
+				if c.completing() {
+					return
+				}
+				defer c.startPhase("cleanup")()
+				c.sendTelemetry(ctx, start, &err)
+			}(now())
+		}
+		if c.UpdateNotifier != nil {
+			defer func() {
+				if c.completing() {
+					return
+				}
+				defer c.startPhase("cleanup")()
+				c.printUpdateNotice(ctx)
+			}()
+		}
+	}
+	defer c.runDeferred()
+	if err := c.checkPlatform(); err != nil {
+		return err
+	}
+	endParse := c.startPhase("parse")
+	args, err = c.parse(ctx, io.Discard, c.normalizeArgs(args))
+	endParse()
 	if err != nil {
-		c.printf("%v\n", err)
+		c.printError(err)
 		if ue, ok := err.(*UsageError); ok {
 			Help(ctx, ue.C, nil)
 		}
 		return err
 	}
-	if c.SubCommands != nil && len(args) > 0 {
-		return c.runsub(ctx, args, extra...)
-	}
-	if c.Func != nil {
-		return c.Func(ctx, c, args, extra...)
+	c.stage = DispatchStage
+	c.recordInvocation()
+	if c.printVersion() {
+		return nil
 	}
-	return nil
+	return dispatch(args)
 }
 
-// RunSubcommands is similar to Run excpet it ignores c.Func and just runs sub
-// commands.
-func (c *Command) RunSubcommands(ctx context.Context, args []string, extra ...any) (err error) {
-	defer func() {
-		if c.onError(err) == nil {
-			return
-		}
-		err = c.onError(err)(c, args, extra, err)
-	}()
-	args, err = c.parse(args)
+// RunFunc calls c.Func, if set, with args as Run does once c's flags have
+// been parsed and c has been chosen to run.  The checks Run makes before
+// calling Func, such as Requires, are made and the deprecation warning is
+// displayed.  RunFunc is intended to be called by a Dispatch func.
+func (c *Command) RunFunc(ctx context.Context, args []string, extra ...any) (err error) {
+	if c.Func == nil {
+		return nil
+	}
+	endChecks := c.startPhase("checks")
+	err = c.configError()
+	if err == nil {
+		err = c.checkRequirements(ctx)
+	}
+	endChecks()
 	if err != nil {
-		c.printf("%v\n", err)
-		if ue, ok := err.(*UsageError); ok {
-			Help(ctx, ue.C, nil)
-		}
 		return err
 	}
-	return c.runsub(ctx, args, extra...)
+	if c.Deprecated != "" {
+		c.printf("%s is deprecated: %s\n", c.Command(), c.Deprecated)
+	}
+	c.Root().ran = c
+	c.stage = RunStage
+	defer c.startPhase("func")()
+	err = c.wrap(runHooked)(ctx, c, args, extra...)
+	if c.quietBrokenPipe(err) {
+		return nil
+	}
+	return err
+}
+
+// RunSubcommands is similar to Run except it ignores c.Func, and Dispatch, and
+// just runs sub commands.
+func (c *Command) RunSubcommands(ctx context.Context, args []string, extra ...any) error {
+	return c.run(ctx, args, extra, func(args []string) error {
+		return c.runsub(ctx, args, extra...)
+	})
+}
+
+// normalizeArgs returns args as normalized by c.NormalizeArgs, if set.
+func (c *Command) normalizeArgs(args []string) []string {
+	if c.NormalizeArgs == nil {
+		return args
+	}
+	return c.NormalizeArgs(args)
 }
 
 func (c *Command) runsub(ctx context.Context, args []string, extra ...any) (err error) {
+	c.discover(ctx)
+	if len(args) < 1 && c.DefaultSubCommand != "" {
+		args = []string{c.DefaultSubCommand}
+	}
 	if len(args) < 1 {
 		return &UsageError{
 			C:   c,
-			Err: fmt.Errorf("sub command required {%s}", strings.Join(c.subCommands(), ", ")),
+			Err: c.errorf("sub command required {%s}", strings.Join(c.subCommands(), ", ")),
 		}
 	}
 	cmd := args[0]
 	args = args[1:]
-	for _, sc := range c.SubCommands {
-		if sc.Name == cmd {
-			sc.parent = c
-			return sc.Run(ctx, args, extra...)
+	sc, err := c.matchSub(cmd, c.Root().AllowPrefixMatch)
+	if err != nil {
+		return &UsageError{C: c, Err: err}
+	}
+	if sc == nil && c.Root().AllowLeafMatch {
+		path, err := c.matchLeaf(ctx, cmd)
+		if err != nil {
+			return &UsageError{C: c, Err: err}
 		}
+		if path != nil {
+			return c.runsub(ctx, append(path, args...), extra...)
+		}
+	}
+	if sc != nil && sc.isTopic() {
+		return &UsageError{C: c, Err: c.errorf("%s is a help topic, not a command", sc.Name)}
+	}
+	if sc != nil {
+		sc.parent = c
+		return sc.Run(ctx, args, extra...)
+	}
+	if c.OnUnknownCommand != nil {
+		return c.OnUnknownCommand(ctx, c, cmd, args)
 	}
 	return &UsageError{
 		C:   c,
-		Err: fmt.Errorf("%s: unknown command", cmd),
+		Err: c.unknownCommand(cmd),
 	}
 }
 
-func (c *Command) parse(args []string) ([]string, error) {
+// parse parses the flags and checks the number of positional parameters in
+// args.  Any output generated by the flag package, such as its usage message,
+// is written to w rather than c's Stderr.  The caller is responsible for
+// reporting the returned error.
+func (c *Command) parse(ctx context.Context, w io.Writer, args []string) ([]string, error) {
+	c.stage = FlagStage
 	var set flags.FlagSet
 	if c.Defaults != nil {
-		c.Flags, set = flags.RegisterNew(c.Command(), c.Defaults)
+		if _, _, err := flagFields(c.Defaults); err != nil {
+			return args, fmt.Errorf("%s: %v", c.Command(), err)
+		}
+		c.Flags = dupFlags(c.Defaults)
+		set = flags.NewFlagSet("")
+		if err := registerFlags(c.Flags, set); err != nil {
+			return args, fmt.Errorf("%s: %v", c.Command(), err)
+		}
 	} else if c.Flags != nil {
 		set = flags.NewFlagSet(c.Name)
-		flags.RegisterSet(c.Command(), c.Flags, set)
+		if err := registerFlags(c.Flags, set); err != nil {
+			return args, fmt.Errorf("%s: %v", c.Command(), err)
+		}
+	}
+	if set == nil && len(c.standardFlags()) > 0 {
+		set = flags.NewFlagSet(c.Name)
 	}
-	var buf bytes.Buffer
-	oStderr := c.Stderr
-	defer func() { c.Stderr = oStderr }()
-	c.Stderr = &buf
-
 	if set != nil {
-		w := c.stderr()
+		setStandard := c.addStandardFlags(set)
 		set.SetOutput(w)
+		if err := c.applyConfig(ctx, set); err != nil {
+			return args, err
+		}
+		if err := c.applyEnv(set); err != nil {
+			return args, err
+		}
+		var rest []string
+		args, rest = c.splitArgs(set, args)
+		var fromArgs map[string]int
+		if c.interpolating() {
+			fromArgs = trackFlags(set)
+		}
 		if err := set.Parse(args); err != nil {
-			flags.Help(w, c.Name, c.parameters(), c.Flags)
+			flagHelp(w, c.Name, c.parameters(), c.Flags)
 			return args, &UsageError{C: c, Err: err}
 		}
-		args = set.Args()
-	}
-	if c.MaxArgs == NoArgs && len(args) != 0 {
-		return args, &UsageError{
-			C:   c,
-			Err: errors.New("takes no arguments"),
+		if err := setStandard(); err != nil {
+			return args, err
 		}
-	}
-	if len(args) < c.MinArgs {
-		return args, &UsageError{
-			C:   c,
-			Err: fmt.Errorf("requires at least %d arguments", c.MinArgs),
+		if c.showVersion {
+			return set.Args(), nil
 		}
-	}
-	if c.MaxArgs > 0 && len(args) > c.MaxArgs {
-		return args, &UsageError{
-			C:   c,
-			Err: fmt.Errorf("takes no more than %d arguments", c.MaxArgs),
+		if c.interpolating() {
+			if err := c.interpolateFlags(ctx, set, fromArgs); err != nil {
+				return args, &UsageError{C: c, Err: err}
+			}
 		}
+		args = append(set.Args(), rest...)
 	}
-	return args, nil
+	c.stage = ArgsStage
+	return args, c.checkArgs(args)
 }
 
 // Lookup returns the value of the flag named flag.  If cmd is not empty Lookup will look for a command in the tree that is named cmd.
@@ -342,46 +997,127 @@ func (c *Command) parse(args []string) ([]string, error) {
 //
 //	bar.Lookup("", "name") -> VALUE2
 //	bar.Lookup("foo", "name") -> VALUE1
+//
+// Once c has been run Lookup returns the values the flags had when they were
+// parsed by the most recent Run, not any later changes made to Flags.  These
+// values are never modified so Lookup may be called from any goroutine, even
+// while the tree is being run again.  Slices and maps held by flags are
+// copied when recorded, other values, such as pointers, are not.
 func (c *Command) Lookup(cmd, name string) any {
 	if c == nil {
 		return nil
 	}
+	if inv := c.lastInvocation().Load(); inv != nil {
+		return inv.lookup(cmd, name)
+	}
 	if cmd == "" || cmd == c.Name {
-		if i := flags.Lookup(c.Flags, name); i != nil {
+		if i := lookupFlag(c.Flags, name); i != nil {
 			return i
 		}
 	}
 	return c.parent.Lookup(cmd, name)
 }
 
+// discover sets c.SubCommands from c.SubCommandsFunc if c.SubCommands is
+// not yet set.
+func (c *Command) discover(ctx context.Context) {
+	if c.SubCommands == nil && c.SubCommandsFunc != nil {
+		c.SubCommands = c.SubCommandsFunc(ctx)
+	}
+}
+
+// findSub returns the sub command of c named name, either by its Name or by
+// one of its Aliases, or nil.
 func (c *Command) findSub(name string) *Command {
 	for _, sc := range c.SubCommands {
 		if sc.Name == name {
 			return sc
 		}
 	}
+	for _, sc := range c.SubCommands {
+		for _, alias := range sc.Aliases {
+			if alias == name {
+				return sc
+			}
+		}
+	}
 	return nil
 }
 
-// PrintUsage write the usage information for c to w.
+// matchSub returns the sub command of c named name.  If there is none and
+// prefix is true then the sub command, other than a hidden one, whose name
+// or alias starts with name is returned.  An error listing the candidates is
+// returned if more than one sub command starts with name.  Nil is returned
+// if no sub command matches.
+func (c *Command) matchSub(name string, prefix bool) (*Command, error) {
+	if sc := c.findSub(name); sc != nil || !prefix || name == "" {
+		return sc, nil
+	}
+	var matches []*Command
+	var names []string
+	for _, sc := range c.SubCommands {
+		if sc.hidden() {
+			continue
+		}
+		for _, n := range append([]string{sc.Name}, sc.Aliases...) {
+			if strings.HasPrefix(n, name) {
+				matches = append(matches, sc)
+				names = append(names, sc.Name)
+				break
+			}
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, nil
+	case 1:
+		return matches[0], nil
+	}
+	sort.Strings(names)
+	return nil, c.errorf("%q is ambiguous {%s}", name, strings.Join(names, ", "))
+}
+
+// PrintUsage write the usage information for c to w.  PrintUsage does not
+// call SubCommandsFunc, only the sub commands already discovered, e.g., by
+// Run, are listed.
 func (c *Command) PrintUsage(w io.Writer) {
 	opts := c.Defaults
 	if opts == nil {
 		opts = c.Flags
 	}
-	if len(c.SubCommands) > 0 {
-		flags.Help(w, c.Name, "subcommand ...", opts)
-		fmt.Fprintf(w, "Known sub commands:\n")
-		// Find the longest name
-		for i, subcmd := range c.SubCommands {
-			if i == 0 {
-				fmt.Fprintln(w)
+	if len(c.SubCommands) > 0 || c.SubCommandsFunc != nil {
+		flagHelp(w, c.Name, "subcommand ...", opts)
+		c.fprintf(w, "Known sub commands:\n")
+		for _, cat := range c.categories() {
+			fmt.Fprintln(w)
+			if cat.name != "" {
+				c.fprintf(w, "%s:\n", cat.name)
+			}
+			for _, subcmd := range cat.cmds {
+				help := subcmd.Help
+				if subcmd.Deprecated != "" {
+					help = c.sprintf("%s (deprecated)", help)
+				}
+				fmt.Fprintf(w, "   %s  %s\n", subcmd.Name, help)
+			}
+		}
+		if topics := c.topics(); len(topics) > 0 {
+			c.fprintf(w, "\nAdditional help topics:\n")
+			for _, tc := range topics {
+				fmt.Fprintf(w, "   %s  %s\n", tc.Name, tc.Help)
 			}
-			fmt.Fprintf(w, "   %s  %s\n", subcmd.Name, subcmd.Help)
 		}
 		return
 	}
-	flags.Help(w, c.Name, "", opts)
+	flagHelp(w, c.Name, "", opts)
+}
+
+// EffectiveStderr returns the writer c displays errors and help on.  It is
+// c.Stderr, if set, otherwise the effective Stderr of c's parent, or
+// os.Stderr for the root command.  The parent of a sub command is only known
+// once the sub command has been run or mounted.
+func (c *Command) EffectiveStderr() io.Writer {
+	return c.stderr()
 }
 
 func (c *Command) stderr() io.Writer {
@@ -394,12 +1130,41 @@ func (c *Command) stderr() io.Writer {
 	return stderr
 }
 
-func (c *Command) onError(err error) func(*Command, []string, []any, error) error {
-	if err == nil {
-		return nil
+func (c *Command) stdin() io.Reader {
+	for c != nil {
+		if c.Stdin != nil {
+			return c.Stdin
+		}
+		c = c.parent
 	}
-	if _, ok := err.(*UsageError); !ok {
+	return stdin
+}
+
+// EffectiveStdout returns the writer c should write its output to.  It is
+// c.Stdout, if set, otherwise the effective Stdout of c's parent, or
+// os.Stdout for the root command.  While Func is running with BufferOutput
+// the writer is a buffer.
+func (c *Command) EffectiveStdout() io.Writer {
+	return c.stdout()
+}
+
+func (c *Command) stdout() io.Writer {
+	for c != nil {
+		if c.outbuf != nil {
+			return c.outbuf
+		}
+		if c.Stdout != nil {
+			return c.Stdout
+		}
+		c = c.parent
 	}
+	return stdout
+}
+
+// EffectiveOnError returns the OnError func used by c.  It is c.OnError, if
+// set, otherwise the effective OnError of c's parent.  Nil is returned if
+// neither c nor any of its parents have an OnError func.
+func (c *Command) EffectiveOnError() func(*Command, []string, []any, error) error {
 	for c != nil {
 		if c.OnError != nil {
 			return c.OnError
@@ -409,6 +1174,49 @@ func (c *Command) onError(err error) func(*Command, []string, []any, error) erro
 	return nil
 }
 
+// onHelp calls the OnHelp in effect for c, whose ancestors, starting with
+// the root, are ancestors, after its help is written to w.  The parents of
+// commands reached by help are not set so ancestors is used instead.
+func (c *Command) onHelp(ancestors []*Command, w io.Writer) {
+	if c.OnHelp != nil {
+		c.OnHelp(c, w)
+		return
+	}
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		if fn := ancestors[i].OnHelp; fn != nil {
+			fn(c, w)
+			return
+		}
+	}
+}
+
+// EffectiveOnRunError returns the OnRunError func used by c.  It is
+// c.OnRunError, if set, otherwise the effective OnRunError of c's parent.
+// Nil is returned if neither c nor any of its parents have an OnRunError
+// func.
+func (c *Command) EffectiveOnRunError() func(*Command, []string, []any, error) error {
+	for c != nil {
+		if c.OnRunError != nil {
+			return c.OnRunError
+		}
+		c = c.parent
+	}
+	return nil
+}
+
+// onError returns the OnError, or OnRunError, func to call for err, or nil.
+func (c *Command) onError(err error) func(*Command, []string, []any, error) error {
+	if err == nil {
+		return nil
+	}
+	if Classify(err) != UsageClass {
+		if fn := c.EffectiveOnRunError(); fn != nil {
+			return fn
+		}
+	}
+	return c.EffectiveOnError()
+}
+
 // HelpCmd is a sub command that calls the Help function.
 var HelpCmd = &Command{
 	Name: "help",
@@ -419,60 +1227,226 @@ var HelpCmd = &Command{
 // Help implements the help command.
 //
 //	Usage: help [subcommand [subcommand [...]]]
+//	       help --flag PATTERN
+//
+// The second form lists every command that declares a flag whose name
+// matches PATTERN, which is either a flag name or a pattern as accepted by
+// path.Match, e.g., "verbose" or "no-*".
 func Help(ctx context.Context, c *Command, args []string, extra ...any) error {
 	w := c.stderr()
 
 	if c.parent != nil {
 		c = c.parent
 	}
+	if pattern, rest, ok := flagSearchArg(args); ok {
+		if len(rest) > 0 {
+			return c.errorf("unexpected arguments: %s", strings.Join(rest, " "))
+		}
+		return c.writeFlagSearch(w, pattern)
+	}
+	return c.writeHelp(ctx, w, args)
+}
 
+// HelpText returns the help Help displays for c, or for the sub command of c
+// named by path, as a string.  The text is the same as what is displayed by
+// the help command.
+func (c *Command) HelpText(path ...string) (string, error) {
+	var buf bytes.Buffer
+	if err := c.writeHelp(context.Background(), &buf, path); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// UsageText returns the usage information PrintUsage writes as a string.
+func (c *Command) UsageText() string {
+	var buf bytes.Buffer
+	c.PrintUsage(&buf)
+	return buf.String()
+}
+
+// writeHelp writes the help for c, or the sub command of c named by args, to
+// w.  The OnHelp in effect for the command is then called.
+func (c *Command) writeHelp(ctx context.Context, w io.Writer, args []string) (err error) {
+	// ancestors are the commands above c, starting with the root.
+	var ancestors []*Command
+	for p := c.parent; p != nil; p = p.parent {
+		ancestors = append([]*Command{p}, ancestors...)
+	}
+	defer func() {
+		if err == nil {
+			c.onHelp(ancestors, w)
+		}
+	}()
+	ulf := c.usageLineFunc()
+	showAliases := c.Root().ShowAliases
+	prefix := c.Root().AllowPrefixMatch
+	leaf := c.Root().AllowLeafMatch
 	command := c.Name
-	for _, name := range args {
+	c.discover(ctx)
+	for i := 0; i < len(args); i++ {
+		name := args[i]
 		if len(c.SubCommands) == 0 {
-			return fmt.Errorf("%s has no subcommands", command)
+			return c.errorf("%s has no subcommands", command)
 		}
-		if c = c.findSub(name); c == nil {
-			return fmt.Errorf("%s has no subcommand %s", command, name)
+		sc, err := c.matchSub(name, prefix)
+		if err != nil {
+			return err
+		}
+		if sc == nil && leaf {
+			path, err := c.matchLeaf(ctx, name)
+			if err != nil {
+				return err
+			}
+			if path != nil {
+				args = append(append(args[:i:i], path...), args[i+1:]...)
+				name = args[i]
+				sc = c.findSub(name)
+			}
+		}
+		if sc == nil {
+			return c.errorf("%s has no subcommand %s", command, name)
+		}
+		ancestors = append(ancestors, c)
+		c = sc
+		c.discover(ctx)
+		if c.UsageLineFunc != nil {
+			ulf = c.UsageLineFunc
 		}
 		command += " " + name
 	}
+	if c.isTopic() {
+		c.writeTopic(w)
+		return nil
+	}
 	if len(c.SubCommands) == 0 {
-		c.printf("Usage: %s\n", flags.UsageLine(c.Name, c.parameters(), c.getFlags()))
+		c.heading(w, "Usage: %s\n", c.usageLine(c.parameters(), ulf))
+		if showAliases && len(c.Aliases) > 0 {
+			c.fprintf(w, "Aliases: %s\n", strings.Join(c.Aliases, ", "))
+		}
+		if len(c.Requires) > 0 {
+			c.fprintf(w, "Requires: %s\n", strings.Join(c.Requires, ", "))
+		}
+		if c.Deprecated != "" {
+			c.fprintf(w, "Deprecated: %s\n", c.Deprecated)
+		}
 		if d := c.description(); d != "" {
-			c.printf("%s\n", indent.String("    ", d))
+			c.fprintf(w, "%s\n", indent.String("    ", d))
 			if c.getFlags() != nil {
-				c.printf("\n")
+				c.fprintf(w, "\n")
 			}
 		}
-		flags.Help(indent.NewWriter(w, "  "), "", "", c.getFlags())
+		flagHelp(indent.NewWriter(w, "  "), "", "", c.getFlags())
+		c.printStandardFlags(w)
+		c.printGlobalOptions(w, ancestors)
+		c.printExamples(w)
 		return nil
 	}
-	c.printf("Usage: %s\n", flags.UsageLine(c.Name, "subcommand [...]", c.getFlags()))
+	c.heading(w, "Usage: %s\n", c.usageLine("subcommand [...]", ulf))
+	if showAliases && len(c.Aliases) > 0 {
+		c.fprintf(w, "Aliases: %s\n", strings.Join(c.Aliases, ", "))
+	}
+	if len(c.Requires) > 0 {
+		c.fprintf(w, "Requires: %s\n", strings.Join(c.Requires, ", "))
+	}
+	if c.Deprecated != "" {
+		c.fprintf(w, "Deprecated: %s\n", c.Deprecated)
+	}
 	if d := c.description(); d != "" {
-		c.printf("%s\n", indent.String("    ", d))
+		c.fprintf(w, "%s\n", indent.String("    ", d))
 		if c.getFlags() != nil {
-			c.printf("\n")
+			c.fprintf(w, "\n")
 		}
 	}
-	flags.Help(indent.NewWriter(w, "  "), "", "", c.getFlags())
-	sc := c.SubCommands
-	sort.Slice(sc, func(i, j int) bool { return sc[i].Name < sc[j].Name })
-	c.printf("\nAvailable sub commands:")
-	for _, sc := range c.SubCommands {
+	flagHelp(indent.NewWriter(w, "  "), "", "", c.getFlags())
+	c.printStandardFlags(w)
+	c.printGlobalOptions(w, ancestors)
+	c.printExamples(w)
+	for _, cat := range c.categories() {
+		if cat.name == "" {
+			c.heading(w, "\nAvailable sub commands:")
+		} else {
+			c.heading(w, "\n%s:", cat.name)
+		}
+		c.printSubCommands(w, cat.cmds, ulf, showAliases)
+	}
+	c.printTopics(w)
+	return nil
+}
+
+// printSubCommands writes the usage line and description of each command in
+// cmds to w for help.
+func (c *Command) printSubCommands(w io.Writer, cmds []*Command, ulf func(*Command) string, showAliases bool) {
+	for _, sc := range cmds {
 		parameters := sc.parameters()
 		if parameters == "" && len(sc.SubCommands) > 0 {
 			parameters = "subcommand [...]"
 		}
-		c.printf("\n%s\n", indent.String("  ", flags.UsageLine(sc.Name, parameters, sc.getFlags())))
+		sulf := ulf
+		if sc.UsageLineFunc != nil {
+			sulf = sc.UsageLineFunc
+		}
+		c.fprintf(w, "\n%s\n", indent.String("  ", sc.usageLine(parameters, sulf)))
 		if d := sc.description(); d != "" {
-			c.printf("%s\n", indent.String("    ", d))
+			c.fprintf(w, "%s\n", indent.String("    ", d))
 		} else if sc.Help != "" {
-			c.printf("%s\n", indent.String("    ", sc.Help))
+			c.fprintf(w, "%s\n", indent.String("    ", sc.Help))
+		}
+		if showAliases && len(sc.Aliases) > 0 {
+			c.fprintf(w, "    Aliases: %s\n", strings.Join(sc.Aliases, ", "))
+		}
+		if sc.Deprecated != "" {
+			c.fprintf(w, "    Deprecated: %s\n", sc.Deprecated)
+		}
+	}
+}
+
+// usageLineFunc returns the UsageLineFunc c inherits, if any.
+func (c *Command) usageLineFunc() func(*Command) string {
+	for ; c != nil; c = c.parent {
+		if c.UsageLineFunc != nil {
+			return c.UsageLineFunc
 		}
 	}
 	return nil
 }
 
+// usageLine returns the usage line for c using ulf, if not nil.  Otherwise
+// the usage line is generated from parameters and the flags of c.
+func (c *Command) usageLine(parameters string, ulf func(*Command) string) string {
+	if ulf != nil {
+		return ulf(c)
+	}
+	return flags.UsageLine(c.Name, parameters, c.getFlags())
+}
+
+// printGlobalOptions writes the flags of each of c's ancestors, starting with
+// the root, to w.  Each set of flags is labeled with the command that owns
+// them as they must appear before the name of the sub command.
+func (c *Command) printGlobalOptions(w io.Writer, ancestors []*Command) {
+	var names []string
+	for _, a := range ancestors {
+		names = append(names, a.Name)
+		if a.getFlags() == nil && len(a.standardFlags()) == 0 {
+			continue
+		}
+		c.heading(w, "\nGlobal options (%s):\n", strings.Join(names, " "))
+		flagHelp(indent.NewWriter(w, "  "), "", "", a.getFlags())
+		a.printStandardFlags(w)
+	}
+}
+
+// printExamples writes c's examples, if any, to w.
+func (c *Command) printExamples(w io.Writer) {
+	if len(c.Examples) == 0 {
+		return
+	}
+	c.heading(w, "\nExamples:\n")
+	for _, ex := range c.Examples {
+		c.fprintf(w, "%s\n", indent.String("    ", displayExample(ex)))
+	}
+}
+
 type helper struct {
 	c *Command
 }
@@ -492,17 +1466,29 @@ func (c *Command) parameters() string {
 	if c.Parameters != "" {
 		return c.Parameters
 	}
-	if c.MaxArgs == NoArgs {
+	min, max, err := c.arity()
+	if c.Arity == "" {
+		// The usage of commands that use MinArgs and MaxArgs is
+		// unchanged by the introduction of Arity.
+		if c.MaxArgs == NoArgs {
+			return ""
+		}
+		max = min
+		if c.MaxArgs == 0 || c.MaxArgs < c.MinArgs {
+			max = -1
+		}
+	}
+	if err != nil || max == 0 {
 		return ""
 	}
 	var b strings.Builder
-	for i := 0; i < c.MinArgs; i++ {
+	for i := 0; i < min; i++ {
 		fmt.Fprintf(&b, " arg%d", i)
 	}
-	if c.MaxArgs == 0 || c.MaxArgs < c.MinArgs {
+	if max != min {
 		fmt.Fprintf(&b, " ...")
 	}
-	return b.String()[1:]
+	return strings.TrimPrefix(b.String(), " ")
 }
 
 func (h *helper) Set(s string) {}
@@ -19,6 +19,8 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -234,7 +236,7 @@ func TestExitOnError(t *testing.T) {
 	defer func() {
 		mainCommand.OnError = nil
 		got := output.String()
-		want := "main: bob: unknown command\n"
+		want := "main: \"bob\": unknown command\n"
 		if got != want {
 			t.Errorf("got output %q, want %q", got, want)
 		}
@@ -271,7 +273,7 @@ func TestContinueOnError(t *testing.T) {
 		t.Errorf("Unexpected error: %v", err)
 	}
 	got := output.String()
-	want := "main: bob: unknown command\n"
+	want := "main: \"bob\": unknown command\n"
 	if got != want {
 		t.Errorf("Got %q, want %q", got, want)
 	}
@@ -388,7 +390,7 @@ func TestArgs(t *testing.T) {
 		MaxArgs: NoArgs,
 	}
 	err := cmd.Run(nil, []string{"arg"})
-	want := "test: takes no arguments"
+	want := `test: takes no arguments, got 1: ["arg"]`
 	if err == nil {
 		t.Errorf("Did not get error %s", want)
 	} else if got := err.Error(); got != want {
@@ -397,7 +399,7 @@ func TestArgs(t *testing.T) {
 	cmd.MinArgs = 1
 	cmd.MaxArgs = 2
 	err = cmd.Run(nil, nil)
-	want = "test: requires at least 1 arguments"
+	want = "test: requires at least 1 arguments, got 0"
 	if err == nil {
 		t.Errorf("Did not get error %s", want)
 	} else if got := err.Error(); got != want {
@@ -405,7 +407,7 @@ func TestArgs(t *testing.T) {
 	}
 
 	err = cmd.Run(nil, []string{"1", "2", "3"})
-	want = "test: takes no more than 2 arguments"
+	want = `test: takes no more than 2 arguments, got 3: ["1" "2" "3"]`
 	if err == nil {
 		t.Errorf("Did not get error %s", want)
 	} else if got := err.Error(); got != want {
@@ -507,4 +509,600 @@ func TestHelp(t *testing.T) {
 	}
 }
 
+func TestHelpText(t *testing.T) {
+	ctx := context.Background()
+
+	output.Reset()
+	Help(ctx, mainCommand, []string{"foo"})
+	want := output.String()
+	got, err := mainCommand.HelpText("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("HelpText got:\n%s\nwant:\n%s", got, want)
+	}
+	_, err = mainCommand.HelpText("bad")
+	if s := check.Error(err, "main has no subcommand bad"); s != "" {
+		t.Error(s)
+	}
+
+	output.Reset()
+	mainCommand.PrintUsage(&output)
+	if got, want := mainCommand.UsageText(), output.String(); got != want {
+		t.Errorf("UsageText got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	var ran []string
+	record := func(_ context.Context, c *Command, args []string, _ ...any) error {
+		ran = append(ran, c.Command()+" "+strings.Join(args, " "))
+		return nil
+	}
+	get := &Command{
+		Name:        "get",
+		Func:        record,
+		SubCommands: []*Command{{Name: "all", Func: record}},
+		Dispatch: func(ctx context.Context, c *Command, args []string, extra ...any) (bool, error) {
+			if len(args) > 0 && c.findSub(args[0]) != nil {
+				return false, nil
+			}
+			return true, c.RunFunc(ctx, args, extra...)
+		},
+	}
+	root := &Command{Name: "prog", SubCommands: []*Command{get}}
+	ctx := context.Background()
+	for _, args := range [][]string{{"get", "pods"}, {"get", "all", "x"}, {"get"}} {
+		if err := root.Run(ctx, args); err != nil {
+			t.Errorf("%v: %v", args, err)
+		}
+	}
+	want := []string{"prog get pods", "prog get all x", "prog get "}
+	if strings.Join(ran, "|") != strings.Join(want, "|") {
+		t.Errorf("got %q, want %q", ran, want)
+	}
+}
+
+func TestDefaultSubCommand(t *testing.T) {
+	var ran []string
+	record := func(_ context.Context, c *Command, args []string, _ ...any) error {
+		ran = append(ran, c.Command())
+		return nil
+	}
+	root := &Command{
+		Name:              "prog",
+		DefaultSubCommand: "status",
+		SubCommands: []*Command{
+			{Name: "status", Func: record},
+			{Name: "stop", Func: record},
+		},
+	}
+	ctx := context.Background()
+	if err := root.Run(ctx, nil); err != nil {
+		t.Error(err)
+	}
+	if err := root.Run(ctx, []string{"stop"}); err != nil {
+		t.Error(err)
+	}
+	if err := root.RunSubcommands(ctx, nil); err != nil {
+		t.Error(err)
+	}
+	want := []string{"prog status", "prog stop", "prog status"}
+	if strings.Join(ran, "|") != strings.Join(want, "|") {
+		t.Errorf("got %q, want %q", ran, want)
+	}
+	if c, _, err := root.Resolve(nil); err != nil || c != root.SubCommands[0] {
+		t.Errorf("Resolve got %v, %v, want prog status", c, err)
+	}
+
+	root.DefaultSubCommand = "bad"
+	if s := check.Error(root.RunSubcommands(ctx, nil), `prog: "bad": unknown command`); s != "" {
+		t.Error(s)
+	}
+	errs := root.Validate()
+	if len(errs) != 1 || errs[0].Error() != "prog: DefaultSubCommand bad is not a sub command" {
+		t.Errorf("Validate got %v", errs)
+	}
+}
+
+func TestNormalizeArgs(t *testing.T) {
+	var got []string
+	root := &Command{
+		Name: "prog",
+		NormalizeArgs: func(args []string) []string {
+			if len(args) > 0 && args[0] == "rm" {
+				return append([]string{"remove"}, args[1:]...)
+			}
+			return args
+		},
+		SubCommands: []*Command{{
+			Name: "remove",
+			Func: func(_ context.Context, _ *Command, args []string, _ ...any) error {
+				got = args
+				return nil
+			},
+		}},
+	}
+	if err := root.Run(context.Background(), []string{"rm", "x"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0] != "x" {
+		t.Errorf("got args %q, want [x]", got)
+	}
+	if c, args, err := root.Resolve([]string{"rm", "y"}); err != nil || c.Name != "remove" || len(args) != 1 || args[0] != "y" {
+		t.Errorf("Resolve got %v, %q, %v", c, args, err)
+	}
+}
+
+func TestOnUnknownCommand(t *testing.T) {
+	var got []string
+	root := &Command{
+		Name:        "prog",
+		SubCommands: []*Command{{Name: "list", Func: func(context.Context, *Command, []string, ...any) error { return nil }}},
+		OnUnknownCommand: func(_ context.Context, c *Command, name string, args []string) error {
+			got = append([]string{c.Name, name}, args...)
+			return nil
+		},
+	}
+	if err := root.Run(context.Background(), []string{"host1", "ping"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "prog host1 ping"; strings.Join(got, " ") != want {
+		t.Errorf("got %q, want %q", strings.Join(got, " "), want)
+	}
+	if c, args, err := root.Resolve([]string{"host2"}); err != nil || c != root || len(args) != 1 || args[0] != "host2" {
+		t.Errorf("Resolve got %v, %q, %v", c, args, err)
+	}
+}
+
+func TestSubCommandsFunc(t *testing.T) {
+	calls := 0
+	ran := false
+	plugins := &Command{
+		Name: "plugins",
+		SubCommandsFunc: func(context.Context) []*Command {
+			calls++
+			return []*Command{{
+				Name: "hello",
+				Help: "say hello",
+				Func: func(context.Context, *Command, []string, ...any) error { ran = true; return nil },
+			}}
+		},
+	}
+	var buf bytes.Buffer
+	root := &Command{Name: "prog", Stderr: &buf, SubCommands: []*Command{plugins}}
+	if calls != 0 {
+		t.Fatalf("SubCommandsFunc called at declaration")
+	}
+	plugins.PrintUsage(&buf)
+	if calls != 0 {
+		t.Fatalf("SubCommandsFunc called by PrintUsage")
+	}
+	if !strings.Contains(buf.String(), "subcommand ...") {
+		t.Errorf("usage before discovery does not show sub commands:\n%s", buf.String())
+	}
+	buf.Reset()
+	ctx := context.Background()
+	if err := root.Run(ctx, []string{"plugins", "hello"}); err != nil || !ran {
+		t.Errorf("got %v, ran %v", err, ran)
+	}
+	if err := Help(ctx, root, []string{"plugins"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "say hello") {
+		t.Errorf("help does not list discovered command:\n%s", buf.String())
+	}
+	buf.Reset()
+	plugins.PrintUsage(&buf)
+	if !strings.Contains(buf.String(), "say hello") {
+		t.Errorf("usage does not list discovered command:\n%s", buf.String())
+	}
+	if calls != 1 {
+		t.Errorf("SubCommandsFunc called %d times, want 1", calls)
+	}
+}
+
+func TestUsageLineFunc(t *testing.T) {
+	var buf bytes.Buffer
+	root := &Command{
+		Name:   "prog",
+		Stderr: &buf,
+		UsageLineFunc: func(c *Command) string {
+			return c.Name + " [options]"
+		},
+		Defaults: &struct {
+			Verbose bool `flag:"-v be verbose"`
+		}{},
+		SubCommands: []*Command{
+			{Name: "a", Func: func(context.Context, *Command, []string, ...any) error { return nil }},
+			{
+				Name:          "b",
+				UsageLineFunc: func(c *Command) string { return "B" },
+				Func:          func(context.Context, *Command, []string, ...any) error { return nil },
+			},
+		},
+	}
+	ctx := context.Background()
+	if err := Help(ctx, root, nil); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	for _, want := range []string{"Usage: prog [options]\n", "\n  a [options]\n", "\n  B\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("help does not contain %q:\n%s", want, got)
+		}
+	}
+	buf.Reset()
+	if err := Help(ctx, root, []string{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "Usage: a [options]\n"; !strings.HasPrefix(got, want) {
+		t.Errorf("got %q, want prefix %q", got, want)
+	}
+}
+
+func TestHelpGlobalOptions(t *testing.T) {
+	var buf bytes.Buffer
+	leaf := &Command{
+		Name: "leaf",
+		Defaults: &struct {
+			Force bool `flag:"--force overwrite"`
+		}{},
+		Func: func(context.Context, *Command, []string, ...any) error { return nil },
+	}
+	root := &Command{
+		Name:   "prog",
+		Stderr: &buf,
+		Defaults: &struct {
+			Config string `flag:"--config=FILE read FILE"`
+		}{},
+		SubCommands: []*Command{
+			{Name: "mid", SubCommands: []*Command{leaf, HelpCmd}},
+		},
+	}
+	if err := root.Run(context.Background(), []string{"mid", "help", "leaf"}); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	own := strings.Index(got, "--force")
+	global := strings.Index(got, "Global options (prog):\n")
+	config := strings.Index(got, "--config=FILE")
+	if own < 0 || global < own || config < global {
+		t.Errorf("global options not listed after own options:\n%s", got)
+	}
+	if strings.Contains(got, "Global options (prog mid)") {
+		t.Errorf("listed options for mid, which has none:\n%s", got)
+	}
+}
+
 // RubSubCommand, findSub, Help,
+
+type stderrCheck struct {
+	c    *Command
+	want io.Writer
+	t    *testing.T
+}
+
+func (s *stderrCheck) String() string { return "" }
+func (s *stderrCheck) Set(string) error {
+	if s.c.Stderr != s.want {
+		s.t.Errorf("Stderr changed during parse: got %v, want %v", s.c.Stderr, s.want)
+	}
+	return nil
+}
+
+func TestParseStderr(t *testing.T) {
+	var buf bytes.Buffer
+	opts := &struct {
+		Check stderrCheck `flag:"--check"`
+	}{}
+	cmd := &Command{
+		Name:   "parse",
+		Flags:  opts,
+		Stderr: &buf,
+	}
+	opts.Check = stderrCheck{c: cmd, want: &buf, t: t}
+	if err := cmd.Run(nil, []string{"--check=x"}); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if cmd.Stderr != &buf {
+		t.Errorf("Stderr not preserved")
+	}
+}
+
+func FuzzResolve(f *testing.F) {
+	f.Add("leaf a b")
+	f.Add("--name x group sub -v y")
+	f.Add("group")
+	f.Add("-- leaf")
+	f.Add("help group sub")
+	f.Fuzz(func(t *testing.T, line string) {
+		var ran *Command
+		var got []string
+		record := func(_ context.Context, c *Command, args []string, _ ...any) error {
+			ran, got = c, args
+			return nil
+		}
+		sub := &Command{
+			Name: "sub",
+			Defaults: &struct {
+				V bool `flag:"-v"`
+			}{},
+			Func: record,
+		}
+		root := &Command{
+			Name:     "root",
+			Stderr:   io.Discard,
+			Defaults: &struct{ Name string }{},
+			SubCommands: []*Command{
+				{Name: "leaf", MaxArgs: 2, Func: record},
+				{Name: "group", SubCommands: []*Command{sub}},
+				HelpCmd,
+			},
+		}
+		args := strings.Split(line, " ")
+		if err := root.Run(context.Background(), args); err != nil || ran == nil {
+			return
+		}
+		// The resolved command must have been given a suffix of the
+		// original arguments.
+		if len(got) > len(args) {
+			t.Fatalf("%q: %s got more arguments than provided: %q", args, ran.Command(), got)
+		}
+		if want := args[len(args)-len(got):]; fmt.Sprintf("%q", got) != fmt.Sprintf("%q", want) {
+			t.Fatalf("%q: %s got arguments %q, want %q", args, ran.Command(), got, want)
+		}
+	})
+}
+
+func TestEffectiveStderrOnError(t *testing.T) {
+	var rootOut, quietOut bytes.Buffer
+	var handled []string
+	handler := func(c *Command, _ []string, _ []any, err error) error {
+		handled = append(handled, c.Name)
+		return nil
+	}
+	quiet := &Command{
+		Name:   "quiet",
+		Stderr: &quietOut,
+		Arity:  "0",
+		Func:   func(context.Context, *Command, []string, ...any) error { return nil },
+	}
+	loud := &Command{
+		Name:  "loud",
+		Arity: "0",
+		Func:  func(context.Context, *Command, []string, ...any) error { return nil },
+	}
+	root := &Command{
+		Name:        "prog",
+		Stderr:      &rootOut,
+		OnError:     handler,
+		SubCommands: []*Command{quiet, loud},
+	}
+	ctx := context.Background()
+	if err := root.Run(ctx, []string{"quiet", "x"}); err != nil {
+		t.Errorf("quiet: got error %v, want handled", err)
+	}
+	if quiet.EffectiveStderr() != &quietOut {
+		t.Errorf("quiet: wrong effective Stderr")
+	}
+	if quietOut.Len() == 0 || rootOut.Len() != 0 {
+		t.Errorf("quiet: error not written to its own Stderr")
+	}
+	if err := root.Run(ctx, []string{"loud", "x"}); err != nil {
+		t.Errorf("loud: got error %v, want handled", err)
+	}
+	if loud.EffectiveStderr() != &rootOut {
+		t.Errorf("loud: did not inherit Stderr")
+	}
+	if rootOut.Len() == 0 {
+		t.Errorf("loud: error not written to the root's Stderr")
+	}
+	if want := []string{"quiet", "loud"}; !reflect.DeepEqual(handled, want) {
+		t.Errorf("handled %q, want %q", handled, want)
+	}
+	if quiet.EffectiveOnError() == nil {
+		t.Errorf("OnError not inherited")
+	}
+	if (&Command{}).EffectiveOnError() != nil {
+		t.Errorf("got an OnError without one set")
+	}
+}
+
+func TestAliases(t *testing.T) {
+	var buf bytes.Buffer
+	var ran string
+	list := &Command{
+		Name:    "list",
+		Help:    "list things",
+		Aliases: []string{"ls", "l"},
+		Func: func(_ context.Context, c *Command, _ []string, _ ...any) error {
+			ran = c.Name
+			return nil
+		},
+	}
+	root := &Command{
+		Name:        "prog",
+		Stderr:      &buf,
+		Stdout:      &buf,
+		SubCommands: []*Command{list, WhichCmd},
+	}
+	ctx := context.Background()
+	for _, name := range []string{"list", "ls", "l"} {
+		ran = ""
+		if err := root.Run(ctx, []string{name}); err != nil {
+			t.Errorf("%s: %v", name, err)
+		} else if ran != "list" {
+			t.Errorf("%s: ran %q, want list", name, ran)
+		}
+	}
+
+	Help(ctx, root, nil)
+	if strings.Contains(buf.String(), "Aliases") {
+		t.Errorf("aliases shown without ShowAliases:\n%s", buf.String())
+	}
+	root.ShowAliases = true
+	buf.Reset()
+	Help(ctx, root, nil)
+	if want := "  list ...\n    list things\n    Aliases: ls, l\n"; !strings.Contains(buf.String(), want) {
+		t.Errorf("help does not contain %q:\n%s", want, buf.String())
+	}
+	buf.Reset()
+	Help(ctx, root, []string{"ls"})
+	if want := "Usage: list ...\nAliases: ls, l\n"; !strings.HasPrefix(buf.String(), want) {
+		t.Errorf("help for ls does not start with %q:\n%s", want, buf.String())
+	}
+
+	buf.Reset()
+	if err := root.Run(ctx, []string{"which", "ls"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := `"ls" is an alias of sub command list`; !strings.Contains(buf.String(), want) {
+		t.Errorf("which does not contain %q:\n%s", want, buf.String())
+	}
+}
+
+func TestPrefixMatch(t *testing.T) {
+	var ran string
+	record := func(_ context.Context, c *Command, _ []string, _ ...any) error {
+		ran = c.Name
+		return nil
+	}
+	var buf bytes.Buffer
+	root := &Command{
+		Name:             "prog",
+		Stderr:           &buf,
+		AllowPrefixMatch: true,
+		SubCommands: []*Command{
+			{Name: "status", Func: record},
+			{Name: "stop", Func: record},
+			{Name: "list", Aliases: []string{"ls"}, Func: record},
+			{Name: "debug", Hidden: true, Func: record},
+		},
+	}
+	ctx := context.Background()
+	for _, tt := range []struct {
+		arg, want, err string
+	}{
+		{arg: "stat", want: "status"},
+		{arg: "sto", want: "stop"},
+		{arg: "l", want: "list"},
+		{arg: "status", want: "status"},
+		{arg: "debug", want: "debug"},
+		{arg: "st", err: `prog: "st" is ambiguous {status, stop}`},
+		{arg: "de", err: `prog: "de": unknown command`},
+	} {
+		ran = ""
+		err := root.Run(ctx, []string{tt.arg})
+		if s := check.Error(err, tt.err); s != "" {
+			t.Errorf("%s: %s", tt.arg, s)
+		}
+		if ran != tt.want {
+			t.Errorf("%s: ran %q, want %q", tt.arg, ran, tt.want)
+		}
+	}
+	root.AllowPrefixMatch = false
+	if err := root.Run(ctx, []string{"stat"}); err == nil {
+		t.Errorf("prefix matched when not allowed")
+	}
+}
+
+func TestHidden(t *testing.T) {
+	var buf bytes.Buffer
+	noop := func(context.Context, *Command, []string, ...any) error { return nil }
+	root := &Command{
+		Name:   "prog",
+		Stderr: &buf,
+		SubCommands: []*Command{
+			{Name: "shown", Help: "a shown command", Func: noop},
+			{Name: "debug", Help: "a hidden command", Hidden: true, Func: noop},
+		},
+	}
+	ctx := context.Background()
+	if err := root.Run(ctx, []string{"debug"}); err != nil {
+		t.Errorf("running hidden command: %v", err)
+	}
+	Help(ctx, root, nil)
+	root.PrintUsage(&buf)
+	if err := root.RunSubcommands(ctx, nil); err == nil || !strings.Contains(err.Error(), "{shown}") {
+		t.Errorf("got error %v, want one listing only shown", err)
+	}
+	got := buf.String()
+	if strings.Contains(got, "debug") {
+		t.Errorf("hidden command listed:\n%s", got)
+	}
+	if strings.Count(got, "a shown command") != 2 {
+		t.Errorf("shown command not listed twice:\n%s", got)
+	}
+	buf.Reset()
+	if err := Help(ctx, root, []string{"debug"}); err != nil || !strings.HasPrefix(buf.String(), "Usage: debug") {
+		t.Errorf("help for hidden command: %v:\n%s", err, buf.String())
+	}
+}
+
+func TestDeprecated(t *testing.T) {
+	var buf bytes.Buffer
+	ran := false
+	root := &Command{
+		Name:   "prog",
+		Stderr: &buf,
+		SubCommands: []*Command{
+			{
+				Name:       "ls",
+				Help:       "list things",
+				Deprecated: `use "list" instead`,
+				Func: func(context.Context, *Command, []string, ...any) error {
+					ran = true
+					return nil
+				},
+			},
+		},
+	}
+	ctx := context.Background()
+	if err := root.Run(ctx, []string{"ls"}); err != nil || !ran {
+		t.Fatalf("got error %v, ran %v", err, ran)
+	}
+	if got, want := buf.String(), "prog ls is deprecated: use \"list\" instead\n"; got != want {
+		t.Errorf("got warning %q, want %q", got, want)
+	}
+	buf.Reset()
+	Help(ctx, root, nil)
+	if want := "    list things\n    Deprecated: use \"list\" instead\n"; !strings.Contains(buf.String(), want) {
+		t.Errorf("help does not contain %q:\n%s", want, buf.String())
+	}
+	buf.Reset()
+	root.PrintUsage(&buf)
+	if want := "ls  list things (deprecated)\n"; !strings.Contains(buf.String(), want) {
+		t.Errorf("usage does not contain %q:\n%s", want, buf.String())
+	}
+}
+
+func TestPath(t *testing.T) {
+	var got []string
+	leaf := &Command{
+		Name: "leaf",
+		Func: func(_ context.Context, c *Command, _ []string, _ ...any) error {
+			for _, pc := range c.Path() {
+				got = append(got, pc.Name)
+			}
+			if c.Root().Name != "prog" || c.Parent().Name != "mid" {
+				t.Errorf("got root %s and parent %s, want prog and mid", c.Root().Name, c.Parent().Name)
+			}
+			return nil
+		},
+	}
+	root := &Command{
+		Name:        "prog",
+		SubCommands: []*Command{{Name: "mid", SubCommands: []*Command{leaf}}},
+	}
+	if root.Parent() != nil || root.Root() != root || len(root.Path()) != 1 {
+		t.Errorf("root: got parent %v, root %v, path %v", root.Parent(), root.Root(), root.Path())
+	}
+	if err := root.Run(context.Background(), []string{"mid", "leaf"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"prog", "mid", "leaf"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got path %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,281 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"github.com/pborman/flags"
+)
+
+// The groups of completion candidates.
+const (
+	completeCommand = "command" // a sub command
+	completeFlag    = "flag"    // a flag
+	completeFile    = "file"    // the shell should complete file names
+	completeExample = "example" // the arguments of an example
+)
+
+// A candidate is a single completion candidate.
+type candidate struct {
+	group string
+	value string
+	help  string
+}
+
+// CompletionCmd is a sub command that displays shell completion scripts.
+// It must be a sub command of the root command.  The scripts call its hidden
+// __complete sub command to find the candidates for the word being
+// completed.
+var CompletionCmd = &Command{
+	Name: "completion",
+	Help: "display a shell completion script",
+	Description: `
+Display a script that sets up completion of commands and flags for
+the named shell.  For example, add
+
+    source <(prog completion bash)
+
+to your ~/.bashrc, or
+
+    prog completion fish | source
+
+to your fish configuration.
+`,
+	SubCommands: []*Command{
+		{Name: "bash", Help: "display the bash completion script", Arity: "0", Func: completionScript},
+		{Name: "zsh", Help: "display the zsh completion script", Arity: "0", Func: completionScript},
+		{Name: "fish", Help: "display the fish completion script", Arity: "0", Func: completionScript},
+		completeCmd,
+	},
+}
+
+// completeCmd is the hidden __complete sub command of CompletionCmd.
+var completeCmd = &Command{Name: "__complete", Hidden: true, Func: complete}
+
+// completing returns true if the root command c ran the hidden __complete
+// command.  It is run by the shell, not the user, so the run is not recorded
+// in c's statistics or telemetry and no update notice is displayed.
+func (c *Command) completing() bool {
+	return c.ran == completeCmd
+}
+
+// completionScript displays the completion script for the shell named by c.
+func completionScript(ctx context.Context, c *Command, _ []string, _ ...any) error {
+	prog := c.Root().Name
+	script := map[string]string{
+		"bash": bashCompletion,
+		"zsh":  zshCompletion,
+		"fish": fishCompletion,
+	}[c.Name]
+	script = strings.NewReplacer("FUNC", shellName(prog), "PROG", prog).Replace(script)
+	c.fprintf(c.stdout(), "%s", script)
+	return nil
+}
+
+// shellName returns name with all characters that are not valid in a shell
+// function name replaced by underscores.
+func shellName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// complete implements the hidden __complete command.  args are the words on
+// the command line following the name of the program, the last of which is
+// the word being completed.  Each candidate is displayed on a line as its
+// group, its value, and its description separated by tabs.  The group is
+// one of command, flag, file, or example.  A file line has no value, it
+// means the shell should also complete file names.  An example line is a
+// template of the arguments of the command, from its Examples, offered when
+// no argument has been typed.
+func complete(ctx context.Context, c *Command, args []string, _ ...any) error {
+	w := c.stdout()
+	for _, cd := range c.Root().candidates(ctx, args) {
+		c.fprintf(w, "%s\t%s\t%s\n", cd.group, cd.value, oneLine(cd.help))
+	}
+	return nil
+}
+
+// oneLine returns s with tabs and newlines replaced by spaces.
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// candidates returns the completion candidates for the last word of args.
+// The preceding words select the command as they would when run.
+func (c *Command) candidates(ctx context.Context, args []string) []candidate {
+	cur := ""
+	if len(args) > 0 {
+		cur, args = args[len(args)-1], args[:len(args)-1]
+	}
+	prefix := c.AllowPrefixMatch
+	positional := false
+	bare := true // no arguments follow the name of c
+	c.discover(ctx)
+	_, set := c.newFlagSet()
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			positional = true
+		}
+		if positional {
+			break
+		}
+		bare = false
+		if strings.HasPrefix(arg, "-") {
+			if takesValue(set, arg) {
+				i++
+			}
+			continue
+		}
+		sc, _ := c.matchSub(arg, prefix)
+		if sc == nil {
+			positional = true
+			break
+		}
+		sc.parent = c
+		c = sc
+		bare = true
+		c.discover(ctx)
+		_, set = c.newFlagSet()
+	}
+
+	var cds []candidate
+	if strings.HasPrefix(cur, "-") && !positional {
+		for _, f := range c.flagNames() {
+			if strings.HasPrefix(f.value, cur) {
+				cds = append(cds, f)
+			}
+		}
+		return cds
+	}
+	if !positional {
+		for _, cat := range c.categories() {
+			for _, sc := range cat.cmds {
+				if strings.HasPrefix(sc.Name, cur) {
+					cds = append(cds, candidate{completeCommand, sc.Name, sc.Help})
+				}
+			}
+		}
+	}
+	if c.Func != nil && (positional || len(c.SubCommands) == 0) {
+		if bare && cur == "" {
+			for _, tmpl := range c.templates() {
+				cds = append(cds, candidate{completeExample, tmpl, "example"})
+			}
+		}
+		if _, max, err := c.arity(); err == nil && max != 0 {
+			cds = append(cds, candidate{group: completeFile})
+		}
+	}
+	return cds
+}
+
+// takesValue returns true if arg is a flag of set whose value is the next
+// argument.
+func takesValue(set flags.FlagSet, arg string) bool {
+	ls, ok := set.(flagLookuper)
+	if !ok {
+		return false
+	}
+	name, _, hasValue := strings.Cut(strings.TrimPrefix(arg[1:], "-"), "=")
+	f := ls.Lookup(name)
+	return f != nil && !hasValue && !isBoolFlag(f)
+}
+
+// flagNames returns the flags c accepts as completion candidates.
+func (c *Command) flagNames() []candidate {
+	var cds []candidate
+	dash := func(name string) string {
+		if len(name) == 1 {
+			return "-" + name
+		}
+		return "--" + name
+	}
+	if opts := c.getFlags(); opts != nil {
+		if _, fields, err := flagFields(opts); err == nil {
+			for _, f := range fields {
+				cds = append(cds, candidate{completeFlag, dash(f.name), f.help})
+			}
+		}
+	}
+	for _, sf := range c.standardFlags() {
+		for _, opt := range strings.Split(sf.opt, ", ") {
+			opt, _, _ = strings.Cut(opt, "=")
+			cds = append(cds, candidate{completeFlag, opt, sf.help})
+		}
+	}
+	return cds
+}
+
+const bashCompletion = `# bash completion for PROG
+_FUNC_complete() {
+	local cur="${COMP_WORDS[COMP_CWORD]}" group value help files=0
+	local -a values=() examples=()
+	while IFS=$'\t' read -r group value help; do
+		case $group in
+		file) files=1 ;;
+		example) examples+=("$value") ;;
+		*) values+=("$value") ;;
+		esac
+	done < <(PROG completion __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null)
+	COMPREPLY=($(compgen -W "${values[*]}" -- "$cur") "${examples[@]}")
+	if [ $files = 1 ] && [ ${#examples[@]} = 0 ]; then
+		COMPREPLY+=($(compgen -f -- "$cur"))
+	fi
+}
+complete -F _FUNC_complete PROG
+`
+
+const zshCompletion = `#compdef PROG
+_FUNC() {
+	local -a commands flags examples
+	local group value help files=0
+	while IFS=$'\t' read -r group value help; do
+		case $group in
+		command) commands+=("${value//:/\\:}:$help") ;;
+		flag) flags+=("${value//:/\\:}:$help") ;;
+		file) files=1 ;;
+		example) examples+=("$value") ;;
+		esac
+	done < <(PROG completion __complete "${(@)words[2,CURRENT]}" 2>/dev/null)
+	(( ${#commands} )) && _describe -t commands 'commands' commands
+	(( ${#flags} )) && _describe -t flags 'flags' flags
+	(( ${#examples} )) && compadd -Q -X examples -- "${examples[@]}"
+	(( files )) && _files
+}
+compdef _FUNC PROG
+`
+
+const fishCompletion = `# fish completion for PROG
+function __FUNC_complete
+	set -l args (commandline -opc)[2..-1] (commandline -ct)
+	for line in (PROG completion __complete $args 2>/dev/null)
+		set -l fields (string split \t -- $line)
+		switch $fields[1]
+			case file
+				__fish_complete_path (commandline -ct)
+			case '*'
+				printf '%s\t%s\n' $fields[2] $fields[3]
+		end
+	end
+end
+complete -c PROG -f -a '(__FUNC_complete)'
+`
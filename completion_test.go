@@ -0,0 +1,194 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func completionTree(out *bytes.Buffer) *Command {
+	noop := func(context.Context, *Command, []string, ...any) error { return nil }
+	return &Command{
+		Name:           "prog",
+		Stdout:         out,
+		VerbosityFlags: true,
+		SubCommands: []*Command{
+			{
+				Name: "list",
+				Help: "list things",
+				Flags: &struct {
+					All  bool   `flag:"-a list all things"`
+					Name string `flag:"--name=NAME only things named NAME"`
+				}{},
+				Arity: "0",
+				Func:  noop,
+			},
+			{Name: "load", Help: "load files", Func: noop},
+			{Name: "secret", Hidden: true, Func: noop},
+			CompletionCmd,
+		},
+	}
+}
+
+func TestComplete(t *testing.T) {
+	var out bytes.Buffer
+	root := completionTree(&out)
+	ctx := context.Background()
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{""}, "command\tcompletion\tdisplay a shell completion script\ncommand\tlist\tlist things\ncommand\tload\tload files\n"},
+		{[]string{"l"}, "command\tlist\tlist things\ncommand\tload\tload files\n"},
+		{[]string{"-"}, "flag\t-q\tonly display errors\nflag\t--quiet\tonly display errors\nflag\t-v\tdisplay additional output\nflag\t--verbose\tdisplay additional output\nflag\t--debug\tdisplay debugging information\n"},
+		{[]string{"list", "--n"}, "flag\t--name\tonly things named NAME\n"},
+		{[]string{"list", ""}, ""},
+		{[]string{"-v", "load", ""}, "file\t\t\n"},
+		{[]string{"load", "x", "-"}, "file\t\t\n"},
+		{[]string{"completion", ""}, "command\tbash\tdisplay the bash completion script\ncommand\tfish\tdisplay the fish completion script\ncommand\tzsh\tdisplay the zsh completion script\n"},
+	} {
+		out.Reset()
+		args := append([]string{"completion", "__complete"}, tt.args...)
+		if err := root.Run(ctx, args); err != nil {
+			t.Errorf("%q: %v", tt.args, err)
+			continue
+		}
+		if got := out.String(); got != tt.want {
+			t.Errorf("%q: got:\n%q\nwant:\n%q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestCompleteFlagValues(t *testing.T) {
+	var out bytes.Buffer
+	noop := func(context.Context, *Command, []string, ...any) error { return nil }
+	root := &Command{
+		Name:   "prog",
+		Stdout: &out,
+		Flags: &struct {
+			Profile string `flag:"--profile=NAME the profile to use"`
+			Dry     bool   `flag:"--dry-run do not make changes"`
+		}{},
+		StatsFile: filepath.Join(t.TempDir(), "stats"),
+		SubCommands: []*Command{
+			{Name: "deploy", Help: "deploy things", Func: noop},
+			{Name: "prod", Help: "production things", Func: noop},
+			CompletionCmd,
+		},
+	}
+	ctx := context.Background()
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--profile", "prod", "de"}, "command\tdeploy\tdeploy things\n"},
+		{[]string{"--profile=prod", "de"}, "command\tdeploy\tdeploy things\n"},
+		{[]string{"--dry-run", "pr"}, "command\tprod\tproduction things\n"},
+		{[]string{"--dry-run", "prod", "-"}, ""},
+	} {
+		out.Reset()
+		args := append([]string{"completion", "__complete"}, tt.args...)
+		if err := root.Run(ctx, args); err != nil {
+			t.Errorf("%q: %v", tt.args, err)
+			continue
+		}
+		if got := out.String(); got != tt.want {
+			t.Errorf("%q: got:\n%q\nwant:\n%q", tt.args, got, tt.want)
+		}
+	}
+	if _, err := os.Stat(root.StatsFile); err == nil {
+		t.Errorf("__complete was recorded in the statistics")
+	}
+}
+
+func TestCompletionScripts(t *testing.T) {
+	var out bytes.Buffer
+	root := completionTree(&out)
+	root.Name = "my-prog"
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		out.Reset()
+		if err := root.Run(context.Background(), []string{"completion", shell}); err != nil {
+			t.Fatalf("%s: %v", shell, err)
+		}
+		got := out.String()
+		if !strings.Contains(got, "my-prog completion __complete") || !strings.Contains(got, "my_prog") {
+			t.Errorf("%s: script not customized:\n%s", shell, got)
+		}
+	}
+}
+
+func TestCompleteExamples(t *testing.T) {
+	var out bytes.Buffer
+	noop := func(context.Context, *Command, []string, ...any) error { return nil }
+	root := &Command{
+		Name:   "prog",
+		Stdout: &out,
+		SubCommands: []*Command{
+			{
+				Name:    "copy",
+				Aliases: []string{"cp"},
+				Flags: &struct {
+					Mode string `flag:"--mode=MODE file mode"`
+				}{},
+				Func: noop,
+				Examples: []string{
+					"prog copy a b",
+					"prog copy --mode=<MODE> <SRC> <DST>",
+					`prog cp "<SRC FILE>" <DST>`,
+				},
+			},
+			{Name: "list", Func: noop, Examples: []string{"prog list"}},
+			CompletionCmd,
+		},
+	}
+	ctx := context.Background()
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"copy", ""}, "example\t--mode=<MODE> <SRC> <DST>\texample\nexample\t'<SRC FILE>' <DST>\texample\nfile\t\t\n"},
+		{[]string{"cp", ""}, "example\t--mode=<MODE> <SRC> <DST>\texample\nexample\t'<SRC FILE>' <DST>\texample\nfile\t\t\n"},
+		{[]string{"copy", "x"}, "file\t\t\n"},
+		{[]string{"copy", "a", ""}, "file\t\t\n"},
+		{[]string{"copy", "--mode=1", ""}, "file\t\t\n"},
+		{[]string{"list", ""}, "file\t\t\n"},
+	} {
+		out.Reset()
+		args := append([]string{"completion", "__complete"}, tt.args...)
+		if err := root.Run(ctx, args); err != nil {
+			t.Errorf("%q: %v", tt.args, err)
+			continue
+		}
+		if got := out.String(); got != tt.want {
+			t.Errorf("%q: got:\n%q\nwant:\n%q", tt.args, got, tt.want)
+		}
+	}
+
+	var ran []string
+	errs := root.CheckExamples(func(args []string) error {
+		ran = append(ran, strings.Join(args, " "))
+		return nil
+	})
+	if len(errs) != 0 {
+		t.Errorf("CheckExamples: %v", errs)
+	}
+	if got, want := strings.Join(ran, "|"), "copy a b|list"; got != want {
+		t.Errorf("CheckExamples ran %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,627 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pborman/flags"
+)
+
+// A Config is a set of flag values, normally read from a configuration file.
+// Each line of a configuration file is either blank, a comment starting with
+// #, or a setting of the form:
+//
+//	key = value
+//
+// The key is the path to a command, not including the root command, and the
+// name of one of its flags, all separated by periods.  The value may be
+// quoted using Go syntax.  Given a root command named main with a sub command
+// bar, the following configuration file
+//
+//	# Defaults for my program
+//	name = bob
+//	bar.value = 42
+//
+// is the same as running
+//
+//	main --name=bob bar --value=42
+//
+// Values in a configuration file are used as the defaults for flags, flags
+// provided on the command line take precedence.
+type Config struct {
+	Name  string // Name of the file the config was read from
+	lines []configLine
+}
+
+// A ConfigEntry is a single setting in a Config.
+type ConfigEntry struct {
+	Key   string
+	Value string
+	Line  int // Line number in the file, 0 if not read from a file
+}
+
+// A configLine is a line in a configuration file.  If entry is nil then
+// text is a blank line or comment.
+type configLine struct {
+	text  string
+	entry *ConfigEntry
+}
+
+// A flagSetter is a flags.FlagSet that can set the value of a flag by name.
+// The standard flag package's FlagSet is a flagSetter.
+type flagSetter interface {
+	Set(name, value string) error
+}
+
+// A ConfigError is an error in a configuration file.
+type ConfigError struct {
+	File string
+	Line int
+	Key  string
+	Err  error
+}
+
+// Implements the error interface.
+func (e *ConfigError) Error() string {
+	var b strings.Builder
+	if e.File != "" {
+		b.WriteString(e.File)
+		if e.Line > 0 {
+			fmt.Fprintf(&b, ":%d", e.Line)
+		}
+		b.WriteString(": ")
+	}
+	if e.Key != "" {
+		fmt.Fprintf(&b, "%s: ", e.Key)
+	}
+	b.WriteString(e.Err.Error())
+	return b.String()
+}
+
+func (e *ConfigError) Unwrap() error { return e.Err }
+
+// An errorList is an error made from one or more errors.
+type errorList []error
+
+func (e errorList) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// ReadConfig reads the configuration file at path.
+func ReadConfig(path string) (*Config, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+	return ParseConfig(path, fd)
+}
+
+// ParseConfig parses the configuration read from r.  The name is used in
+// error messages.
+func ParseConfig(name string, r io.Reader) (*Config, error) {
+	cfg := &Config{Name: name}
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		text := s.Text()
+		line := strings.TrimSpace(text)
+		if line == "" || line[0] == '#' {
+			cfg.lines = append(cfg.lines, configLine{text: text})
+			continue
+		}
+		x := strings.Index(line, "=")
+		if x < 0 {
+			return nil, &ConfigError{File: name, Line: n, Err: errors.New("missing =")}
+		}
+		key := strings.TrimSpace(line[:x])
+		if key == "" {
+			return nil, &ConfigError{File: name, Line: n, Err: errors.New("missing key")}
+		}
+		value := strings.TrimSpace(line[x+1:])
+		if strings.HasPrefix(value, `"`) {
+			v, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, &ConfigError{File: name, Line: n, Key: key, Err: fmt.Errorf("bad quoted value %s", value)}
+			}
+			value = v
+		}
+		cfg.lines = append(cfg.lines, configLine{text: text, entry: &ConfigEntry{Key: key, Value: value, Line: n}})
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Entries returns the settings in cfg in the order they appear.
+func (cfg *Config) Entries() []ConfigEntry {
+	if cfg == nil {
+		return nil
+	}
+	var entries []ConfigEntry
+	for _, l := range cfg.lines {
+		if l.entry != nil {
+			entries = append(entries, *l.entry)
+		}
+	}
+	return entries
+}
+
+// Lookup returns the setting for key in cfg.  If key is set more than once the
+// last setting is returned.
+func (cfg *Config) Lookup(key string) (ConfigEntry, bool) {
+	if cfg == nil {
+		return ConfigEntry{}, false
+	}
+	for i := len(cfg.lines) - 1; i >= 0; i-- {
+		if e := cfg.lines[i].entry; e != nil && e.Key == key {
+			return *e, true
+		}
+	}
+	return ConfigEntry{}, false
+}
+
+// Set sets key to value in cfg.  Any other settings of key are removed.
+func (cfg *Config) Set(key, value string) {
+	cfg.Unset(key)
+	cfg.lines = append(cfg.lines, configLine{entry: &ConfigEntry{Key: key, Value: value}})
+}
+
+// Unset removes all settings of key from cfg.  It returns false if key was
+// not set.
+func (cfg *Config) Unset(key string) bool {
+	found := false
+	lines := cfg.lines[:0]
+	for _, l := range cfg.lines {
+		if l.entry != nil && l.entry.Key == key {
+			found = true
+			continue
+		}
+		lines = append(lines, l)
+	}
+	cfg.lines = lines
+	return found
+}
+
+// WriteFile writes cfg to the file at path, replacing any existing file.  The
+// permissions of an existing file are preserved.  A new file is only
+// readable by its owner as it may hold secrets.
+func (cfg *Config) WriteFile(path string) error {
+	fd, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(fd.Name())
+	if fi, err := os.Stat(path); err == nil {
+		if err := fd.Chmod(fi.Mode().Perm()); err != nil {
+			fd.Close()
+			return err
+		}
+	}
+	if _, err := cfg.WriteTo(fd); err != nil {
+		fd.Close()
+		return err
+	}
+	if err := fd.Close(); err != nil {
+		return err
+	}
+	return os.Rename(fd.Name(), path)
+}
+
+// WriteTo writes cfg to w in the format read by ParseConfig.  Comments
+// and blank lines read by ParseConfig are preserved.
+func (cfg *Config) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	for _, l := range cfg.lines {
+		switch {
+		case l.entry == nil:
+			b.WriteString(l.text)
+		case l.text != "":
+			b.WriteString(l.text)
+		default:
+			fmt.Fprintf(&b, "%s = %s", l.entry.Key, quoteConfig(l.entry.Value))
+		}
+		b.WriteString("\n")
+	}
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// quoteConfig quotes v if it would not otherwise be read back as v.
+func quoteConfig(v string) string {
+	if v != strings.TrimSpace(v) || strings.HasPrefix(v, `"`) || strings.ContainsAny(v, "\n\r") {
+		return strconv.Quote(v)
+	}
+	return v
+}
+
+// configKey returns the key prefix used in a configuration file for the flags
+// of c.
+func (c *Command) configKey() string {
+	if c.parent == nil {
+		return ""
+	}
+	return c.parent.configKey() + c.Name + "."
+}
+
+// A DefaultsSource provides flag defaults for a command tree.  The
+// RemoteDefaults type, which is not available when built with the
+// commander_nonet build tag, is a DefaultsSource.
+type DefaultsSource interface {
+	Fetch(ctx context.Context) (*Config, error)
+}
+
+// loadConfig fetches c.RemoteDefaults and then reads the configuration file
+// named by c.ConfigFile and validates it against the tree rooted at c.  A
+// missing configuration file is not an error.  Any error is saved and
+// reported by configError so the commands in ConfigCmd can still be used to
+// fix a broken file.
+func (c *Command) loadConfig(ctx context.Context) {
+	c.config, c.configErr, c.remote = nil, nil, nil
+	if c.RemoteDefaults != nil {
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		cfg, err := c.RemoteDefaults.Fetch(ctx)
+		if err != nil {
+			c.printf("warning: remote defaults: %v\n", err)
+		}
+		c.remote = cfg
+	}
+	if c.ConfigFile == "" {
+		return
+	}
+	cfg, err := ReadConfig(c.ConfigFile)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		c.configErr = err
+	default:
+		if errs := c.ValidateConfig(cfg); len(errs) > 0 {
+			c.configErr = errorList(errs)
+			return
+		}
+		c.config = cfg
+	}
+}
+
+// configError returns the error, if any, encountered loading the
+// configuration file of c's root.  Errors are ignored by the commands in
+// ConfigCmd.
+func (c *Command) configError() error {
+	for p := c; p != nil; p = p.parent {
+		if p == ConfigCmd {
+			return nil
+		}
+	}
+	return c.Root().configErr
+}
+
+// applyConfig sets the flags in set to the values for c found in the remote
+// defaults and configuration file of c's root command.  Values from the
+// remote defaults are never interpolated.
+func (c *Command) applyConfig(ctx context.Context, set flags.FlagSet) error {
+	root := c.Root()
+	if root.remote == nil && root.config == nil {
+		return nil
+	}
+	_, fields, err := flagFields(c.Flags)
+	if err != nil {
+		return nil
+	}
+	setter, ok := set.(flagSetter)
+	if !ok {
+		return nil
+	}
+	prefix := c.configKey()
+	for _, f := range fields {
+		// Invalid remote defaults are ignored.
+		if e, ok := root.remote.Lookup(prefix + f.name); ok {
+			setter.Set(f.name, e.Value)
+		}
+		e, ok := root.config.Lookup(prefix + f.name)
+		if !ok {
+			continue
+		}
+		v, err := c.configValue(ctx, e.Value)
+		if err != nil {
+			return &ConfigError{File: root.config.Name, Line: e.Line, Key: e.Key, Err: err}
+		}
+		if err := setter.Set(f.name, v); err != nil {
+			return &ConfigError{File: root.config.Name, Line: e.Line, Key: e.Key, Err: fmt.Errorf("invalid value %q: %v", v, err)}
+		}
+	}
+	return nil
+}
+
+// configValue returns the value v from a configuration file, interpolated if
+// requested.
+func (c *Command) configValue(ctx context.Context, v string) (string, error) {
+	if !c.interpolating() {
+		return v, nil
+	}
+	return c.ExpandContext(ctx, v)
+}
+
+// newFlagSet returns a new copy of c's flags registered with a new flag set.
+// c.Flags is not changed.  nil, nil is returned if c has no flags.
+func (c *Command) newFlagSet() (any, flags.FlagSet) {
+	opts := c.getFlags()
+	if opts == nil {
+		if len(c.standardFlags()) == 0 {
+			return nil, nil
+		}
+		set := flags.NewFlagSet(c.Name)
+		set.SetOutput(io.Discard)
+		c.addStandardFlags(set)
+		return nil, set
+	}
+	opts = dupFlags(opts)
+	set := flags.NewFlagSet(c.Name)
+	set.SetOutput(io.Discard)
+	if err := registerFlags(opts, set); err != nil {
+		return nil, nil
+	}
+	c.addStandardFlags(set)
+	return opts, set
+}
+
+// configSchema returns a map of all keys that may be used in a configuration
+// file for the tree rooted at c.  The value of each key is the command that
+// declares the flag.
+func (c *Command) configSchema() map[string]*Command {
+	schema := map[string]*Command{}
+	var walk func(c *Command, prefix string)
+	walk = func(c *Command, prefix string) {
+		if opts := c.getFlags(); opts != nil {
+			if _, fields, err := flagFields(opts); err == nil {
+				for _, f := range fields {
+					schema[prefix+f.name] = c
+				}
+			}
+		}
+		for _, sc := range c.SubCommands {
+			walk(sc, prefix+sc.Name+".")
+		}
+	}
+	walk(c, "")
+	return schema
+}
+
+// ValidateConfig checks cfg against the flags declared by c and all of its
+// sub commands.  A *ConfigError is returned for each unknown key and for each
+// value that is not valid for its flag.
+func (c *Command) ValidateConfig(cfg *Config) []error {
+	schema := c.configSchema()
+	var errs []error
+	for _, e := range cfg.Entries() {
+		if err := c.checkConfig(schema, e.Key, e.Value); err != nil {
+			errs = append(errs, &ConfigError{File: cfg.Name, Line: e.Line, Key: e.Key, Err: err})
+		}
+	}
+	return errs
+}
+
+// reservedKeys are configuration keys used by commander itself rather than
+// for flags.  Each key maps to a function that validates its value.
+var reservedKeys = map[string]func(string) error{}
+
+// checkConfig returns an error if key is not in schema or value is not a
+// valid value for key.
+func (c *Command) checkConfig(schema map[string]*Command, key, value string) error {
+	if check, ok := reservedKeys[key]; ok {
+		return check(value)
+	}
+	cmd, ok := schema[key]
+	if !ok {
+		return c.errorf("unknown key")
+	}
+	if c.Interpolate && strings.Contains(value, "${") {
+		// The value can only be checked once expanded.
+		return nil
+	}
+	return validateValue(cmd, key[strings.LastIndex(key, ".")+1:], value)
+}
+
+// validateValue returns an error if value is not a valid value for c's flag
+// named name.
+func validateValue(c *Command, name, value string) error {
+	_, set := c.newFlagSet()
+	if setter, ok := set.(flagSetter); ok {
+		if err := setter.Set(name, value); err != nil {
+			return fmt.Errorf("invalid value %q: %v", value, err)
+		}
+	}
+	return nil
+}
+
+// ConfigCmd is a sub command for managing the configuration file named by
+// the ConfigFile field of the root command.  Keys and values are validated
+// against the flags of the root command's tree.
+var ConfigCmd = &Command{
+	Name: "config",
+	Help: "manage the configuration file",
+	SubCommands: []*Command{
+		configGetCmd,
+		configSetCmd,
+		configUnsetCmd,
+		configListCmd,
+		configEditCmd,
+		configValidateCmd,
+	},
+}
+
+var configGetCmd = &Command{
+	Name:       "get",
+	Help:       "display the value of a key",
+	Parameters: "KEY",
+	MinArgs:    1,
+	MaxArgs:    1,
+	Func: func(ctx context.Context, c *Command, args []string, _ ...any) error {
+		cfg, _, err := c.readConfig()
+		if err != nil {
+			return err
+		}
+		e, ok := cfg.Lookup(args[0])
+		if !ok {
+			return c.errorf("%s: not set", args[0])
+		}
+		fmt.Fprintln(c.stdout(), e.Value)
+		return nil
+	},
+}
+
+var configSetCmd = &Command{
+	Name:       "set",
+	Help:       "set the value of a key",
+	Parameters: "KEY VALUE",
+	MinArgs:    2,
+	MaxArgs:    2,
+	Func: func(ctx context.Context, c *Command, args []string, _ ...any) error {
+		cfg, path, err := c.readConfig()
+		if err != nil {
+			return err
+		}
+		key, value := args[0], args[1]
+		root := c.Root()
+		if err := root.checkConfig(root.configSchema(), key, value); err != nil {
+			return fmt.Errorf("%s: %v", key, err)
+		}
+		cfg.Set(key, value)
+		return cfg.WriteFile(path)
+	},
+}
+
+var configUnsetCmd = &Command{
+	Name:       "unset",
+	Help:       "remove a key",
+	Parameters: "KEY",
+	MinArgs:    1,
+	MaxArgs:    1,
+	Func: func(ctx context.Context, c *Command, args []string, _ ...any) error {
+		cfg, path, err := c.readConfig()
+		if err != nil {
+			return err
+		}
+		if !cfg.Unset(args[0]) {
+			return c.errorf("%s: not set", args[0])
+		}
+		return cfg.WriteFile(path)
+	},
+}
+
+var configListCmd = &Command{
+	Name:    "list",
+	Help:    "list all keys and values",
+	MaxArgs: NoArgs,
+	Func: func(ctx context.Context, c *Command, args []string, _ ...any) error {
+		cfg, _, err := c.readConfig()
+		if err != nil {
+			return err
+		}
+		for _, e := range cfg.Entries() {
+			fmt.Fprintf(c.stdout(), "%s = %s\n", e.Key, quoteConfig(e.Value))
+		}
+		return nil
+	},
+}
+
+var configEditCmd = &Command{
+	Name:    "edit",
+	Help:    "edit the configuration file",
+	MaxArgs: NoArgs,
+	Description: `
+Open the configuration file with $VISUAL or $EDITOR (defaults to vi)
+and validate it once the editor exits.
+`,
+	Func: func(ctx context.Context, c *Command, args []string, _ ...any) error {
+		_, path, err := c.readConfig()
+		if err != nil {
+			return err
+		}
+		editor := os.Getenv("VISUAL")
+		if editor == "" {
+			editor = os.Getenv("EDITOR")
+		}
+		if editor == "" {
+			editor = "vi"
+		}
+		words := strings.Fields(editor)
+		cmd := exec.CommandContext(ctx, words[0], append(words[1:], path)...)
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = c.stdin(), c.stdout(), c.stderr()
+		if err := cmd.Run(); err != nil {
+			return err
+		}
+		return configValidate(ctx, c, nil)
+	},
+}
+
+// readConfig reads the configuration file of c's root command.  An empty
+// configuration is returned if the file does not yet exist.
+func (c *Command) readConfig() (*Config, string, error) {
+	path := c.Root().ConfigFile
+	if path == "" {
+		return nil, "", &UsageError{C: c, Err: c.errorf("no configuration file")}
+	}
+	cfg, err := ReadConfig(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Config{Name: path}, path, nil
+	}
+	return cfg, path, err
+}
+
+var configValidateCmd = &Command{
+	Name:       "validate",
+	Help:       "validate a configuration file",
+	Parameters: "[FILE]",
+	MaxArgs:    1,
+	Description: `
+Check FILE, or the program's configuration file, for unknown keys
+and invalid values.
+`,
+	Func: configValidate,
+}
+
+func configValidate(ctx context.Context, c *Command, args []string, _ ...any) error {
+	root := c.Root()
+	path := root.ConfigFile
+	if len(args) > 0 {
+		path = args[0]
+	}
+	if path == "" {
+		return &UsageError{C: c, Err: c.errorf("no configuration file")}
+	}
+	cfg, err := ReadConfig(path)
+	if err != nil {
+		return err
+	}
+	if errs := root.ValidateConfig(cfg); len(errs) > 0 {
+		return errorList(errs)
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	in := `
+# A comment
+name = bob
+  bar.value=42
+quoted = "  spaced  "
+`[1:]
+	cfg, err := ParseConfig("test.cfg", strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := fmt.Sprintf("%v", cfg.Entries())
+	want := `[{name bob 2} {bar.value 42 3} {quoted   spaced   4}]`
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if e, ok := cfg.Lookup("bar.value"); !ok || e.Value != "42" {
+		t.Errorf("Lookup got %v, %v", e, ok)
+	}
+	var buf bytes.Buffer
+	cfg.WriteTo(&buf)
+	if buf.String() != in {
+		t.Errorf("WriteTo got:\n%s\nwant:\n%s", buf.String(), in)
+	}
+
+	for _, tt := range []struct {
+		in, err string
+	}{
+		{"name", "test.cfg:1: missing ="},
+		{"\n= x", "test.cfg:2: missing key"},
+		{`name = "x`, `test.cfg:1: name: bad quoted value "x`},
+	} {
+		_, err := ParseConfig("test.cfg", strings.NewReader(tt.in))
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("%q: got error %v, want %s", tt.in, err, tt.err)
+		}
+	}
+}
+
+func configTree() (*Command, *struct{ Value int }) {
+	var got struct{ Value int }
+	sub := &Command{
+		Name:     "sub",
+		Defaults: &struct{ Value int }{Value: 1},
+		Func: func(_ context.Context, c *Command, _ []string, _ ...any) error {
+			got.Value = c.Lookup("", "value").(int)
+			return nil
+		},
+	}
+	root := &Command{
+		Name:        "root",
+		Defaults:    &struct{ Name string }{},
+		SubCommands: []*Command{sub, ConfigCmd},
+	}
+	return root, &got
+}
+
+func TestConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config")
+	root, got := configTree()
+	root.ConfigFile = path
+	ctx := context.Background()
+
+	// A missing file is not an error
+	if err := root.Run(ctx, []string{"sub"}); err != nil {
+		t.Fatal(err)
+	}
+	if got.Value != 1 {
+		t.Errorf("got value %d, want 1", got.Value)
+	}
+
+	os.WriteFile(path, []byte("name = bob\nsub.value = 7\n"), 0644)
+	if err := root.Run(ctx, []string{"sub"}); err != nil {
+		t.Fatal(err)
+	}
+	if got.Value != 7 {
+		t.Errorf("got value %d, want 7", got.Value)
+	}
+	if name := root.Lookup("", "name"); name != "bob" {
+		t.Errorf("got name %q, want bob", name)
+	}
+	// The command line overrides the configuration file.
+	if err := root.Run(ctx, []string{"sub", "--value=9"}); err != nil {
+		t.Fatal(err)
+	}
+	if got.Value != 9 {
+		t.Errorf("got value %d, want 9", got.Value)
+	}
+
+	os.WriteFile(path, []byte("# bad values\nsub.value = seven\nsub.name = x\n"), 0644)
+	output.Reset()
+	err := root.Run(ctx, []string{"sub"})
+	want := path + `:2: sub.value: invalid value "seven": parse error` + "\n" + path + `:3: sub.name: unknown key`
+	if err == nil {
+		t.Fatalf("did not get error")
+	}
+	if got := err.Error(); got != want {
+		t.Errorf("got error:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good")
+	bad := filepath.Join(dir, "bad")
+	os.WriteFile(good, []byte("sub.value = 3\n"), 0644)
+	os.WriteFile(bad, []byte("sub.value = x\n"), 0644)
+	root, _ := configTree()
+	root.ConfigFile = filepath.Join(dir, "missing")
+	ctx := context.Background()
+	if err := root.Run(ctx, []string{"config", "validate", good}); err != nil {
+		t.Errorf("validate of good file failed: %v", err)
+	}
+	err := root.Run(ctx, []string{"config", "validate", bad})
+	if err == nil || !strings.HasPrefix(err.Error(), bad+":1: sub.value: ") {
+		t.Errorf("validate of bad file got %v", err)
+	}
+}
+
+func TestConfigCmd(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config")
+	root, _ := configTree()
+	root.ConfigFile = path
+	var out bytes.Buffer
+	root.Stdout = &out
+	ctx := context.Background()
+	run := func(args ...string) error {
+		out.Reset()
+		return root.Run(ctx, args)
+	}
+
+	if err := run("config", "set", "sub.value", "5"); err != nil {
+		t.Fatal(err)
+	}
+	if err := run("config", "set", "name", " padded "); err != nil {
+		t.Fatal(err)
+	}
+	if err := run("config", "get", "sub.value"); err != nil || out.String() != "5\n" {
+		t.Errorf("get got %q, %v", out.String(), err)
+	}
+	if err := run("config", "list"); err != nil || out.String() != "sub.value = 5\nname = \" padded \"\n" {
+		t.Errorf("list got %q, %v", out.String(), err)
+	}
+	if err := run("config", "set", "sub.value", "five"); err == nil {
+		t.Errorf("set of an invalid value did not fail")
+	}
+	if err := run("config", "set", "bad.key", "1"); err == nil {
+		t.Errorf("set of an unknown key did not fail")
+	}
+	if err := run("config", "unset", "name"); err != nil {
+		t.Fatal(err)
+	}
+	if err := run("config", "get", "name"); err == nil {
+		t.Errorf("get of an unset key did not fail")
+	}
+
+	// A broken configuration can still be repaired.
+	os.WriteFile(path, []byte("sub.value = five\n"), 0644)
+	if err := run("sub"); err == nil {
+		t.Errorf("broken configuration did not fail")
+	}
+	if err := run("config", "unset", "sub.value"); err != nil {
+		t.Fatal(err)
+	}
+	if err := run("sub"); err != nil {
+		t.Errorf("repaired configuration failed: %v", err)
+	}
+
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "true")
+	if err := run("config", "edit"); err != nil {
+		t.Errorf("edit failed: %v", err)
+	}
+}
+
+func TestConfigWriteFileMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte("name = bob\n"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	os.Chmod(path, 0640) // in case of a restrictive umask
+	cfg, err := ReadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Set("name", "alice")
+	if err := cfg.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fi.Mode().Perm(); got != 0640 {
+		t.Errorf("got mode %v, want %v", got, os.FileMode(0640))
+	}
+}
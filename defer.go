@@ -0,0 +1,81 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import "sync"
+
+// A deferList is the list of functions registered with Defer.
+type deferList struct {
+	mu  sync.Mutex
+	fns []func()
+}
+
+// Defer registers fn to be called when the current Run of c returns, after
+// c and any of its sub commands have finished, whether they succeeded or
+// not.  Functions are called in the reverse of the order they were
+// registered, as with a defer statement, and before the OnError in effect for
+// c.  Defer may be called from c's Func, from its hooks, or from functions
+// started with Go.  To register teardown for the whole program register it
+// on the root command:
+//
+//	conn, err := dial(ctx)
+//	if err != nil {
+//		return err
+//	}
+//	c.Root().Defer(func() { conn.Close() })
+//
+// A function registered while c is not running is called at the end of the
+// next Run of c.
+func (c *Command) Defer(fn func()) {
+	d := c.deferList()
+	d.mu.Lock()
+	d.fns = append(d.fns, fn)
+	d.mu.Unlock()
+}
+
+// deferList returns the deferList of c, creating it if needed.
+func (c *Command) deferList() *deferList {
+	lazyMu.Lock()
+	defer lazyMu.Unlock()
+	if c.deferred == nil {
+		c.deferred = &deferList{}
+	}
+	return c.deferred
+}
+
+// runDeferred calls, and then forgets, the functions registered with Defer.
+// A function that calls Defer from a deferred function has it called as
+// well.
+func (c *Command) runDeferred() {
+	d := c.deferList()
+	d.mu.Lock()
+	n := len(d.fns)
+	d.mu.Unlock()
+	if n == 0 {
+		return
+	}
+	defer c.startPhase("cleanup")()
+	for {
+		d.mu.Lock()
+		n := len(d.fns)
+		if n == 0 {
+			d.mu.Unlock()
+			return
+		}
+		fn := d.fns[n-1]
+		d.fns = d.fns[:n-1]
+		d.mu.Unlock()
+		fn()
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDefer(t *testing.T) {
+	var calls []string
+	record := func(s string) func() {
+		return func() { calls = append(calls, s) }
+	}
+	fail := errors.New("failed")
+	root := &Command{
+		Name: "prog",
+		PersistentPreRun: func(_ context.Context, c *Command, _ []string) error {
+			c.Root().Defer(record("close log"))
+			return nil
+		},
+		OnError: func(c *Command, _ []string, _ []any, err error) error {
+			calls = append(calls, "on error "+c.Name)
+			return err
+		},
+		OnShutdown: func(*Command, error) { calls = append(calls, "shutdown") },
+		SubCommands: []*Command{{
+			Name: "sub",
+			Func: func(_ context.Context, c *Command, args []string, _ ...any) error {
+				c.Defer(record("remove temp"))
+				c.Defer(record("unlock"))
+				c.Go(func(context.Context) error {
+					c.Defer(record("from go"))
+					return nil
+				})
+				if len(args) > 0 {
+					return fail
+				}
+				return nil
+			},
+		}},
+	}
+	ctx := context.Background()
+	if err := root.Run(ctx, []string{"sub"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(calls, "|"), "from go|unlock|remove temp|close log|shutdown"; got != want {
+		t.Errorf("got %s\nwant %s", got, want)
+	}
+
+	calls = nil
+	if err := root.Run(ctx, []string{"sub", "fail"}); err != fail {
+		t.Errorf("got error %v, want %v", err, fail)
+	}
+	if got, want := strings.Join(calls, "|"), "from go|unlock|remove temp|on error sub|close log|on error prog|shutdown"; got != want {
+		t.Errorf("got %s\nwant %s", got, want)
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// A ChangeKind is the kind of a Change between two trees of commands.
+type ChangeKind int
+
+const (
+	CommandAdded ChangeKind = iota
+	CommandRemoved
+	CommandRenamed
+	FlagAdded
+	FlagRemoved
+	FlagRenamed
+)
+
+var changeKindNames = map[ChangeKind]string{
+	CommandAdded:   "added command",
+	CommandRemoved: "removed command",
+	CommandRenamed: "renamed command",
+	FlagAdded:      "added flag",
+	FlagRemoved:    "removed flag",
+	FlagRenamed:    "renamed flag",
+}
+
+func (k ChangeKind) String() string {
+	if s, ok := changeKindNames[k]; ok {
+		return s
+	}
+	return fmt.Sprintf("ChangeKind(%d)", int(k))
+}
+
+// A Change is a single difference between two trees of commands as
+// returned by DiffTrees.
+type Change struct {
+	Kind ChangeKind
+
+	// Command is the full name of the command, e.g., "prog sub".  The
+	// name is from the old tree for removed commands and from the new tree
+	// otherwise.
+	Command string
+
+	// Flag is the name of the flag, without dashes, for flag changes.
+	Flag string
+
+	// Old is the previous name of a renamed command or flag.  For
+	// commands it is the full name of the command.
+	Old string
+}
+
+// String returns c as a line suitable for release notes, such as "renamed
+// flag --dir of prog sub (was --directory)".
+func (c Change) String() string {
+	switch c.Kind {
+	case CommandRenamed:
+		return fmt.Sprintf("%v %s (was %s)", c.Kind, c.Command, c.Old)
+	case FlagAdded, FlagRemoved:
+		return fmt.Sprintf("%v %s of %s", c.Kind, dashes(c.Flag), c.Command)
+	case FlagRenamed:
+		return fmt.Sprintf("%v %s of %s (was %s)", c.Kind, dashes(c.Flag), c.Command, dashes(c.Old))
+	}
+	return fmt.Sprintf("%v %s", c.Kind, c.Command)
+}
+
+// dashes returns name with the dashes used to display it in help.
+func dashes(name string) string {
+	if len(name) == 1 {
+		return "-" + name
+	}
+	return "--" + name
+}
+
+// DiffTrees reports the commands and flags that were added, removed, or
+// renamed between the old and new trees of commands.  It is intended for
+// generating release notes and checking for incompatible changes.
+//
+// Renames are detected heuristically.  A removed and an added sub command of
+// the same command are a rename if they have the same Func or the same,
+// non-empty, Help.  A removed and an added flag of the same command are a
+// rename if they are declared by the same structure field.  Flags of added
+// and removed commands are not reported.
+func DiffTrees(old, new *Command) []Change {
+	var changes []Change
+	diffCommands(&changes, old, new, old.Name, new.Name)
+	return changes
+}
+
+// diffCommands appends the differences between the commands old and new,
+// named oldName and newName, to changes.
+func diffCommands(changes *[]Change, old, new *Command, oldName, newName string) {
+	if oldName != newName {
+		*changes = append(*changes, Change{Kind: CommandRenamed, Command: newName, Old: oldName})
+	}
+	diffFlags(changes, old.getFlags(), new.getFlags(), newName)
+
+	var added, removed []*Command
+	for _, sc := range sortedSubs(new) {
+		if old.findSub(sc.Name) == nil {
+			added = append(added, sc)
+		}
+	}
+	for _, sc := range sortedSubs(old) {
+		nsc := new.findSub(sc.Name)
+		if nsc == nil {
+			removed = append(removed, sc)
+			continue
+		}
+		diffCommands(changes, sc, nsc, oldName+" "+sc.Name, newName+" "+sc.Name)
+	}
+	for _, osc := range removed {
+		i := renamedCommand(osc, added)
+		if i < 0 {
+			*changes = append(*changes, Change{Kind: CommandRemoved, Command: oldName + " " + osc.Name})
+			continue
+		}
+		nsc := added[i]
+		added = append(added[:i], added[i+1:]...)
+		diffCommands(changes, osc, nsc, oldName+" "+osc.Name, newName+" "+nsc.Name)
+	}
+	for _, sc := range added {
+		*changes = append(*changes, Change{Kind: CommandAdded, Command: newName + " " + sc.Name})
+	}
+}
+
+// sortedSubs returns the sub commands of c sorted by name.
+func sortedSubs(c *Command) []*Command {
+	subs := append([]*Command(nil), c.SubCommands...)
+	sort.Slice(subs, func(i, j int) bool { return subs[i].Name < subs[j].Name })
+	return subs
+}
+
+// renamedCommand returns the index of the command in added that c was
+// renamed to, or -1.
+func renamedCommand(c *Command, added []*Command) int {
+	for i, sc := range added {
+		if c.Func != nil && sc.Func != nil && reflect.ValueOf(c.Func).Pointer() == reflect.ValueOf(sc.Func).Pointer() {
+			return i
+		}
+		if c.Help != "" && c.Help == sc.Help {
+			return i
+		}
+	}
+	return -1
+}
+
+// diffFlags appends the differences between the flags structures old and
+// new of the command named name to changes.
+func diffFlags(changes *[]Change, old, new any, name string) {
+	var oldFields, newFields []flagField
+	if old != nil {
+		_, oldFields, _ = flagFields(old)
+	}
+	if new != nil {
+		_, newFields, _ = flagFields(new)
+	}
+	find := func(fields []flagField, match func(flagField) bool) (flagField, bool) {
+		for _, f := range fields {
+			if match(f) {
+				return f, true
+			}
+		}
+		return flagField{}, false
+	}
+	for _, of := range oldFields {
+		if _, ok := find(newFields, func(f flagField) bool { return f.name == of.name }); ok {
+			continue
+		}
+		if nf, ok := find(newFields, func(f flagField) bool { return f.field == of.field }); ok {
+			if _, ok := find(oldFields, func(f flagField) bool { return f.name == nf.name }); !ok {
+				*changes = append(*changes, Change{Kind: FlagRenamed, Command: name, Flag: nf.name, Old: of.name})
+				continue
+			}
+		}
+		*changes = append(*changes, Change{Kind: FlagRemoved, Command: name, Flag: of.name})
+	}
+	for _, nf := range newFields {
+		if _, ok := find(oldFields, func(f flagField) bool { return f.name == nf.name }); ok {
+			continue
+		}
+		if of, ok := find(oldFields, func(f flagField) bool { return f.field == nf.field }); ok {
+			if _, ok := find(newFields, func(f flagField) bool { return f.name == of.name }); !ok {
+				continue // reported as renamed
+			}
+		}
+		*changes = append(*changes, Change{Kind: FlagAdded, Command: name, Flag: nf.name})
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func runList(context.Context, *Command, []string, ...any) error { return nil }
+
+func TestDiffTrees(t *testing.T) {
+	old := &Command{
+		Name: "prog",
+		Defaults: &struct {
+			Verbose bool   `flag:"-v be verbose"`
+			Dir     string `flag:"--directory=DIR working directory"`
+			Old     bool   `flag:"--old obsolete"`
+		}{},
+		SubCommands: []*Command{
+			{Name: "ls", Func: runList},
+			{Name: "rm", Help: "remove files"},
+			{Name: "gone"},
+			{Name: "keep", SubCommands: []*Command{{Name: "x"}}},
+		},
+	}
+	new := &Command{
+		Name: "prog",
+		Defaults: &struct {
+			Verbose bool   `flag:"-v be verbose"`
+			Dir     string `flag:"--dir=DIR working directory"`
+			Color   bool   `flag:"--color use color"`
+		}{},
+		SubCommands: []*Command{
+			{Name: "list", Func: runList},
+			{Name: "delete", Help: "remove files"},
+			{Name: "new"},
+			{Name: "keep", SubCommands: []*Command{{Name: "y"}}},
+		},
+	}
+	var got []string
+	for _, c := range DiffTrees(old, new) {
+		got = append(got, c.String())
+	}
+	want := []string{
+		"renamed flag --dir of prog (was --directory)",
+		"removed flag --old of prog",
+		"added flag --color of prog",
+		"removed command prog keep x",
+		"added command prog keep y",
+		"removed command prog gone",
+		"renamed command prog list (was prog ls)",
+		"renamed command prog delete (was prog rm)",
+		"added command prog new",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if changes := DiffTrees(old, old); len(changes) != 0 {
+		t.Errorf("got changes comparing a tree with itself: %v", changes)
+	}
+}
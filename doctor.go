@@ -0,0 +1,104 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"errors"
+)
+
+// A healthCheck is a check registered with RegisterCheck.
+type healthCheck struct {
+	name string
+	fn   func(context.Context, *Command) error
+}
+
+// A CheckError may be returned by a health check registered with
+// RegisterCheck to give the user a hint on how to fix the problem, or to
+// report the problem as a warning rather than a failure.
+type CheckError struct {
+	Err     error
+	Hint    string // how to fix the problem
+	Warning bool   // the problem does not fail the check
+}
+
+func (e *CheckError) Error() string { return e.Err.Error() }
+func (e *CheckError) Unwrap() error { return e.Err }
+
+// RegisterCheck registers fn as a health check named name that is run by
+// DoctorCmd.  fn is called with c, it passes if it returns nil.  fn may
+// return a *CheckError to provide a hint or to only warn about a problem.
+func (c *Command) RegisterCheck(name string, fn func(ctx context.Context, c *Command) error) {
+	c.checks = append(c.checks, healthCheck{name: name, fn: fn})
+}
+
+// DoctorCmd is a sub command that calls the Doctor function.
+var DoctorCmd = &Command{
+	Name:       "doctor",
+	Help:       "check that the program is working correctly",
+	Parameters: "[check ...]",
+	Func:       Doctor,
+}
+
+// Doctor implements the doctor command.
+//
+//	Usage: doctor [check ...]
+//
+// Doctor runs the health checks registered with RegisterCheck by all the
+// commands in the tree, or only the named checks, and displays whether each
+// check passed, warned, or failed along with any hint on how to fix the
+// problem.  Doctor returns an *ExitError with a code of 1 if any check
+// failed.
+func Doctor(ctx context.Context, c *Command, args []string, extra ...any) error {
+	want := map[string]bool{}
+	for _, arg := range args {
+		want[arg] = true
+	}
+	var failed, total int
+	c.Root().Walk(func(hc *Command) error {
+		for _, check := range hc.checks {
+			if len(args) > 0 && !want[check.name] {
+				continue
+			}
+			delete(want, check.name)
+			total++
+			err := check.fn(ctx, hc)
+			var ce *CheckError
+			errors.As(err, &ce)
+			switch {
+			case err == nil:
+				c.Printf("PASS  %s\n", check.name)
+				continue
+			case ce != nil && ce.Warning:
+				c.Printf("WARN  %s: %v\n", check.name, err)
+			default:
+				failed++
+				c.Printf("FAIL  %s: %v\n", check.name, err)
+			}
+			if ce != nil && ce.Hint != "" {
+				c.Printf("      %s\n", ce.Hint)
+			}
+		}
+		return nil
+	})
+	for _, arg := range args {
+		if want[arg] {
+			return c.errorf("%s: no such check", arg)
+		}
+	}
+	if failed > 0 {
+		return &ExitError{Code: 1, Err: c.errorf("%d of %d checks failed", failed, total)}
+	}
+	return nil
+}
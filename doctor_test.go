@@ -0,0 +1,68 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestDoctor(t *testing.T) {
+	var out bytes.Buffer
+	sub := &Command{Name: "sub"}
+	root := &Command{
+		Name:        "prog",
+		Stdout:      &out,
+		SubCommands: []*Command{sub, DoctorCmd},
+	}
+	root.RegisterCheck("config", func(context.Context, *Command) error { return nil })
+	sub.RegisterCheck("cache", func(_ context.Context, c *Command) error {
+		if c != sub {
+			t.Errorf("check called with %s, want %s", c.Command(), sub.Command())
+		}
+		return &CheckError{Err: errors.New("cache is old"), Hint: "run prog sub --refresh", Warning: true}
+	})
+	sub.RegisterCheck("network", func(context.Context, *Command) error {
+		return &CheckError{Err: errors.New("no route to host"), Hint: "check your connection"}
+	})
+	ctx := context.Background()
+
+	err := root.Run(ctx, []string{"doctor"})
+	if ExitCode(err) != 1 || err.Error() != "1 of 3 checks failed" {
+		t.Errorf("got error %v, want 1 of 3 checks failed", err)
+	}
+	want := `PASS  config
+WARN  cache: cache is old
+      run prog sub --refresh
+FAIL  network: no route to host
+      check your connection
+`
+	if got := out.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	out.Reset()
+	if err := root.Run(ctx, []string{"doctor", "config", "cache"}); err != nil {
+		t.Errorf("got error %v", err)
+	}
+	if got, want := out.String(), "PASS  config\nWARN  cache: cache is old\n      run prog sub --refresh\n"; got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	if err := root.Run(ctx, []string{"doctor", "bad"}); err == nil || err.Error() != "bad: no such check" {
+		t.Errorf("got error %v, want bad: no such check", err)
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/pborman/flags"
+)
+
+// An EnvVar is an environment variable used by a tree of commands.
+type EnvVar struct {
+	Name    string
+	Command string // full name of the command with the flag, if any
+	Flag    string // name of the flag bound to the variable, if any
+	Help    string // what the variable is used for
+	Secret  bool   // the value must not be displayed
+}
+
+// EnvVars returns the environment variables used by the tree rooted at c,
+// sorted by name.  These are the variables bound to flags with an env tag
+// along with the variables used by commander itself.
+func (c *Command) EnvVars() []EnvVar {
+	vars := []EnvVar{{Name: "NO_COLOR", Help: "disable colored output"}}
+	if c.NonInteractiveFlag {
+		vars = append(vars, EnvVar{Name: "CI", Help: "fail rather than ask questions"})
+	}
+	var walk func(c *Command, name string)
+	walk = func(c *Command, name string) {
+		if c == ConfigCmd {
+			vars = append(vars,
+				EnvVar{Name: "VISUAL", Command: name + " edit", Help: "editor for the configuration file"},
+				EnvVar{Name: "EDITOR", Command: name + " edit", Help: "editor used if VISUAL is not set"},
+			)
+		}
+		if opts := c.getFlags(); opts != nil {
+			if _, fields, err := flagFields(opts); err == nil {
+				for _, f := range fields {
+					if f.env != "" {
+						vars = append(vars, EnvVar{
+							Name:    f.env,
+							Command: name,
+							Flag:    f.name,
+							Help:    f.help,
+							Secret:  f.secret,
+						})
+					}
+				}
+			}
+		}
+		for _, sc := range c.SubCommands {
+			walk(sc, name+" "+sc.Name)
+		}
+	}
+	walk(c, c.Name)
+	sort.SliceStable(vars, func(i, j int) bool { return vars[i].Name < vars[j].Name })
+	return vars
+}
+
+// applyEnv sets the flags in set that are bound to environment variables
+// that are set.
+func (c *Command) applyEnv(set flags.FlagSet) error {
+	_, fields, err := flagFields(c.Flags)
+	if err != nil {
+		return nil
+	}
+	setter, ok := set.(flagSetter)
+	if !ok {
+		return nil
+	}
+	for _, f := range fields {
+		if f.env == "" {
+			continue
+		}
+		v, ok := os.LookupEnv(f.env)
+		if !ok {
+			continue
+		}
+		if err := setter.Set(f.name, v); err != nil {
+			return &UsageError{
+				C:   c,
+				Err: c.errorf("$%s: invalid value for %s: %v", f.env, dashes(f.name), err),
+			}
+		}
+	}
+	return nil
+}
+
+// EnvCmd is a sub command that calls the Env function.
+var EnvCmd = &Command{
+	Name:  "env",
+	Help:  "display the environment variables used",
+	Arity: "0",
+	Func:  Env,
+}
+
+// Env implements the env command.
+//
+//	Usage: env
+//
+// Env displays each environment variable used by the program, whether it is
+// set, its value, and what uses it.  The values of secret variables are
+// masked.
+func Env(ctx context.Context, c *Command, args []string, extra ...any) error {
+	tw := tabwriter.NewWriter(c.stdout(), 0, 4, 2, ' ', 0)
+	c.fprintf(tw, "NAME\tSET\tVALUE\tUSED BY\n")
+	for _, v := range c.Root().EnvVars() {
+		value, ok := os.LookupEnv(v.Name)
+		set := c.sprintf("no")
+		if ok {
+			set = c.sprintf("yes")
+		}
+		switch {
+		case ok && v.Secret:
+			value = "********"
+		case ok:
+			value = strconv.Quote(value)
+		}
+		usedBy := v.Command
+		if v.Flag != "" {
+			usedBy += " " + dashes(v.Flag)
+		}
+		if usedBy == "" {
+			usedBy = v.Help
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", v.Name, set, value, usedBy)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,89 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func envTree() (*Command, *bytes.Buffer) {
+	var buf bytes.Buffer
+	return &Command{
+		Name:   "prog",
+		Stdout: &buf,
+		Stderr: &buf,
+		Defaults: &struct {
+			Token string `flag:"--token=TOKEN API token" env:"PROG_TOKEN,secret"`
+			Host  string `flag:"--host=HOST server" env:"PROG_HOST"`
+		}{},
+		SubCommands: []*Command{
+			EnvCmd,
+			{
+				Name: "get",
+				Defaults: &struct {
+					N int `flag:"-n=N count" env:"PROG_N"`
+				}{},
+				Func: func(context.Context, *Command, []string, ...any) error { return nil },
+			},
+		},
+	}, &buf
+}
+
+func TestEnvBinding(t *testing.T) {
+	root, _ := envTree()
+	t.Setenv("PROG_HOST", "example.com")
+	t.Setenv("PROG_N", "3")
+	if err := root.Run(context.Background(), []string{"--host=other", "get"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := root.Lookup("", "host"); got != "other" {
+		t.Errorf("got host %v, want other", got)
+	}
+	if got := root.SubCommands[1].Lookup("get", "n"); got != 3 {
+		t.Errorf("got n %v, want 3", got)
+	}
+	if err := root.Run(context.Background(), []string{"get"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := root.Lookup("", "host"); got != "example.com" {
+		t.Errorf("got host %v, want example.com", got)
+	}
+	t.Setenv("PROG_N", "three")
+	err := root.Run(context.Background(), []string{"get"})
+	if want := "prog get: $PROG_N: invalid value for -n"; err == nil || !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("got error %v, want %s", err, want)
+	}
+}
+
+func TestEnvCmd(t *testing.T) {
+	root, buf := envTree()
+	t.Setenv("PROG_TOKEN", "hunter2")
+	t.Setenv("PROG_HOST", "example.com")
+	t.Setenv("NO_COLOR", "")
+	if err := root.Run(context.Background(), []string{"env"}); err != nil {
+		t.Fatal(err)
+	}
+	want := `NAME        SET  VALUE          USED BY
+NO_COLOR    yes  ""             disable colored output
+PROG_HOST   yes  "example.com"  prog --host
+PROG_N      no                  prog get -n
+PROG_TOKEN  yes  ********       prog --token
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// An ExitError is returned by a command that wants the program to exit with
+// a specific exit code.  Main and ExitCode use Code as the exit code.
+type ExitError struct {
+	Code int
+	Err  error // may be nil
+}
+
+func (e *ExitError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit status %d", e.Code)
+	}
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// An ErrorClass is the kind of an error as determined by Classify.
+type ErrorClass string
+
+const (
+	NoError       ErrorClass = ""         // the error is nil
+	UsageClass    ErrorClass = "usage"    // a *UsageError
+	ExitClass     ErrorClass = "exit"     // an *ExitError
+	BusyClass     ErrorClass = "busy"     // a *BusyError
+	CanceledClass ErrorClass = "canceled" // context.Canceled, including a *CanceledError
+	DeadlineClass ErrorClass = "deadline" // context.DeadlineExceeded
+	OtherClass    ErrorClass = "error"    // any other error
+)
+
+// Classify returns the class of err.  The first matching class in the order
+// usage, exit, busy, canceled, and deadline is returned.
+func Classify(err error) ErrorClass {
+	var ue *UsageError
+	var ee *ExitError
+	var be *BusyError
+	switch {
+	case err == nil:
+		return NoError
+	case errors.As(err, &ue):
+		return UsageClass
+	case errors.As(err, &ee):
+		return ExitClass
+	case errors.As(err, &be):
+		return BusyClass
+	case errors.Is(err, context.Canceled):
+		return CanceledClass
+	case errors.Is(err, context.DeadlineExceeded):
+		return DeadlineClass
+	}
+	return OtherClass
+}
+
+// OnErrorByClass returns an OnError func that calls the handler in handlers
+// for the class of the error, as returned by Classify.  If there is no
+// handler for the class then the handler for OtherClass, if any, is called.
+// Otherwise the error is returned unchanged.  For example, to exit quietly
+// when canceled and display usage errors:
+//
+//	cmd.OnError = commander.OnErrorByClass(map[commander.ErrorClass]func(*commander.Command, []string, []any, error) error{
+//		commander.CanceledClass: commander.ExitQuietly(130),
+//		commander.UsageClass:    commander.ContinueOnError,
+//	})
+func OnErrorByClass(handlers map[ErrorClass]func(*Command, []string, []any, error) error) func(*Command, []string, []any, error) error {
+	return func(c *Command, args []string, extra []any, err error) error {
+		h, ok := handlers[Classify(err)]
+		if !ok {
+			h, ok = handlers[OtherClass]
+		}
+		if !ok {
+			return err
+		}
+		return h(c, args, extra, err)
+	}
+}
+
+// ExitQuietly returns an OnError func that exits with code without
+// displaying the error.
+func ExitQuietly(code int) func(*Command, []string, []any, error) error {
+	return func(*Command, []string, []any, error) error {
+		Exit(code)
+		return nil
+	}
+}
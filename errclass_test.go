@@ -0,0 +1,144 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	c := &Command{Name: "test"}
+	for _, tt := range []struct {
+		err  error
+		want ErrorClass
+	}{
+		{nil, NoError},
+		{errors.New("failed"), OtherClass},
+		{&UsageError{C: c}, UsageClass},
+		{&ExitError{Code: 3}, ExitClass},
+		{&BusyError{C: c}, BusyClass},
+		{context.Canceled, CanceledClass},
+		{&CanceledError{Err: context.Canceled}, CanceledClass},
+		{fmt.Errorf("wrapped: %w", context.DeadlineExceeded), DeadlineClass},
+		{&ExitError{Code: 2, Err: &UsageError{C: c}}, UsageClass},
+	} {
+		if got := Classify(tt.err); got != tt.want {
+			t.Errorf("Classify(%v) got %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestExitCodeExitError(t *testing.T) {
+	if got := ExitCode(&ExitError{Code: 3}); got != 3 {
+		t.Errorf("got %d, want 3", got)
+	}
+	if got := ExitCode(fmt.Errorf("wrapped: %w", &ExitError{Code: 4})); got != 4 {
+		t.Errorf("got %d, want 4", got)
+	}
+}
+
+func TestOnErrorByClass(t *testing.T) {
+	defer func(exit func(int)) { Exit = exit }(Exit)
+	var exited []int
+	Exit = func(x int) { exited = append(exited, x) }
+
+	var out bytes.Buffer
+	var fail error
+	c := &Command{
+		Name:   "test",
+		Stderr: &out,
+		Func:   func(context.Context, *Command, []string, ...any) error { return fail },
+		OnError: OnErrorByClass(map[ErrorClass]func(*Command, []string, []any, error) error{
+			CanceledClass: ExitQuietly(130),
+			UsageClass:    ContinueOnError,
+		}),
+	}
+	ctx := context.Background()
+
+	fail = &CanceledError{Err: context.Canceled}
+	if err := c.Run(ctx, nil); err != nil {
+		t.Errorf("canceled: got error %v", err)
+	}
+	if len(exited) != 1 || exited[0] != 130 {
+		t.Errorf("canceled: got exits %v, want [130]", exited)
+	}
+	if out.Len() != 0 {
+		t.Errorf("canceled: unexpected output %q", out.String())
+	}
+
+	fail = &UsageError{C: c, Err: errors.New("bad")}
+	if err := c.Run(ctx, nil); err != nil {
+		t.Errorf("usage: got error %v", err)
+	}
+	if want := "test: bad\n"; out.String() != want {
+		t.Errorf("usage: got output %q, want %q", out.String(), want)
+	}
+
+	fail = errors.New("failed")
+	if err := c.Run(ctx, nil); err != fail {
+		t.Errorf("other: got error %v, want %v", err, fail)
+	}
+	if len(exited) != 1 {
+		t.Errorf("other: got exits %v", exited)
+	}
+}
+
+func TestOnRunError(t *testing.T) {
+	var got []string
+	handler := func(name string) func(*Command, []string, []any, error) error {
+		return func(c *Command, _ []string, _ []any, err error) error {
+			got = append(got, name+": "+err.Error())
+			return nil
+		}
+	}
+	fail := errors.New("failed")
+	root := &Command{
+		Name:       "prog",
+		Stderr:     io.Discard,
+		OnError:    handler("usage"),
+		OnRunError: handler("run"),
+		SubCommands: []*Command{{
+			Name:  "sub",
+			Arity: "1",
+			Func:  func(context.Context, *Command, []string, ...any) error { return fail },
+		}},
+	}
+	ctx := context.Background()
+	if err := root.Run(ctx, []string{"sub", "a"}); err != nil {
+		t.Errorf("run: got error %v", err)
+	}
+	if err := root.Run(ctx, []string{"sub"}); err != nil {
+		t.Errorf("usage: got error %v", err)
+	}
+	want := []string{"run: failed", "usage: prog sub: expected 1 arguments, got 0"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// Without OnRunError, OnError handles every error.
+	got = nil
+	root.OnRunError = nil
+	if err := root.Run(ctx, []string{"sub", "a"}); err != nil {
+		t.Errorf("run: got error %v", err)
+	}
+	if want := []string{"usage: failed"}; len(got) != 1 || got[0] != want[0] {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
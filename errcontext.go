@@ -0,0 +1,100 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+// An ErrorStage is the stage of running a command in which an error
+// occurred.
+type ErrorStage string
+
+const (
+	FlagStage     ErrorStage = "flags"    // parsing flags, config files, and environment variables
+	ArgsStage     ErrorStage = "args"     // checking the positional parameters
+	DispatchStage ErrorStage = "dispatch" // choosing and preparing the command to run
+	RunStage      ErrorStage = "run"      // running Func and its hooks
+)
+
+// An ErrorContext describes where a failed run of a command tree failed.
+// It is returned by the ErrorContext method and passed to the func given to
+// WithErrorContext.
+type ErrorContext struct {
+	Command *Command   // the command that failed
+	Path    []string   // the names of the commands from the root to Command
+	Stage   ErrorStage // the stage Command was in when it failed
+	Flags   any        // the flags of Command, possibly only partially parsed
+	Args    []string   // the arguments passed to Run of the root command
+	Err     error      // the error being handled
+}
+
+// ErrorContext returns the context of the error returned by the most recent
+// run of the command tree c is in, or nil if there was no error.  It is
+// intended to be called by OnError and OnRunError funcs.  The context
+// describes the deepest command that failed, even when the error is being
+// handled by one of its ancestors.  Err is the error the command returned.
+func (c *Command) ErrorContext() *ErrorContext {
+	return c.Root().errCtx
+}
+
+// WithErrorContext returns an OnError func that calls fn with the
+// ErrorContext of the error.  Err is set to the error being handled, which
+// may differ from the error originally returned if the OnError of a sub
+// command replaced it.  The error returned by fn is returned.  For example:
+//
+//	cmd.OnRunError = commander.WithErrorContext(func(ec *commander.ErrorContext) error {
+//		log.Printf("%s failed during %s: %v", strings.Join(ec.Path, " "), ec.Stage, ec.Err)
+//		return ec.Err
+//	})
+func WithErrorContext(fn func(*ErrorContext) error) func(*Command, []string, []any, error) error {
+	return func(c *Command, _ []string, _ []any, err error) error {
+		var ec ErrorContext
+		if rec := c.ErrorContext(); rec != nil {
+			ec = *rec
+		} else {
+			ec = *c.newErrorContext(err)
+		}
+		ec.Err = err
+		return fn(&ec)
+	}
+}
+
+// noteError records err, returned by c, as the error of the current run of
+// the command tree, unless an error has already been recorded by a sub
+// command of c.
+func (c *Command) noteError(err error) {
+	if err == nil {
+		return
+	}
+	if root := c.Root(); root.errCtx == nil {
+		root.errCtx = c.newErrorContext(err)
+	}
+}
+
+// newErrorContext returns the context of err returned by c.
+func (c *Command) newErrorContext(err error) *ErrorContext {
+	var path []string
+	for _, pc := range c.Path() {
+		path = append(path, pc.Name)
+	}
+	stage := c.stage
+	if stage == "" {
+		stage = DispatchStage
+	}
+	return &ErrorContext{
+		Command: c,
+		Path:    path,
+		Stage:   stage,
+		Flags:   c.Flags,
+		Args:    c.Root().runArgs,
+		Err:     err,
+	}
+}
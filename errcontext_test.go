@@ -0,0 +1,145 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestErrorContext(t *testing.T) {
+	type options struct {
+		Name string `flag:"--name=NAME the name"`
+	}
+	fail := errors.New("failed")
+	var got *ErrorContext
+	var handled []string
+	root := &Command{
+		Name:   "prog",
+		Stderr: io.Discard,
+		OnError: WithErrorContext(func(ec *ErrorContext) error {
+			got = ec
+			return ec.Err
+		}),
+		SubCommands: []*Command{{
+			Name: "sub",
+			SubCommands: []*Command{{
+				Name:     "leaf",
+				Defaults: &options{},
+				Arity:    "0..1",
+				Func: func(context.Context, *Command, []string, ...any) error {
+					return fail
+				},
+				OnError: func(c *Command, _ []string, _ []any, err error) error {
+					handled = append(handled, c.ErrorContext().Command.Name)
+					return err
+				},
+			}},
+		}},
+	}
+	ctx := context.Background()
+	for _, tt := range []struct {
+		args  []string
+		path  string
+		stage ErrorStage
+		name  string
+	}{
+		{[]string{"sub", "leaf", "--name=bob", "x"}, "prog sub leaf", RunStage, "bob"},
+		{[]string{"sub", "leaf", "--bad"}, "prog sub leaf", FlagStage, ""},
+		{[]string{"sub", "leaf", "--name=x", "a", "b"}, "prog sub leaf", ArgsStage, "x"},
+		{[]string{"sub", "missing"}, "prog sub", DispatchStage, ""},
+	} {
+		got = nil
+		err := root.Run(ctx, tt.args)
+		if err == nil {
+			t.Errorf("%q: did not get an error", tt.args)
+			continue
+		}
+		if got == nil {
+			t.Errorf("%q: OnError not called", tt.args)
+			continue
+		}
+		if p := strings.Join(got.Path, " "); p != tt.path {
+			t.Errorf("%q: got path %q, want %q", tt.args, p, tt.path)
+		}
+		if got.Stage != tt.stage {
+			t.Errorf("%q: got stage %q, want %q", tt.args, got.Stage, tt.stage)
+		}
+		if got.Err != err {
+			t.Errorf("%q: got error %v, want %v", tt.args, got.Err, err)
+		}
+		if !reflect.DeepEqual(got.Args, tt.args) {
+			t.Errorf("%q: got args %q", tt.args, got.Args)
+		}
+		if tt.name != "" {
+			if o, ok := got.Flags.(*options); !ok || o.Name != tt.name {
+				t.Errorf("%q: got flags %#v, want name %q", tt.args, got.Flags, tt.name)
+			}
+		}
+	}
+	if want := []string{"leaf", "leaf", "leaf"}; !reflect.DeepEqual(handled, want) {
+		t.Errorf("leaf OnError saw %q, want %q", handled, want)
+	}
+
+	if err := root.Run(ctx, []string{"sub", "leaf"}); err != fail {
+		t.Fatalf("got error %v, want %v", err, fail)
+	}
+	root.SubCommands[0].SubCommands[0].Func = func(context.Context, *Command, []string, ...any) error { return nil }
+	if err := root.Run(ctx, []string{"sub", "leaf"}); err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	if ec := root.ErrorContext(); ec != nil {
+		t.Errorf("successful run left error context %+v", ec)
+	}
+}
+
+func TestErrorContextHandled(t *testing.T) {
+	fail := errors.New("batch failed")
+	var got *ErrorContext
+	root := &Command{
+		Name:   "prog",
+		Stderr: io.Discard,
+		OnError: WithErrorContext(func(ec *ErrorContext) error {
+			got = ec
+			return ec.Err
+		}),
+		SubCommands: []*Command{{
+			Name: "batch",
+			Func: func(ctx context.Context, c *Command, _ []string, _ ...any) error {
+				c.RunSubcommands(ctx, []string{"step"})
+				return fail
+			},
+			SubCommands: []*Command{{
+				Name: "step",
+				Func: func(context.Context, *Command, []string, ...any) error {
+					return errors.New("step failed")
+				},
+				OnError: func(*Command, []string, []any, error) error { return nil },
+			}},
+		}},
+	}
+	if err := root.Run(context.Background(), []string{"batch"}); err != fail {
+		t.Fatalf("got error %v, want %v", err, fail)
+	}
+	if got == nil {
+		t.Fatal("OnError not called")
+	}
+	if p := strings.Join(got.Path, " "); p != "prog batch" {
+		t.Errorf("got path %q, want %q", p, "prog batch")
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"regexp"
+	"strings"
+)
+
+// placeholderRE matches a placeholder in an example, such as <FILE>.
+var placeholderRE = regexp.MustCompile(`<[A-Z][A-Z0-9_-]*>`)
+
+// hasPlaceholder returns true if the example ex contains a placeholder.
+func hasPlaceholder(ex string) bool {
+	return placeholderRE.MatchString(ex)
+}
+
+// templates returns the templates of c's examples, the arguments that follow
+// the name of c in each example that contains a placeholder.  Placeholders
+// are upper case names in angle brackets, such as <FILE> or <NAME>, that
+// stand for a value the user supplies, e.g.,
+//
+//	prog copy --mode=<MODE> <SRC> <DST>
+//
+// has the template "--mode=<MODE> <SRC> <DST>".  Completing the arguments
+// of a command offers its templates before any argument has been typed.
+func (c *Command) templates() []string {
+	var tmpls []string
+	path := c.Path()
+	for _, ex := range c.Examples {
+		if !hasPlaceholder(ex) {
+			continue
+		}
+		words, err := SplitLine(ex)
+		if err != nil || len(words) == 0 {
+			continue
+		}
+		// Skip the words up to, and including, the name of c.
+		start, j := 1, 1
+		for i := 1; i < len(words) && j < len(path); i++ {
+			if sc, _ := path[j-1].matchSub(words[i], false); sc == path[j] {
+				j++
+				start = i + 1
+			}
+		}
+		if j < len(path) || start == len(words) {
+			continue
+		}
+		args := words[start:]
+		for i, arg := range args {
+			args[i] = shellQuote(arg)
+		}
+		tmpls = append(tmpls, strings.Join(args, " "))
+	}
+	return tmpls
+}
+
+// shellQuote returns s quoted, if necessary, so it is a single word when
+// split by SplitLine or a shell.
+func shellQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n'\"\\$`|&;()*?[]#~") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// displayExample returns the example ex as help displays it on the current
+// platform.  Examples are written with the quoting of SplitLine, which
+// matches POSIX shells.  On Windows the words of ex are requoted following
+// the rules Windows programs use to split their command line, so the
+// example can be pasted into a Windows shell.
+func displayExample(ex string) string {
+	if goos != "windows" || !strings.ContainsAny(ex, "'\"\\") {
+		return ex
+	}
+	words, err := SplitLine(ex)
+	if err != nil {
+		return ex
+	}
+	for i, w := range words {
+		words[i] = windowsQuote(w)
+	}
+	return strings.Join(words, " ")
+}
+
+// windowsQuote returns s quoted, if necessary, so it is a single argument
+// when split by a Windows program.  Backslashes are only special before a
+// double quote.
+func windowsQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\"") {
+		return s
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	slashes := 0
+	for _, r := range s {
+		switch r {
+		case '\\':
+			slashes++
+		case '"':
+			b.WriteString(strings.Repeat(`\`, slashes+1))
+			slashes = 0
+		default:
+			slashes = 0
+		}
+		b.WriteRune(r)
+	}
+	b.WriteString(strings.Repeat(`\`, slashes))
+	b.WriteByte('"')
+	return b.String()
+}
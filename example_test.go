@@ -0,0 +1,37 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import "testing"
+
+func TestDisplayExample(t *testing.T) {
+	defer func(os string) { goos = os }(goos)
+	for _, tt := range []struct {
+		goos string
+		ex   string
+		want string
+	}{
+		{"linux", `prog add 'a b' "c d"`, `prog add 'a b' "c d"`},
+		{"windows", `prog add x`, `prog add x`},
+		{"windows", `prog add 'a b' "c d"`, `prog add "a b" "c d"`},
+		{"windows", `prog say 'he said "hi"'`, `prog say "he said \"hi\""`},
+		{"windows", `prog copy 'C:\Program Files\' 'D:\x'`, `prog copy "C:\Program Files\\" D:\x`},
+		{"windows", `prog bad 'unterminated`, `prog bad 'unterminated`},
+	} {
+		goos = tt.goos
+		if got := displayExample(tt.ex); got != tt.want {
+			t.Errorf("%s: displayExample(%s) got %s, want %s", tt.goos, tt.ex, got, tt.want)
+		}
+	}
+}
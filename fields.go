@@ -0,0 +1,269 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pborman/flags"
+)
+
+// A flagField describes a single flag declared by a field in a flags
+// structure.
+type flagField struct {
+	index  int          // index of the field in the structure
+	offset uintptr      // offset of the field in the structure
+	field  string       // name of the structure field
+	typ    reflect.Type // type of the field
+	name   string       // name of the flag without leading dashes
+	param  string       // parameter name, if any
+	help   string       // help text
+	env    string       // environment variable bound to the flag
+	secret bool         // the value of the flag is secret
+	def    string       // how help displays the default, see flagHelp
+}
+
+// A flagStruct is the cached reflection analysis of a flags structure type.
+type flagStruct struct {
+	fields []flagField
+	err    error
+}
+
+// flagCache maps a reflect.Type of a flags structure to a *flagStruct.
+var flagCache sync.Map
+
+// flagFields returns the value of the structure i points to along with the
+// analysis of its flags.  The analysis of each type is only done once.  An
+// error is returned if i is not a pointer to a structure or the structure has
+// an invalid flag tag.
+func flagFields(i any) (reflect.Value, []flagField, error) {
+	v := reflect.ValueOf(i)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, nil, fmt.Errorf("%T is not a pointer to a struct", i)
+	}
+	v = v.Elem()
+	t := v.Type()
+	if fs, ok := flagCache.Load(t); ok {
+		fs := fs.(*flagStruct)
+		return v, fs.fields, fs.err
+	}
+	fs := analyzeFlags(t)
+	flagCache.Store(t, fs)
+	return v, fs.fields, fs.err
+}
+
+// analyzeFlags does the actual work for flagFields.  It follows the same
+// rules as the github.com/pborman/flags package.
+func analyzeFlags(t reflect.Type) *flagStruct {
+	var fs flagStruct
+	n := t.NumField()
+	for i := 0; i < n; i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("flag")
+		if tag == "-" || !field.IsExported() {
+			continue
+		}
+		name, param, help, err := parseFlagTag(tag)
+		if err != nil {
+			return &flagStruct{err: err}
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		env, opts, _ := strings.Cut(field.Tag.Get("env"), ",")
+		fs.fields = append(fs.fields, flagField{
+			index:  i,
+			offset: field.Offset,
+			field:  field.Name,
+			typ:    field.Type,
+			name:   name,
+			param:  param,
+			help:   help,
+			env:    env,
+			secret: opts == "secret",
+			def:    field.Tag.Get("default"),
+		})
+	}
+	return &fs
+}
+
+// parseFlagTag parses a flag tag as described by the github.com/pborman/flags
+// package.  An empty name is returned if the tag does not declare an option.
+// Flags are registered by registerFlags from the result of parseFlagTag, not
+// by the flags package, so parseFlagTag alone defines what a tag means.
+func parseFlagTag(tag string) (name, param, help string, err error) {
+	tag = strings.TrimSpace(tag)
+	next := tag
+	for next != "" && next[0] == '-' {
+		var arg, p string
+		arg, next = next, ""
+		if x := strings.Index(arg, " "); x >= 0 {
+			arg, next = arg[:x], strings.TrimSpace(arg[x:])
+		}
+		if x := strings.Index(arg, "="); x >= 0 {
+			arg, p = arg[:x], arg[x+1:]
+		}
+		if arg == "-" || arg == "--" {
+			if p != "" {
+				return "", "", "", fmt.Errorf("flag tag missing option name: %q", tag)
+			}
+			break
+		}
+		if p != "" {
+			if param != "" {
+				return "", "", "", fmt.Errorf("flag tag has multiple parameter names: %q", tag)
+			}
+			param = p
+		}
+		if name != "" {
+			return "", "", "", fmt.Errorf("flag tag has too many names: %q", tag)
+		}
+		name = strings.TrimPrefix(arg[1:], "-")
+	}
+	if name == "" {
+		if next != "" {
+			return "", "", "", fmt.Errorf("flag tag missing option name: %q", tag)
+		}
+		return "", "", "", nil
+	}
+	return name, param, next, nil
+}
+
+// A flagList is the flag.Value of a []string flag.  Each time the flag is set
+// its value is appended, as with the github.com/pborman/flags package.
+type flagList []string
+
+func (l *flagList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+func (l *flagList) String() string {
+	return strings.Join(*l, " ")
+}
+
+// registerFlags registers the flags declared by the flags structure i points
+// to with set.  It is the equivalent of flags.RegisterSet but uses the cached
+// analysis of the structure rather than analyzing it again.  An error is
+// returned if i is not a valid flags structure or declares a flag of an
+// unsupported type.
+func registerFlags(i any, set flags.FlagSet) error {
+	v, fields, err := flagFields(i)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		help := f.help
+		if help == "" {
+			help = "unspecified"
+		}
+		switch p := v.Field(f.index).Addr().Interface().(type) {
+		case flags.Value:
+			err = setVar(set, p, f.name, help)
+		case *[]string:
+			err = setVar(set, (*flagList)(p), f.name, help)
+		case *time.Duration:
+			set.DurationVar(p, f.name, *p, help)
+		case *string:
+			set.StringVar(p, f.name, *p, help)
+		case *int:
+			set.IntVar(p, f.name, *p, help)
+		case *int64:
+			set.Int64Var(p, f.name, *p, help)
+		case *uint:
+			set.UintVar(p, f.name, *p, help)
+		case *uint64:
+			set.Uint64Var(p, f.name, *p, help)
+		case *float64:
+			set.Float64Var(p, f.name, *p, help)
+		case *bool:
+			set.BoolVar(p, f.name, *p, help)
+		default:
+			return fmt.Errorf("invalid option type: %s", f.typ)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setVar registers value as the flag name in set.  An error is returned if
+// set does not have the Var method of the standard flag package's FlagSet.
+func setVar(set flags.FlagSet, value flag.Value, name, usage string) error {
+	vs, ok := set.(interface {
+		Var(flag.Value, string, string)
+	})
+	if !ok {
+		return fmt.Errorf("%T missing Var method", set)
+	}
+	vs.Var(value, name, usage)
+	return nil
+}
+
+// dupFlags returns a shallow copy of the flags structure i points to.  Only
+// fields that declare flags are copied.  dupFlags panics if i is not a valid
+// flags structure.
+func dupFlags(i any) any {
+	v, fields, err := flagFields(i)
+	if err != nil {
+		panic(err)
+	}
+	nv := reflect.New(v.Type())
+	for _, f := range fields {
+		nv.Elem().Field(f.index).Set(v.Field(f.index))
+	}
+	return nv.Interface()
+}
+
+// lookupFlag returns the value of the flag named name in the flags structure
+// i points to, or nil.
+func lookupFlag(i any, name string) any {
+	if i == nil {
+		return nil
+	}
+	v, fields, err := flagFields(i)
+	if err != nil {
+		return nil
+	}
+	for _, f := range fields {
+		if f.name == name {
+			return v.Field(f.index).Interface()
+		}
+	}
+	return nil
+}
+
+// lookupFlagField returns the description of the flag named name in the flags
+// structure i points to, or nil.
+func lookupFlagField(i any, name string) *flagField {
+	if i == nil {
+		return nil
+	}
+	_, fields, err := flagFields(i)
+	if err != nil {
+		return nil
+	}
+	for _, f := range fields {
+		if f.name == name {
+			return &f
+		}
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pborman/flags"
+)
+
+func TestParseFlagTag(t *testing.T) {
+	for _, tt := range []struct {
+		tag  string
+		want string
+		err  bool
+	}{
+		{tag: "", want: `"" "" ""`},
+		{tag: "--name", want: `"name" "" ""`},
+		{tag: "-n=N number of times", want: `"n" "N" "number of times"`},
+		{tag: "--name=NAME   the name", want: `"name" "NAME" "the name"`},
+		{tag: "-v -- -v means verbose", want: `"v" "" "-v means verbose"`},
+		{tag: "just help", err: true},
+		{tag: "--a --b", err: true},
+		{tag: "--a=X -b=Y", err: true},
+		{tag: "--=X", err: true},
+	} {
+		name, param, help, err := parseFlagTag(tt.tag)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%q: did not get an error", tt.tag)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", tt.tag, err)
+			continue
+		}
+		if got := fmt.Sprintf("%q %q %q", name, param, help); got != tt.want {
+			t.Errorf("%q: got %s, want %s", tt.tag, got, tt.want)
+		}
+	}
+}
+
+func TestFlagCache(t *testing.T) {
+	opts := &barFlags{Name: "bob", Value: 3}
+	_, f1, err := flagFields(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, f2, _ := flagFields(&barFlags{})
+	if len(f1) != 2 || &f1[0] != &f2[0] {
+		t.Errorf("flag analysis was not cached")
+	}
+	if _, ok := flagCache.Load(reflect.TypeOf(*opts)); !ok {
+		t.Errorf("barFlags not in cache")
+	}
+	if _, _, err := flagFields(barFlags{}); err == nil {
+		t.Errorf("non-pointer did not return an error")
+	}
+
+	dup := dupFlags(opts).(*barFlags)
+	if dup == opts || *dup != *opts {
+		t.Errorf("dupFlags got %p %v, want copy of %p %v", dup, dup, opts, opts)
+	}
+	if got := lookupFlag(opts, "value"); got != 3 {
+		t.Errorf("lookupFlag got %v, want 3", got)
+	}
+	if got := lookupFlag(opts, "missing"); got != nil {
+		t.Errorf("lookupFlag of missing flag got %v", got)
+	}
+}
+
+func TestRegisterFlags(t *testing.T) {
+	type allFlags struct {
+		Duration time.Duration `flag:"--duration=D a duration"`
+		String   string        `flag:"--string=S"`
+		Int      int           `flag:"-i=N an int"`
+		Int64    int64
+		Uint     uint     `flag:"--uint=N a uint"`
+		Uint64   uint64   `flag:"--uint64=N a uint64"`
+		Float    float64  `flag:"--float=F a float"`
+		Bool     bool     `flag:"--bool a bool"`
+		List     []string `flag:"--list=ITEM add ITEM"`
+		Skipped  string   `flag:"-"`
+		hidden   string
+	}
+	args := []string{
+		"--duration=2s", "--string=s", "-i=3", "--int64=4", "--uint=5",
+		"--uint64=6", "--float=7.5", "--bool", "--list=a", "--list=b", "arg",
+	}
+	parse := func(register func(any, flags.FlagSet) error) (*allFlags, map[string]string) {
+		opts := &allFlags{String: "default"}
+		set := flag.NewFlagSet("test", flag.ContinueOnError)
+		if err := register(opts, set); err != nil {
+			t.Fatal(err)
+		}
+		usage := map[string]string{}
+		set.VisitAll(func(f *flag.Flag) { usage[f.Name] = f.Usage + "|" + f.DefValue })
+		if err := set.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		return opts, usage
+	}
+	got, gotUsage := parse(registerFlags)
+	want, wantUsage := parse(func(i any, set flags.FlagSet) error {
+		return flags.RegisterSet("test", i, set)
+	})
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if !reflect.DeepEqual(gotUsage, wantUsage) {
+		t.Errorf("got usage %q, want %q", gotUsage, wantUsage)
+	}
+
+	if err := registerFlags(&struct{ C chan int }{}, flag.NewFlagSet("", flag.ContinueOnError)); err == nil {
+		t.Errorf("unsupported type did not return an error")
+	}
+}
+
+func TestBadFlags(t *testing.T) {
+	type badTag struct {
+		Name string `flag:"just help"`
+	}
+	type badType struct {
+		C chan int `flag:"--c a channel"`
+	}
+	noop := func(context.Context, *Command, []string, ...any) error { return nil }
+	for _, tt := range []struct {
+		name string
+		cmd  *Command
+	}{
+		{"Flags tag", &Command{Flags: &badTag{}}},
+		{"Defaults tag", &Command{Defaults: &badTag{}}},
+		{"Flags type", &Command{Flags: &badType{}}},
+		{"Defaults type", &Command{Defaults: &badType{}}},
+	} {
+		root := tt.cmd
+		root.Name, root.Func, root.Stderr = "prog", noop, io.Discard
+		err := root.Run(context.Background(), nil)
+		if err == nil || !strings.HasPrefix(err.Error(), "prog: ") {
+			t.Errorf("%s: got error %v, want a prog: error", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/pborman/indent"
+)
+
+// flagHelp writes help for the flags declared by the flags structure i
+// points to, as flags.Help does, except that the display of the default
+// value of each flag is controlled by its default tag.  By default a flag
+// whose value is not the zero value is followed by its value in brackets,
+// e.g., [10s].  A default tag of "-" never displays the value and any other
+// default tag is displayed in place of the value, even if the value is the
+// zero value:
+//
+//	Config map[string]string `flag:"--config=KEY=VALUE set KEY" default:"-"`
+//	Editor string            `flag:"--editor=PROG edit with PROG" default:"$EDITOR"`
+//
+// If cmd is not empty the usage line is written first.
+func flagHelp(w io.Writer, cmd, parameters string, i any) {
+	type flagInfo struct {
+		prefix string
+		flag   string
+		help   string
+		def    string
+	}
+	var usage []flagInfo
+	ml := 0
+	if i != nil {
+		if v, fields, err := flagFields(i); err == nil {
+			for _, f := range fields {
+				fi := flagInfo{prefix: "--", flag: f.name, help: f.help}
+				if len(f.name) == 1 {
+					fi.prefix = " -"
+				}
+				if _, ok := v.Field(f.index).Addr().Interface().(*bool); !ok {
+					param := f.param
+					if param == "" {
+						param = "VALUE"
+					}
+					fi.flag += "=" + param
+				}
+				switch fv := v.Field(f.index); {
+				case f.def == "-":
+				case f.def != "":
+					fi.def = fmt.Sprintf(" [%s]", f.def)
+				case !fv.IsZero():
+					fi.def = fmt.Sprintf(" [%v]", fv.Interface())
+				}
+				if n := len(fi.flag) + 1 + len(fi.prefix); n > ml && n < 20 {
+					ml = n
+				}
+				usage = append(usage, fi)
+			}
+		}
+	}
+	sort.Slice(usage, func(i, j int) bool { return usage[i].flag < usage[j].flag })
+
+	if cmd != "" {
+		var b strings.Builder
+		b.WriteString(cmd)
+		for _, fi := range usage {
+			fmt.Fprintf(&b, " [%s%s]", strings.TrimSpace(fi.prefix), fi.flag)
+		}
+		if parameters != "" {
+			fmt.Fprintf(&b, " %s", parameters)
+		}
+		fmt.Fprintf(w, "Usage: %s\n", b.String())
+	}
+	w = indent.NewWriter(w, "  ")
+	for _, fi := range usage {
+		flag := fi.prefix + fi.flag
+		switch {
+		case fi.help == "" && fi.def == "":
+			fmt.Fprintf(w, "%s\n", flag)
+		case len(flag) > ml:
+			fmt.Fprintf(w, "%s\n  %*s %s%s\n", flag, ml, "", fi.help, fi.def)
+		default:
+			fmt.Fprintf(w, "%s%*s %s%s\n", flag, ml-len(fi.flag), "", fi.help, fi.def)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/pborman/flags"
+)
+
+func TestFlagHelp(t *testing.T) {
+	opts := &struct {
+		Alpha  string            `flag:"--alpha=LEVEL set the alpha level"`
+		Beta   int               `flag:"--beta=N      set beta to N"`
+		Float  float64           `flag:"-f=RATE       set frame rate to RATE"`
+		Fancy  bool              `flag:"--the_real_fancy_and_long_option yes or no"`
+		Wait   time.Duration     `flag:"--wait=D      how long to wait"`
+		List   []string          `flag:"--list=ITEM   add ITEM to list"`
+		Shell  string            `flag:"--shell=PROG  shell to run" default:"$SHELL"`
+		Config map[string]string `flag:"--config=KV   set a value" default:"-"`
+		Retry  time.Duration     `flag:"--retry=D     retry after D" default:"-"`
+	}{
+		Alpha: "high",
+		Beta:  3,
+		Retry: time.Second,
+	}
+
+	var got bytes.Buffer
+	flagHelp(&got, "xyzzy", "...", opts)
+	want := `Usage: xyzzy [--alpha=LEVEL] [--beta=N] [--config=KV] [-f=RATE] [--list=ITEM] [--retry=D] [--shell=PROG] [--the_real_fancy_and_long_option] [--wait=D] ...
+  --alpha=LEVEL    set the alpha level [high]
+  --beta=N         set beta to N [3]
+  --config=KV      set a value
+   -f=RATE         set frame rate to RATE
+  --list=ITEM      add ITEM to list
+  --retry=D        retry after D
+  --shell=PROG     shell to run [$SHELL]
+  --the_real_fancy_and_long_option
+                   yes or no
+  --wait=D         how long to wait
+`
+	if got.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got.String(), want)
+	}
+
+	// Without default tags the output matches flags.Help.
+	plain := &struct {
+		Alpha string        `flag:"--alpha=LEVEL set the alpha level"`
+		N     int           `flag:"-n number of times"`
+		Quiet bool          `flag:"-q be quiet"`
+		Wait  time.Duration `flag:"--wait how long to wait"`
+	}{Alpha: "x", Wait: time.Minute}
+	got.Reset()
+	flagHelp(&got, "prog", "", plain)
+	var fh bytes.Buffer
+	flags.Help(&fh, "prog", "", plain)
+	if got.String() != fh.String() {
+		t.Errorf("got:\n%s\nflags.Help:\n%s", got.String(), fh.String())
+	}
+}
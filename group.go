@@ -0,0 +1,159 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/pborman/flags"
+)
+
+// A group is a collection of goroutines started by Go while a Func runs.
+type group struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	sem    chan struct{} // limits the number of goroutines, if not nil
+	once   sync.Once
+	err    error // the first error returned by a goroutine
+}
+
+// Go calls fn in a new goroutine as part of the current invocation of c.  fn
+// is passed a context derived from the context passed to c's Func that is
+// canceled when Func, or any function started by Go, returns an error, or
+// once Func and all the functions it started have returned.  If the standard
+// --parallel=N flag was given (see the ParallelFlag field of Command) at most
+// N of the functions run at once, the others wait for a running one to
+// return.  Go itself never blocks.
+//
+// Wait waits for the functions to return.  Run calls Wait when Func returns
+// so Func need not, and returns the first error returned by a function if
+// Func itself did not fail:
+//
+//	for _, host := range args {
+//		host := host
+//		c.Go(func(ctx context.Context) error {
+//			return ping(ctx, host)
+//		})
+//	}
+//	return c.Wait()
+//
+// Go may also be called from a function started by Go.  Go panics if c is not
+// running.
+func (c *Command) Go(fn func(ctx context.Context) error) {
+	g := c.group
+	if g == nil {
+		panic(c.Command() + ": Go called while the command is not running")
+	}
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		// The slot is taken here, not by the caller, so a function
+		// started by Go that calls Go does not wait for its own slot.
+		if g.sem != nil {
+			g.sem <- struct{}{}
+			defer func() { <-g.sem }()
+		}
+		if err := c.goRecovered(g.ctx, fn); err != nil {
+			g.once.Do(func() {
+				g.err = err
+				g.cancel()
+			})
+		}
+	}()
+}
+
+// Wait waits for all the functions started by Go during the current
+// invocation of c to return and returns the first error any of them
+// returned.
+func (c *Command) Wait() error {
+	g := c.group
+	if g == nil {
+		return nil
+	}
+	g.wg.Wait()
+	return g.err
+}
+
+// callFunc calls c.Func with the arguments and then waits for the functions
+// it started with Go.  The error returned by Func takes precedence over the
+// errors of the functions.
+func (c *Command) callFunc(ctx context.Context, args []string, extra ...any) error {
+	parent := ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	g := &group{}
+	g.ctx, g.cancel = context.WithCancel(parent)
+	if n := c.Root().parallel; n > 0 {
+		g.sem = make(chan struct{}, n)
+	}
+	c.group = g
+	defer func() {
+		g.cancel()
+		c.group = nil
+	}()
+	err := c.callRecovered(ctx, args, extra...)
+	if err != nil {
+		g.cancel()
+	}
+	if werr := c.Wait(); err == nil {
+		err = werr
+	}
+	return err
+}
+
+// parallelFlagHelp returns the help for the standard --parallel flag if c
+// accepts it.
+func (c *Command) parallelFlagHelp() []standardFlag {
+	if !c.acceptsParallel() {
+		return nil
+	}
+	return []standardFlag{{"--parallel=N", "run at most N tasks at once"}}
+}
+
+// acceptsParallel returns true if c accepts the standard --parallel flag.
+// Only a root command with ParallelFlag set accepts it, and only if it does
+// not declare its own parallel flag.
+func (c *Command) acceptsParallel() bool {
+	if c.parent != nil || !c.ParallelFlag {
+		return false
+	}
+	return lookupFlagField(c.getFlags(), "parallel") == nil
+}
+
+// addParallelFlag adds the standard --parallel flag to set if c accepts it.
+// The returned function, which is nil if the flag was not added, must be
+// called after set is parsed.
+func (c *Command) addParallelFlag(set flags.FlagSet) func() error {
+	if !c.acceptsParallel() {
+		return nil
+	}
+	var parallel string
+	set.StringVar(&parallel, "parallel", "", "run at most N tasks at once")
+	return func() error {
+		c.parallel = 0
+		if parallel == "" {
+			return nil
+		}
+		n, err := strconv.Atoi(parallel)
+		if err != nil || n < 1 {
+			return &UsageError{C: c, Err: c.errorf("invalid parallelism %q", parallel)}
+		}
+		c.parallel = n
+		return nil
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGo(t *testing.T) {
+	var running, most int32
+	var canceled atomic.Bool
+	fail := errors.New("task failed")
+	root := &Command{
+		Name:         "prog",
+		ParallelFlag: true,
+		Func: func(ctx context.Context, c *Command, args []string, _ ...any) error {
+			for _, arg := range args {
+				arg := arg
+				c.Go(func(ctx context.Context) error {
+					n := atomic.AddInt32(&running, 1)
+					defer atomic.AddInt32(&running, -1)
+					for {
+						m := atomic.LoadInt32(&most)
+						if n <= m || atomic.CompareAndSwapInt32(&most, m, n) {
+							break
+						}
+					}
+					switch arg {
+					case "fail":
+						return fail
+					case "wait":
+						<-ctx.Done()
+						canceled.Store(true)
+					}
+					return nil
+				})
+			}
+			return nil
+		},
+	}
+	ctx := context.Background()
+
+	if err := root.Run(ctx, []string{"--parallel=1", "a", "b", "c", "d"}); err != nil {
+		t.Fatal(err)
+	}
+	if most != 1 {
+		t.Errorf("--parallel=1 ran %d at once", most)
+	}
+
+	// The first error is returned and cancels the other functions.
+	err := root.Run(ctx, []string{"wait", "fail"})
+	if err != fail {
+		t.Errorf("got error %v, want %v", err, fail)
+	}
+	if !canceled.Load() {
+		t.Errorf("context not canceled")
+	}
+
+	err = root.Run(ctx, []string{"--parallel=0", "a"})
+	if want := `prog: invalid parallelism "0"`; err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %s", err, want)
+	}
+	if help, _ := root.HelpText(); !strings.Contains(help, "--parallel=N") {
+		t.Errorf("help does not list --parallel:\n%s", help)
+	}
+}
+
+func TestGoFuncError(t *testing.T) {
+	funcErr := errors.New("func failed")
+	root := &Command{
+		Name: "prog",
+		Func: func(ctx context.Context, c *Command, args []string, _ ...any) error {
+			c.Go(func(context.Context) error { return errors.New("task failed") })
+			if err := c.Wait(); err == nil {
+				t.Errorf("Wait did not return the task error")
+			}
+			return funcErr
+		},
+	}
+	if err := root.Run(context.Background(), nil); err != funcErr {
+		t.Errorf("got error %v, want %v", err, funcErr)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Go did not panic outside of Func")
+		}
+	}()
+	root.Go(func(context.Context) error { return nil })
+}
+
+func TestGoNestedParallel(t *testing.T) {
+	nested := false
+	root := &Command{
+		Name:         "prog",
+		ParallelFlag: true,
+		SubCommands: []*Command{{
+			Name: "sub",
+			Func: func(_ context.Context, c *Command, _ []string, _ ...any) error {
+				c.Go(func(context.Context) error {
+					c.Go(func(context.Context) error {
+						nested = true
+						return nil
+					})
+					return nil
+				})
+				return nil
+			},
+		}},
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- root.Run(context.Background(), []string{"--parallel=1", "sub"}) }()
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("nested Go with --parallel=1 did not return")
+	}
+	if !nested {
+		t.Errorf("nested function did not run")
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"io"
+	"path"
+	"reflect"
+	"strings"
+
+	"github.com/pborman/indent"
+)
+
+// flagSearchArg returns the pattern of a --flag argument to the help
+// command, which may be given as "--flag PATTERN" or "--flag=PATTERN", and
+// the remaining arguments.  ok is false if args does not start with --flag.
+func flagSearchArg(args []string) (pattern string, rest []string, ok bool) {
+	if len(args) == 0 {
+		return "", args, false
+	}
+	arg := strings.TrimPrefix(args[0], "-")
+	if arg == args[0] {
+		return "", args, false
+	}
+	arg = strings.TrimPrefix(arg, "-")
+	if arg == "flag" {
+		if len(args) < 2 {
+			return "", nil, true
+		}
+		return args[1], args[2:], true
+	}
+	if strings.HasPrefix(arg, "flag=") {
+		return arg[len("flag="):], args[1:], true
+	}
+	return "", args, false
+}
+
+// matchFlagName returns true if the flag named name matches pattern.
+// Leading dashes are ignored in pattern, which is otherwise a pattern as
+// accepted by path.Match.
+func matchFlagName(pattern, name string) bool {
+	pattern = strings.TrimLeft(pattern, "-")
+	if pattern == name {
+		return true
+	}
+	ok, _ := path.Match(pattern, name)
+	return ok
+}
+
+// flagSyntax returns f as it is written on the command line, e.g., "-v" or
+// "--name=PARAM".  v is the flags structure holding f.
+func flagSyntax(v reflect.Value, f flagField) string {
+	s := "--" + f.name
+	if len(f.name) == 1 {
+		s = "-" + f.name
+	}
+	if _, ok := v.Field(f.index).Addr().Interface().(*bool); !ok {
+		param := f.param
+		if param == "" {
+			param = "VALUE"
+		}
+		s += "=" + param
+	}
+	return s
+}
+
+// writeFlagSearch writes each command in the tree rooted at c that declares
+// a flag whose name matches pattern, along with the matching flags, to w.
+// Hidden commands are not searched.  An error is returned if pattern is
+// invalid or no command has a matching flag.
+func (c *Command) writeFlagSearch(w io.Writer, pattern string) error {
+	if pattern == "" {
+		return c.errorf("help --flag requires a flag name")
+	}
+	if _, err := path.Match(strings.TrimLeft(pattern, "-"), ""); err != nil {
+		return c.errorf("invalid flag pattern %q", pattern)
+	}
+	found := false
+	err := c.Walk(func(wc *Command) error {
+		if wc.hidden() {
+			return SkipSubCommands
+		}
+		v, fields, err := flagFields(wc.getFlags())
+		if wc.getFlags() == nil || err != nil {
+			return nil
+		}
+		var matches []flagField
+		for _, f := range fields {
+			if matchFlagName(pattern, f.name) {
+				matches = append(matches, f)
+			}
+		}
+		if len(matches) == 0 {
+			return nil
+		}
+		if !found {
+			c.heading(w, "Commands with flags matching %s:\n", pattern)
+			found = true
+		}
+		c.fprintf(w, "\n  %s\n", wc.Command())
+		for _, f := range matches {
+			c.fprintf(w, "    %s\n", flagSyntax(v, f))
+			if f.help != "" {
+				c.fprintf(w, "%s\n", indent.String("        ", f.help))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return c.errorf("no command has a flag matching %s", pattern)
+	}
+	return nil
+}
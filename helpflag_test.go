@@ -0,0 +1,102 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestHelpFlagSearch(t *testing.T) {
+	type verbose struct {
+		Verbose bool   `flag:"--verbose be verbose"`
+		Name    string `flag:"--name=NAME the name"`
+	}
+	type quiet struct {
+		NoWait  bool `flag:"--no-wait do not wait"`
+		Verbose bool `flag:"-v"`
+	}
+	fn := func(context.Context, *Command, []string, ...any) error { return nil }
+	var out bytes.Buffer
+	root := &Command{
+		Name:   "prog",
+		Stderr: &out,
+		SubCommands: []*Command{
+			{Name: "a", Flags: &verbose{}, Func: fn},
+			{Name: "b", SubCommands: []*Command{
+				{Name: "c", Flags: &quiet{}, Func: fn},
+				{Name: "d", Flags: &verbose{}, Func: fn, Hidden: true},
+			}},
+			HelpCmd,
+		},
+	}
+	ctx := context.Background()
+	for _, tt := range []struct {
+		args []string
+		want string
+		err  string
+	}{{
+		args: []string{"help", "--flag", "verbose"},
+		want: `Commands with flags matching verbose:
+
+  prog a
+    --verbose
+        be verbose
+`,
+	}, {
+		args: []string{"help", "--flag=--n*"},
+		want: `Commands with flags matching --n*:
+
+  prog a
+    --name=NAME
+        the name
+
+  prog b c
+    --no-wait
+        do not wait
+`,
+	}, {
+		args: []string{"help", "-flag", "v"},
+		want: `Commands with flags matching v:
+
+  prog b c
+    -v
+`,
+	}, {
+		args: []string{"help", "--flag", "missing"},
+		err:  "no command has a flag matching missing",
+	}, {
+		args: []string{"help", "--flag", "[x"},
+		err:  `invalid flag pattern "[x"`,
+	}, {
+		args: []string{"help", "--flag"},
+		err:  "help --flag requires a flag name",
+	}, {
+		args: []string{"help", "--flag", "v", "a"},
+		err:  "unexpected arguments: a",
+	}} {
+		out.Reset()
+		err := root.Run(ctx, tt.args)
+		switch {
+		case err == nil && tt.err != "":
+			t.Errorf("%q: did not get error %s", tt.args, tt.err)
+		case err != nil && err.Error() != tt.err:
+			t.Errorf("%q: got error %v, want %s", tt.args, err, tt.err)
+		}
+		if got := out.String(); got != tt.want {
+			t.Errorf("%q: got:\n%s\nwant:\n%s", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import "context"
+
+// runHooked runs c's Func, surrounded by its hooks, with args.
+func runHooked(ctx context.Context, c *Command, args []string, extra ...any) error {
+	entered, err := c.runPersistentPreRun(ctx, args)
+	if err == nil {
+		if c.bufferOutput() {
+			err = c.runBuffered(ctx, args, extra...)
+		} else {
+			err = c.callFunc(ctx, args, extra...)
+		}
+		if c.PostRun != nil {
+			if perr := c.PostRun(ctx, c, args, err); err == nil {
+				err = perr
+			}
+		}
+	}
+	return c.runPersistentPostRun(ctx, args, entered, err)
+}
+
+// runPersistentPreRun calls the PersistentPreRun of each command from the
+// root to c, parent first, as c is about to run.  It stops at the first
+// error and returns the number of commands, from the root, that were
+// entered: those before the one that failed, or all of them.
+func (c *Command) runPersistentPreRun(ctx context.Context, args []string) (int, error) {
+	path := c.Path()
+	for i, pc := range path {
+		if pc.PersistentPreRun == nil {
+			continue
+		}
+		if err := pc.PersistentPreRun(ctx, c, args); err != nil {
+			return i, err
+		}
+	}
+	return len(path), nil
+}
+
+// runPersistentPostRun calls the PersistentPostRun of each of the first n
+// commands from the root to c, child first, once c has run.  err is the
+// error from running c and is returned unless it is nil, in which case the
+// first error returned by a PersistentPostRun is returned.
+func (c *Command) runPersistentPostRun(ctx context.Context, args []string, n int, err error) error {
+	path := c.Path()
+	for i := n - 1; i >= 0; i-- {
+		pc := path[i]
+		if pc.PersistentPostRun == nil {
+			continue
+		}
+		if perr := pc.PersistentPostRun(ctx, c, args, err); err == nil {
+			err = perr
+		}
+	}
+	return err
+}
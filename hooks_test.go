@@ -0,0 +1,122 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestPersistentHooks(t *testing.T) {
+	var calls []string
+	denied := errors.New("denied")
+	hooks := func(c *Command) *Command {
+		c.PersistentPreRun = func(_ context.Context, rc *Command, _ []string) error {
+			calls = append(calls, fmt.Sprintf("pre %s(%s)", c.Name, rc.Name))
+			if c.Name == "admin" && rc.Name == "reset" {
+				return denied
+			}
+			return nil
+		}
+		c.PersistentPostRun = func(_ context.Context, rc *Command, _ []string, err error) error {
+			calls = append(calls, fmt.Sprintf("post %s(%s) %v", c.Name, rc.Name, err))
+			return nil
+		}
+		return c
+	}
+	fn := func(_ context.Context, c *Command, _ []string, _ ...any) error {
+		calls = append(calls, "func "+c.Name)
+		return nil
+	}
+	root := hooks(&Command{
+		Name: "prog",
+		SubCommands: []*Command{
+			hooks(&Command{
+				Name: "admin",
+				SubCommands: []*Command{
+					{Name: "list", Func: fn},
+					{Name: "reset", Func: fn},
+				},
+			}),
+			{Name: "status", Func: fn},
+		},
+	})
+	ctx := context.Background()
+	for _, tt := range []struct {
+		args []string
+		err  error
+		want string
+	}{
+		{[]string{"admin", "list"}, nil, "pre prog(list)|pre admin(list)|func list|post admin(list) <nil>|post prog(list) <nil>"},
+		{[]string{"status"}, nil, "pre prog(status)|func status|post prog(status) <nil>"},
+		{[]string{"admin", "reset"}, denied, "pre prog(reset)|pre admin(reset)|post prog(reset) denied"},
+	} {
+		calls = nil
+		if err := root.Run(ctx, tt.args); err != tt.err {
+			t.Errorf("%v: got error %v, want %v", tt.args, err, tt.err)
+		}
+		if got := strings.Join(calls, "|"); got != tt.want {
+			t.Errorf("%v: got %s\nwant %s", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestOnHelp(t *testing.T) {
+	var shown []string
+	noop := func(context.Context, *Command, []string, ...any) error { return nil }
+	var stderr strings.Builder
+	root := &Command{
+		Name:   "prog",
+		Stderr: &stderr,
+		OnHelp: func(c *Command, _ io.Writer) { shown = append(shown, c.Name) },
+		SubCommands: []*Command{
+			{
+				Name: "deploy",
+				Func: noop,
+				OnHelp: func(c *Command, w io.Writer) {
+					fmt.Fprintf(w, "\nRegions: east, west\n")
+				},
+			},
+			{Name: "status", Func: noop},
+			HelpCmd,
+		},
+	}
+	ctx := context.Background()
+	if err := root.Run(ctx, []string{"help", "status"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.Run(ctx, []string{"help"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(shown, " "), "status prog"; got != want {
+		t.Errorf("OnHelp called for %q, want %q", got, want)
+	}
+	help, err := root.HelpText("deploy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(help, "\nRegions: east, west\n") {
+		t.Errorf("OnHelp did not append to help:\n%s", help)
+	}
+	if _, err := root.HelpText("bad"); err == nil {
+		t.Errorf("help for an unknown command did not fail")
+	}
+	if got, want := strings.Join(shown, " "), "status prog"; got != want {
+		t.Errorf("OnHelp called for %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"os"
+
+	"github.com/pborman/flags"
+)
+
+// A NonInteractiveError is returned by the prompt helpers, such as Prompt and
+// Confirm, when the program is not interactive (see Interactive).
+type NonInteractiveError struct {
+	C        *Command
+	Question string
+	Flag     string // flag that supplies the answer, if known
+}
+
+func (e *NonInteractiveError) Error() string {
+	if e.Flag != "" {
+		return e.C.sprintf("%s: cannot ask %q when not interactive, use %s", e.C.Command(), e.Question, e.Flag)
+	}
+	return e.C.sprintf("%s: cannot ask %q when not interactive", e.C.Command(), e.Question)
+}
+
+// promptFlagKey is the context key for the flag set by WithPromptFlag.
+type promptFlagKey struct{}
+
+// WithPromptFlag returns a copy of ctx that records that flag, such as
+// "--name", supplies the answer to the next question asked with ctx.  The
+// flag is included in the *NonInteractiveError returned when the question
+// cannot be asked:
+//
+//	name, err := c.Prompt(commander.WithPromptFlag(ctx, "--name"), "Name: ")
+func WithPromptFlag(ctx context.Context, flag string) context.Context {
+	return context.WithValue(ctx, promptFlagKey{}, flag)
+}
+
+// Interactive returns false if questions must not be asked, in which case
+// the prompt helpers return a *NonInteractiveError rather than waiting for
+// an answer that will never come.  A root command with NonInteractiveFlag
+// set is not interactive when given the standard --non-interactive flag or
+// when the CI environment variable is set to anything but "", "0", or
+// "false", as it is by most continuous integration systems.
+func (c *Command) Interactive() bool {
+	r := c.Root()
+	if !r.NonInteractiveFlag {
+		return true
+	}
+	if r.nonInteractive {
+		return false
+	}
+	switch os.Getenv("CI") {
+	case "", "0", "false":
+		return true
+	}
+	return false
+}
+
+// checkInteractive returns a *NonInteractiveError for question if c is not
+// interactive.
+func (c *Command) checkInteractive(ctx context.Context, question string) error {
+	if c.Interactive() {
+		return nil
+	}
+	flag, _ := ctx.Value(promptFlagKey{}).(string)
+	return &NonInteractiveError{C: c, Question: question, Flag: flag}
+}
+
+// acceptsNonInteractive returns true if c accepts the standard
+// --non-interactive flag.  Only a root command with NonInteractiveFlag set
+// accepts it, and only if it does not declare its own non-interactive flag.
+func (c *Command) acceptsNonInteractive() bool {
+	if c.parent != nil || !c.NonInteractiveFlag {
+		return false
+	}
+	return lookupFlagField(c.getFlags(), "non-interactive") == nil
+}
+
+// nonInteractiveFlagHelp returns the help for the standard --non-interactive
+// flag if c accepts it.
+func (c *Command) nonInteractiveFlagHelp() []standardFlag {
+	if !c.acceptsNonInteractive() {
+		return nil
+	}
+	return []standardFlag{{"--non-interactive", "fail rather than ask questions"}}
+}
+
+// addNonInteractiveFlag adds the standard --non-interactive flag to set if c
+// accepts it.  The returned function, which is nil if the flag was not
+// added, must be called after set is parsed.
+func (c *Command) addNonInteractiveFlag(set flags.FlagSet) func() {
+	if !c.acceptsNonInteractive() {
+		return nil
+	}
+	var on bool
+	set.BoolVar(&on, "non-interactive", false, "fail rather than ask questions")
+	return func() {
+		c.nonInteractive = on
+	}
+}
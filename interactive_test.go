@@ -0,0 +1,71 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestNonInteractive(t *testing.T) {
+	t.Setenv("CI", "")
+	var err error
+	root := &Command{
+		Name:               "prog",
+		Prompter:           fakePrompter{},
+		NonInteractiveFlag: true,
+		SubCommands: []*Command{{
+			Name: "ask",
+			Func: func(ctx context.Context, c *Command, _ []string, _ ...any) error {
+				_, err = c.Prompt(WithPromptFlag(ctx, "--name"), "Name: ")
+				return nil
+			},
+		}},
+	}
+	ctx := context.Background()
+
+	root.Run(ctx, []string{"ask"})
+	if err != nil {
+		t.Errorf("interactive: got error %v", err)
+	}
+
+	root.Run(ctx, []string{"--non-interactive", "ask"})
+	var nie *NonInteractiveError
+	if !errors.As(err, &nie) {
+		t.Fatalf("--non-interactive: got error %v, want a *NonInteractiveError", err)
+	}
+	if want := `prog ask: cannot ask "Name: " when not interactive, use --name`; err.Error() != want {
+		t.Errorf("got error %q, want %q", err, want)
+	}
+
+	// The flag is reset each run.
+	root.Run(ctx, []string{"ask"})
+	if err != nil {
+		t.Errorf("after --non-interactive: got error %v", err)
+	}
+
+	t.Setenv("CI", "true")
+	if root.Interactive() {
+		t.Errorf("CI=true: interactive")
+	}
+	if _, err := root.Confirm(ctx, "Sure? ", false); err == nil {
+		t.Errorf("CI=true: Confirm did not fail")
+	}
+
+	root.NonInteractiveFlag = false
+	if !root.Interactive() {
+		t.Errorf("CI=true without NonInteractiveFlag: not interactive")
+	}
+}
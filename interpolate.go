@@ -0,0 +1,205 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/pborman/flags"
+)
+
+// maxInterpolationDepth limits how deeply ${config:...} references may nest.
+const maxInterpolationDepth = 8
+
+// interpolating returns true if the Interpolate field is set on c's root.
+func (c *Command) interpolating() bool {
+	return c.Root().Interpolate
+}
+
+// Expand expands references in s as described by the Interpolate field of
+// Command.  Expand uses the configuration file of c's root command to
+// expand ${config:KEY}.
+func (c *Command) Expand(s string) (string, error) {
+	return c.ExpandContext(context.Background(), s)
+}
+
+// ExpandContext is like Expand but a command run by ${cmdoutput:CMD} is
+// killed if ctx is done before it finishes.
+func (c *Command) ExpandContext(ctx context.Context, s string) (string, error) {
+	return c.interpolate(ctx, s, 0, true)
+}
+
+// interpolate expands the references in s.  ${cmdoutput:CMD} is an error
+// unless cmds is true.
+func (c *Command) interpolate(ctx context.Context, s string, depth int, cmds bool) (string, error) {
+	if !strings.Contains(s, "$") {
+		return s, nil
+	}
+	if depth > maxInterpolationDepth {
+		return "", errors.New("interpolation nested too deeply")
+	}
+	var b strings.Builder
+	for {
+		x := strings.Index(s, "$")
+		if x < 0 || x == len(s)-1 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		b.WriteString(s[:x])
+		s = s[x:]
+		switch {
+		case strings.HasPrefix(s, "$${"):
+			b.WriteString("${")
+			s = s[3:]
+			continue
+		case !strings.HasPrefix(s, "${"):
+			b.WriteString("$")
+			s = s[1:]
+			continue
+		}
+		end := strings.Index(s, "}")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated reference in %q", s)
+		}
+		ref := s[2:end]
+		s = s[end+1:]
+		kind, arg, ok := strings.Cut(ref, ":")
+		if !ok {
+			return "", fmt.Errorf("invalid reference ${%s}", ref)
+		}
+		var v string
+		switch kind {
+		case "env":
+			v = os.Getenv(arg)
+		case "config":
+			// Remote defaults are not consulted, they are not trusted
+			// to be expanded.
+			e, ok := c.Root().config.Lookup(arg)
+			if !ok {
+				return "", fmt.Errorf("${%s}: %s not set", ref, arg)
+			}
+			var err error
+			if v, err = c.interpolate(ctx, e.Value, depth+1, true); err != nil {
+				return "", err
+			}
+		case "cmdoutput":
+			if !cmds {
+				return "", fmt.Errorf("${%s}: commands are not run, see InterpolateCmdOutput", ref)
+			}
+			words := strings.Fields(arg)
+			if len(words) == 0 {
+				return "", fmt.Errorf("${%s}: missing command", ref)
+			}
+			out, err := exec.CommandContext(ctx, words[0], words[1:]...).Output()
+			if err != nil {
+				return "", fmt.Errorf("${%s}: %v", ref, err)
+			}
+			v = strings.TrimRight(string(out), "\r\n")
+		default:
+			return "", fmt.Errorf("unknown reference ${%s}", ref)
+		}
+		b.WriteString(v)
+	}
+}
+
+// A trackedValue is a flag.Value that counts how many times it is set.
+type trackedValue struct {
+	flag.Value
+	name string
+	set  map[string]int
+}
+
+func (v *trackedValue) Set(s string) error {
+	v.set[v.name]++
+	return v.Value.Set(s)
+}
+
+// A trackedBoolValue is a trackedValue of a flag that does not take a value.
+type trackedBoolValue struct {
+	*trackedValue
+}
+
+func (trackedBoolValue) IsBoolFlag() bool { return true }
+
+// trackFlags returns a map that, from now on, counts how many times each flag
+// in set is set, e.g., by parsing the command line.  Nil is returned if the
+// flags of set cannot be tracked.
+func trackFlags(set flags.FlagSet) map[string]int {
+	ls, ok := set.(flagLookuper)
+	if !ok {
+		return nil
+	}
+	names := map[string]int{}
+	ls.VisitAll(func(f *flag.Flag) {
+		tv := &trackedValue{Value: f.Value, name: f.Name, set: names}
+		if isBoolFlag(f) {
+			f.Value = trackedBoolValue{tv}
+		} else {
+			f.Value = tv
+		}
+	})
+	return names
+}
+
+// interpolateFlags expands the values of the string flags in set that were
+// set on the command line.  names is how many times each flag was set.  Only
+// the values added by the command line are expanded, values from the
+// defaults, configuration file, environment, or an earlier parse are not
+// expanded again.  ${cmdoutput:CMD} is only expanded if the root command
+// sets InterpolateCmdOutput.
+func (c *Command) interpolateFlags(ctx context.Context, set flags.FlagSet, names map[string]int) error {
+	visitor, ok := set.(interface{ Visit(func(*flag.Flag)) })
+	if !ok {
+		return nil
+	}
+	v, fields, err := flagFields(c.Flags)
+	if err != nil {
+		return nil
+	}
+	byName := map[string]flagField{}
+	for _, f := range fields {
+		byName[f.name] = f
+	}
+	visitor.Visit(func(fl *flag.Flag) {
+		f, ok := byName[fl.Name]
+		n := names[fl.Name]
+		if !ok || n == 0 || err != nil {
+			return
+		}
+		cmds := c.Root().InterpolateCmdOutput
+		switch fv := v.Field(f.index).Addr().Interface().(type) {
+		case *string:
+			*fv, err = c.interpolate(ctx, *fv, 0, cmds)
+		case *[]string:
+			if n > len(*fv) {
+				n = len(*fv)
+			}
+			for i := len(*fv) - n; i < len(*fv); i++ {
+				if (*fv)[i], err = c.interpolate(ctx, (*fv)[i], 0, cmds); err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			err = fmt.Errorf("--%s: %v", fl.Name, err)
+		}
+	})
+	return err
+}
@@ -0,0 +1,199 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	t.Setenv("CMDR_TEST", "value")
+	c := &Command{
+		config: &Config{lines: []configLine{
+			{entry: &ConfigEntry{Key: "a", Value: "A"}},
+			{entry: &ConfigEntry{Key: "b", Value: "[${config:a}]"}},
+			{entry: &ConfigEntry{Key: "loop", Value: "${config:loop}"}},
+		}},
+	}
+	for _, tt := range []struct {
+		in, want, err string
+	}{
+		{in: "plain", want: "plain"},
+		{in: "$5 and $", want: "$5 and $"},
+		{in: "${env:CMDR_TEST}/x", want: "value/x"},
+		{in: "$${env:CMDR_TEST}", want: "${env:CMDR_TEST}"},
+		{in: "${config:b}", want: "[A]"},
+		{in: "${cmdoutput:echo hello}", want: "hello"},
+		{in: "${config:missing}", err: "not set"},
+		{in: "${config:loop}", err: "nested too deeply"},
+		{in: "${env:X", err: "unterminated"},
+		{in: "${bob}", err: "invalid reference"},
+		{in: "${bob:x}", err: "unknown reference"},
+	} {
+		got, err := c.Expand(tt.in)
+		switch {
+		case tt.err != "":
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%q: got error %v, want %s", tt.in, err, tt.err)
+			}
+		case err != nil:
+			t.Errorf("%q: unexpected error %v", tt.in, err)
+		case got != tt.want:
+			t.Errorf("%q: got %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestExpandContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := (&Command{}).ExpandContext(ctx, "${cmdoutput:sleep 10}"); err == nil {
+		t.Errorf("canceled command did not fail")
+	}
+}
+
+func TestInterpolateFlags(t *testing.T) {
+	t.Setenv("CMDR_TEST", "7")
+	dir := t.TempDir()
+	root, got := configTree()
+	root.ConfigFile = filepath.Join(dir, "config")
+	root.Interpolate = true
+	os.WriteFile(root.ConfigFile, []byte("sub.value = ${env:CMDR_TEST}\n"), 0644)
+	ctx := context.Background()
+	if err := root.Run(ctx, []string{"--name=${env:CMDR_TEST}$${x}", "sub"}); err != nil {
+		t.Fatal(err)
+	}
+	if got.Value != 7 {
+		t.Errorf("got value %d, want 7", got.Value)
+	}
+	if name := root.Lookup("", "name"); name != "7${x}" {
+		t.Errorf("got name %q, want 7${x}", name)
+	}
+	if err := root.Run(ctx, []string{"--name=${bad}", "sub"}); err == nil {
+		t.Errorf("bad reference did not fail")
+	}
+}
+
+// staticDefaults is a DefaultsSource that returns a fixed configuration.
+type staticDefaults string
+
+func (s staticDefaults) Fetch(context.Context) (*Config, error) {
+	return ParseConfig("remote", strings.NewReader(string(s)))
+}
+
+func TestInterpolateOnlyOnce(t *testing.T) {
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "marker")
+	ctx := context.Background()
+
+	var got struct {
+		Name string `flag:"--name=NAME the name" env:"CMDR_TEST_NAME"`
+	}
+	newRoot := func() *Command {
+		return &Command{
+			Name:        "root",
+			Interpolate: true,
+			ConfigFile:  filepath.Join(dir, "config"),
+			Defaults:    &got,
+			Func: func(_ context.Context, c *Command, _ []string, _ ...any) error {
+				got.Name = c.Lookup("", "name").(string)
+				return nil
+			},
+		}
+	}
+
+	// A config value is only expanded once, so an escaped reference
+	// stays escaped.
+	os.WriteFile(filepath.Join(dir, "config"), []byte("name = $${HOME}\n"), 0644)
+	if err := newRoot().Run(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "${HOME}" {
+		t.Errorf("config: got name %q, want ${HOME}", got.Name)
+	}
+	os.Remove(filepath.Join(dir, "config"))
+
+	// Environment variables bound to flags are not expanded.
+	t.Setenv("CMDR_TEST_NAME", "${env:HOME}")
+	if err := newRoot().Run(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "${env:HOME}" {
+		t.Errorf("env: got name %q, want ${env:HOME}", got.Name)
+	}
+	os.Unsetenv("CMDR_TEST_NAME")
+
+	// Remote defaults are never expanded nor used by ${config:KEY}.
+	root := newRoot()
+	root.RemoteDefaults = staticDefaults("name = ${cmdoutput:touch " + marker + "}\nother = x\n")
+	if err := root.Run(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Errorf("remote default ran a command")
+	}
+	if want := "${cmdoutput:touch " + marker + "}"; got.Name != want {
+		t.Errorf("remote: got name %q, want %q", got.Name, want)
+	}
+	if err := root.Run(ctx, []string{"--name=${config:other}"}); err == nil {
+		t.Errorf("${config:other} found a remote default")
+	}
+}
+
+func TestInterpolateCmdOutput(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "marker")
+	var got struct {
+		Name string   `flag:"--name=NAME the name"`
+		List []string `flag:"--list=ITEM add ITEM"`
+	}
+	root := &Command{
+		Name:        "root",
+		Interpolate: true,
+		Stderr:      io.Discard,
+		Flags:       &got,
+		Func:        func(context.Context, *Command, []string, ...any) error { return nil },
+	}
+	ctx := context.Background()
+	if err := root.Run(ctx, []string{"--name=${cmdoutput:touch " + marker + "}"}); err == nil {
+		t.Errorf("${cmdoutput:CMD} did not fail without InterpolateCmdOutput")
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Errorf("command line ran a command without InterpolateCmdOutput")
+	}
+
+	root.InterpolateCmdOutput = true
+	if err := root.Run(ctx, []string{"--name=${cmdoutput:echo hello}"}); err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "hello" {
+		t.Errorf("got name %q, want hello", got.Name)
+	}
+
+	// Values added to a list by an earlier parse are not expanded again.
+	got.List = nil
+	if err := root.Run(ctx, []string{"--list=$${a}"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.Run(ctx, []string{"--list=$${b}"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "${a} ${b}"; strings.Join(got.List, " ") != want {
+		t.Errorf("got list %q, want %q", got.List, want)
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"reflect"
+	"sync/atomic"
+)
+
+// An invocation is the state of a command recorded by Run once the command's
+// flags have been parsed.  An invocation is never changed once recorded so
+// Lookup may read it while the command tree is being run again.
+type invocation struct {
+	name   string
+	flags  map[string]any // flag values by name
+	parent *invocation
+}
+
+// recordInvocation records the current flag values of c as the invocation
+// of c, linked to the most recent invocation of its parent.  Slices and maps
+// are copied so later changes to them do not change the invocation.
+func (c *Command) recordInvocation() {
+	inv := &invocation{name: c.Name, flags: map[string]any{}}
+	if c.parent != nil {
+		inv.parent = c.parent.lastInvocation().Load()
+	}
+	if v, fields, err := flagFields(c.Flags); c.Flags != nil && err == nil {
+		for _, f := range fields {
+			inv.flags[f.name] = deepCopy(v.Field(f.index)).Interface()
+		}
+	}
+	c.lastInvocation().Store(inv)
+}
+
+// lastInvocation returns where the most recent invocation of c is recorded,
+// creating it if needed.
+func (c *Command) lastInvocation() *atomic.Pointer[invocation] {
+	lazyMu.Lock()
+	defer lazyMu.Unlock()
+	if c.inv == nil {
+		c.inv = &atomic.Pointer[invocation]{}
+	}
+	return c.inv
+}
+
+// deepCopy returns a copy of v in which slices and maps, including those
+// held by arrays, slices, and maps in v, are replaced by copies.  Other
+// values, such as pointers, are not copied.
+func deepCopy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		nv := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			nv.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return nv
+	case reflect.Array:
+		nv := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			nv.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return nv
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		nv := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			nv.SetMapIndex(iter.Key(), deepCopy(iter.Value()))
+		}
+		return nv
+	}
+	return v
+}
+
+// lookup implements Lookup for inv.
+func (inv *invocation) lookup(cmd, name string) any {
+	for ; inv != nil; inv = inv.parent {
+		if cmd == "" || cmd == inv.name {
+			if v := inv.flags[name]; v != nil {
+				return v
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentLookup(t *testing.T) {
+	type options struct {
+		N int `flag:"--n=N a number"`
+	}
+	sub := &Command{Name: "sub", Defaults: &options{}}
+	sub.Func = func(_ context.Context, c *Command, _ []string, _ ...any) error {
+		c.Flags.(*options).N = -1 // not seen by Lookup
+		return nil
+	}
+	root := &Command{
+		Name:        "prog",
+		Defaults:    &options{},
+		SubCommands: []*Command{sub},
+	}
+	ctx := context.Background()
+	if err := root.Run(ctx, []string{"--n=1", "sub", "--n=2"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := sub.Lookup("", "n"); got != 2 {
+		t.Errorf("got %v, want 2", got)
+	}
+	if got := sub.Lookup("prog", "n"); got != 1 {
+		t.Errorf("got %v, want 1", got)
+	}
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if n, _ := sub.Lookup("", "n").(int); n < 2 {
+				t.Errorf("got %d, want at least 2", n)
+				return
+			}
+		}
+	}()
+	for i := 3; i < 100; i++ {
+		if err := root.Run(ctx, []string{"sub", fmt.Sprintf("--n=%d", i)}); err != nil {
+			t.Error(err)
+		}
+	}
+	close(done)
+	wg.Wait()
+}
+
+func TestLookupCopies(t *testing.T) {
+	type options struct {
+		List []string `flag:"--list=ITEM add ITEM"`
+	}
+	root := &Command{
+		Name:     "prog",
+		Defaults: &options{},
+		Func: func(_ context.Context, c *Command, _ []string, _ ...any) error {
+			c.Flags.(*options).List[0] = "changed" // not seen by Lookup
+			return nil
+		},
+	}
+	if err := root.Run(context.Background(), []string{"--list=a", "--list=b"}); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := root.Lookup("", "list").([]string)
+	if len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Errorf("got %q, want [a b]", got)
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/pborman/indent"
+)
+
+// dashIsArg returns true if c or any of its parents has DashIsArg set.
+func (c *Command) dashIsArg() bool {
+	for c != nil {
+		if c.DashIsArg {
+			return true
+		}
+		c = c.parent
+	}
+	return false
+}
+
+// OpenInput opens the file named arg for reading.  If DashIsArg is set and
+// arg is "-" then c's Stdin is returned instead.  Closing the returned
+// Stdin does not close the underlying reader.
+//
+// Note that the flag parsing done by commander never consumes a lone "-",
+// it is always returned as a positional parameter.
+func (c *Command) OpenInput(arg string) (io.ReadCloser, error) {
+	if arg == "-" && c.dashIsArg() {
+		return io.NopCloser(c.stdin()), nil
+	}
+	return os.Open(arg)
+}
+
+// OpenOutput creates or truncates the file named arg for writing.  If
+// DashIsArg is set and arg is "-" then c's Stdout is returned instead.
+// Closing the returned Stdout does not close the underlying writer.
+func (c *Command) OpenOutput(arg string) (io.WriteCloser, error) {
+	if arg == "-" && c.dashIsArg() {
+		return nopWriteCloser{c.stdout()}, nil
+	}
+	return os.Create(arg)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// bufferOutput returns true if c or any of its parents has BufferOutput set.
+func (c *Command) bufferOutput() bool {
+	for c != nil {
+		if c.BufferOutput {
+			return true
+		}
+		c = c.parent
+	}
+	return false
+}
+
+// A syncBuffer is a bytes.Buffer that may be written to by multiple
+// goroutines, such as those started by Func with Go.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// runBuffered calls c.Func with c's output held in a buffer.  The buffer is
+// written to c's Stdout if Func succeeds and to c's Stderr if it fails.
+func (c *Command) runBuffered(ctx context.Context, args []string, extra ...any) error {
+	w := c.stdout()
+	c.outbuf = &syncBuffer{}
+	defer func() { c.outbuf = nil }()
+	// callFunc waits for the goroutines started with Go so nothing is
+	// writing to buf once it returns.
+	err := c.callFunc(ctx, args, extra...)
+	buf := &c.outbuf.buf
+	if err == nil {
+		_, err = buf.WriteTo(w)
+		return err
+	}
+	if buf.Len() > 0 {
+		ew := c.stderr()
+		c.fprintf(ew, "%s: output before the error:\n", c.Command())
+		out := buf.Bytes()
+		if !bytes.HasSuffix(out, []byte("\n")) {
+			out = append(out, '\n')
+		}
+		indent.NewWriter(ew, "  ").Write(out)
+	}
+	return err
+}
@@ -0,0 +1,167 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDashIsArg(t *testing.T) {
+	var in, out bytes.Buffer
+	in.WriteString("input data")
+	var got []string
+	sub := &Command{
+		Name: "copy",
+		Defaults: &struct {
+			V bool `flag:"-v"`
+		}{},
+		Func: func(_ context.Context, c *Command, args []string, _ ...any) error {
+			got = args
+			r, err := c.OpenInput(args[0])
+			if err != nil {
+				return err
+			}
+			defer r.Close()
+			w, err := c.OpenOutput(args[1])
+			if err != nil {
+				return err
+			}
+			defer w.Close()
+			_, err = io.Copy(w, r)
+			return err
+		},
+	}
+	root := &Command{
+		Name:        "root",
+		DashIsArg:   true,
+		Stdin:       &in,
+		Stdout:      &out,
+		SubCommands: []*Command{sub},
+	}
+	if err := root.Run(context.Background(), []string{"copy", "-v", "-", "-"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := `["-" "-"]`; fmt.Sprintf("%q", got) != want {
+		t.Errorf("got args %q, want %s", got, want)
+	}
+	if got, want := out.String(), "input data"; got != want {
+		t.Errorf("got output %q, want %q", got, want)
+	}
+
+	// Without DashIsArg "-" is just a file name.
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	if err := os.WriteFile(src, []byte("file data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	root.DashIsArg = false
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(dir)
+	if err := root.Run(context.Background(), []string{"copy", "-", "out"}); err == nil || !strings.Contains(err.Error(), "no such file") {
+		t.Errorf("got error %v, want no such file", err)
+	}
+	if err := root.Run(context.Background(), []string{"copy", src, "-"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "-"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), "file data"; got != want {
+		t.Errorf("got file %q, want %q", got, want)
+	}
+}
+
+func TestBufferOutput(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	fail := false
+	sub := &Command{
+		Name: "sub",
+		Func: func(_ context.Context, c *Command, _ []string, _ ...any) error {
+			w := c.EffectiveStdout()
+			if w == &stdout {
+				t.Errorf("output is not buffered")
+			}
+			fmt.Fprint(w, "line 1\nline 2")
+			if fail {
+				return errors.New("failed")
+			}
+			return nil
+		},
+	}
+	root := &Command{
+		Name:         "prog",
+		Stdout:       &stdout,
+		Stderr:       &stderr,
+		BufferOutput: true,
+		SubCommands:  []*Command{sub},
+	}
+	if err := root.Run(context.Background(), []string{"sub"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := stdout.String(), "line 1\nline 2"; got != want {
+		t.Errorf("got stdout %q, want %q", got, want)
+	}
+	if stderr.Len() != 0 {
+		t.Errorf("unexpected stderr %q", stderr.String())
+	}
+	if sub.EffectiveStdout() != &stdout {
+		t.Errorf("buffer not removed after Func returned")
+	}
+
+	stdout.Reset()
+	fail = true
+	if err := root.Run(context.Background(), []string{"sub"}); err == nil {
+		t.Fatal("did not get an error")
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("output of failed command written to stdout: %q", stdout.String())
+	}
+	if got, want := stderr.String(), "prog sub: output before the error:\n  line 1\n  line 2\n"; got != want {
+		t.Errorf("got stderr %q, want %q", got, want)
+	}
+}
+
+func TestBufferOutputGo(t *testing.T) {
+	var out bytes.Buffer
+	c := &Command{
+		Name:         "prog",
+		Stdout:       &out,
+		BufferOutput: true,
+		Func: func(ctx context.Context, c *Command, _ []string, _ ...any) error {
+			for i := 0; i < 10; i++ {
+				c.Go(func(context.Context) error {
+					fmt.Fprint(c.EffectiveStdout(), "x")
+					return nil
+				})
+			}
+			return nil
+		},
+	}
+	if err := c.Run(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), strings.Repeat("x", 10); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
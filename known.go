@@ -0,0 +1,147 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"flag"
+	"strings"
+
+	"github.com/pborman/flags"
+)
+
+// A flagLookuper is a flags.FlagSet that can look up its flags by name.  The
+// flag sets returned by flags.NewFlagSet implement it.
+type flagLookuper interface {
+	Lookup(name string) *flag.Flag
+	VisitAll(func(*flag.Flag))
+}
+
+// isBoolFlag returns true if f does not take a value.
+func isBoolFlag(f *flag.Flag) bool {
+	bf, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && bf.IsBoolFlag()
+}
+
+// splitArgs returns the arguments from args that c should parse with set and
+// the arguments that c leaves for its sub command or Func.  Flag names are
+// normalized and, if c has ParseKnownFlags, unknown flags are left.
+func (c *Command) splitArgs(set flags.FlagSet, args []string) (parse, rest []string) {
+	if norm := c.flagNormalizer(); norm != nil {
+		args = normalizeFlags(set, args, norm, c.ParseKnownFlags)
+	}
+	if c.ParseKnownFlags {
+		return knownFlags(set, args)
+	}
+	return args, nil
+}
+
+// flagNormalizer returns the NormalizeFlagName func c inherits, if any.
+func (c *Command) flagNormalizer() func(string) string {
+	for ; c != nil; c = c.parent {
+		if c.NormalizeFlagName != nil {
+			return c.NormalizeFlagName
+		}
+	}
+	return nil
+}
+
+// UnderscoresToDashes is a NormalizeFlagName func that replaces underscores
+// with dashes, so --dry_run is accepted for --dry-run.
+func UnderscoresToDashes(name string) string {
+	return strings.ReplaceAll(name, "_", "-")
+}
+
+// normalizeFlags returns a copy of args with each flag for set renamed to
+// the name it is declared with in set, if the two names are the same once
+// normalized by norm.  Only the leading flags of args, which set parses, are
+// renamed unless all is true, in which case all flags before "--" are.
+func normalizeFlags(set flags.FlagSet, args []string, norm func(string) string, all bool) []string {
+	ls, ok := set.(flagLookuper)
+	if !ok {
+		return args
+	}
+	canon := map[string]string{}
+	ls.VisitAll(func(f *flag.Flag) {
+		canon[norm(f.Name)] = f.Name
+	})
+	args = append([]string(nil), args...)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			if all {
+				continue
+			}
+			break
+		}
+		dashes := "-"
+		if arg[1] == '-' {
+			dashes = "--"
+		}
+		name, value, hasValue := strings.Cut(arg[len(dashes):], "=")
+		declared, ok := canon[norm(name)]
+		if !ok {
+			continue
+		}
+		if declared != name {
+			args[i] = dashes + declared
+			if hasValue {
+				args[i] += "=" + value
+			}
+		}
+		if !hasValue && !isBoolFlag(ls.Lookup(declared)) {
+			i++
+		}
+	}
+	return args
+}
+
+// knownFlags splits args, for a command with ParseKnownFlags, into the flags
+// defined in set, along with their values, and everything else.  Arguments
+// following "--" are never flags for set; the "--" itself is dropped.  The
+// order of the arguments in both lists is preserved.  If set cannot look up
+// its flags then all of args are returned as known.
+func knownFlags(set flags.FlagSet, args []string) (known, rest []string) {
+	ls, ok := set.(flagLookuper)
+	if !ok {
+		return args, nil
+	}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return known, append(rest, args[i+1:]...)
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			rest = append(rest, arg)
+			continue
+		}
+		name, _, hasValue := strings.Cut(strings.TrimPrefix(arg[1:], "-"), "=")
+		f := ls.Lookup(name)
+		if f == nil {
+			rest = append(rest, arg)
+			continue
+		}
+		known = append(known, arg)
+		if hasValue || isBoolFlag(f) {
+			continue
+		}
+		if i+1 < len(args) {
+			i++
+			known = append(known, args[i])
+		}
+	}
+	return known, rest
+}
@@ -0,0 +1,113 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestParseKnownFlags(t *testing.T) {
+	type options struct {
+		Attempts int    `flag:"--attempts=N number of attempts"`
+		Quick    bool   `flag:"-q quick"`
+		Name     string `flag:"--name=NAME name"`
+	}
+	for _, tt := range []struct {
+		args []string
+		want []string
+		opts options
+	}{
+		{
+			args: []string{"--region=x", "--attempts=3", "deploy"},
+			want: []string{"--region=x", "deploy"},
+			opts: options{Attempts: 3},
+		},
+		{
+			args: []string{"deploy", "--attempts", "4", "-q", "--force"},
+			want: []string{"deploy", "--force"},
+			opts: options{Attempts: 4, Quick: true},
+		},
+		{
+			args: []string{"-q=false", "--name", "bob", "--", "deploy", "--attempts=5"},
+			want: []string{"deploy", "--attempts=5"},
+			opts: options{Name: "bob"},
+		},
+		{
+			args: []string{"-", "x"},
+			want: []string{"-", "x"},
+		},
+	} {
+		var got []string
+		var opts *options
+		c := &Command{
+			Name:            "retry",
+			Defaults:        &options{},
+			ParseKnownFlags: true,
+			Func: func(_ context.Context, c *Command, args []string, _ ...any) error {
+				got, opts = args, c.Flags.(*options)
+				return nil
+			},
+		}
+		if err := c.Run(context.Background(), tt.args); err != nil {
+			t.Errorf("%q: %v", tt.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got args %q, want %q", tt.args, got, tt.want)
+		}
+		if *opts != tt.opts {
+			t.Errorf("%q: got flags %+v, want %+v", tt.args, *opts, tt.opts)
+		}
+	}
+}
+
+func TestNormalizeFlagName(t *testing.T) {
+	type options struct {
+		DryRun bool   `flag:"--dry-run do nothing"`
+		Output string `flag:"--output-file=FILE write to FILE"`
+	}
+	var got []string
+	var opts *options
+	sub := &Command{
+		Name:     "sub",
+		Defaults: &options{},
+		Func: func(_ context.Context, c *Command, args []string, _ ...any) error {
+			got, opts = args, c.Flags.(*options)
+			return nil
+		},
+	}
+	root := &Command{
+		Name:              "prog",
+		NormalizeFlagName: UnderscoresToDashes,
+		SubCommands:       []*Command{sub},
+	}
+	ctx := context.Background()
+	if err := root.Run(ctx, []string{"sub", "--dry_run", "--output_file", "--x_y", "a_b", "--dry_run"}); err != nil {
+		t.Fatal(err)
+	}
+	want := options{DryRun: true, Output: "--x_y"}
+	if *opts != want {
+		t.Errorf("got flags %+v, want %+v", *opts, want)
+	}
+	if wantArgs := []string{"a_b", "--dry_run"}; !reflect.DeepEqual(got, wantArgs) {
+		t.Errorf("got args %q, want %q", got, wantArgs)
+	}
+
+	sub.NormalizeFlagName = func(name string) string { return name }
+	if err := root.Run(ctx, []string{"sub", "--dry_run"}); err == nil {
+		t.Errorf("sub command's own NormalizeFlagName not used")
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"strings"
+)
+
+// matchLeaf returns the names of the commands leading from a sub command of c
+// to the leaf command below c named name, either by its Name or by one of its
+// Aliases.  A leaf command is a command without sub commands.  Hidden
+// commands and help topics are not considered.  Nil is returned if there is
+// no such leaf.  An error listing the leaves is returned if name names more
+// than one leaf.
+func (c *Command) matchLeaf(ctx context.Context, name string) ([]string, error) {
+	var found [][]string
+	seen := map[*Command]bool{c: true}
+	var walk func(*Command, []string)
+	walk = func(wc *Command, path []string) {
+		wc.discover(ctx)
+		for _, sc := range wc.SubCommands {
+			if seen[sc] || sc.hidden() || sc.isTopic() {
+				continue
+			}
+			seen[sc] = true
+			sc.discover(ctx)
+			p := append(path[:len(path):len(path)], sc.Name)
+			if len(sc.SubCommands) > 0 {
+				walk(sc, p)
+				continue
+			}
+			for _, n := range append([]string{sc.Name}, sc.Aliases...) {
+				if n == name {
+					found = append(found, p)
+					break
+				}
+			}
+		}
+	}
+	walk(c, nil)
+	switch len(found) {
+	case 0:
+		return nil, nil
+	case 1:
+		return found[0], nil
+	}
+	var names []string
+	for _, p := range found {
+		names = append(names, strings.Join(p, " "))
+	}
+	return nil, c.errorf("%q is ambiguous {%s}", name, strings.Join(names, ", "))
+}
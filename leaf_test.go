@@ -0,0 +1,80 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestLeafMatch(t *testing.T) {
+	var ran []string
+	run := func(_ context.Context, c *Command, args []string, _ ...any) error {
+		ran = append(ran, c.Command()+" "+strings.Join(args, " "))
+		return nil
+	}
+	var out bytes.Buffer
+	root := &Command{
+		Name:           "prog",
+		Stderr:         &out,
+		AllowLeafMatch: true,
+		SubCommands: []*Command{
+			{Name: "node", SubCommands: []*Command{
+				{Name: "add", Aliases: []string{"create"}, Func: run},
+				{Name: "rm", Func: run},
+				{Name: "secret", Hidden: true, Func: run},
+			}},
+			{Name: "user", SubCommands: []*Command{
+				{Name: "rm", Func: run},
+				{Name: "group", SubCommands: []*Command{
+					{Name: "list", Func: run},
+				}},
+			}},
+			HelpCmd,
+		},
+	}
+	ctx := context.Background()
+	for _, args := range [][]string{
+		{"add", "x"},
+		{"create", "y"},
+		{"list"},
+		{"user", "list"},
+	} {
+		if err := root.Run(ctx, args); err != nil {
+			t.Errorf("%q: %v", args, err)
+		}
+	}
+	want := []string{"prog node add x", "prog node add y", "prog user group list ", "prog user group list "}
+	if strings.Join(ran, "|") != strings.Join(want, "|") {
+		t.Errorf("got %q, want %q", ran, want)
+	}
+
+	err := root.Run(ctx, []string{"rm"})
+	if want := `prog: "rm" is ambiguous {node rm, user rm}`; err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %s", err, want)
+	}
+	if err := root.Run(ctx, []string{"secret"}); err == nil {
+		t.Errorf("hidden leaf was matched")
+	}
+
+	if c, _, err := root.Resolve([]string{"list"}); err != nil || c.Command() != "prog user group list" {
+		t.Errorf("Resolve: got %s, %v", c.Command(), err)
+	}
+	wantHelp, _ := root.HelpText("node", "add")
+	if help, err := root.HelpText("add"); err != nil || help != wantHelp {
+		t.Errorf("HelpText: got %q, %v, want %q", help, err, wantHelp)
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// A BusyError is returned by Run and RunSubcommands when the MaxConcurrent
+// or MinInterval limits of a command prevent it from running.
+type BusyError struct {
+	C     *Command
+	Retry time.Duration // If not 0, how long until C may be run again
+}
+
+// Implements the error interface.
+func (b *BusyError) Error() string {
+	if b.Retry > 0 {
+		return fmt.Sprintf("%s: busy, try again in %v", b.C.Command(), b.Retry)
+	}
+	return fmt.Sprintf("%s: busy, too many executions in progress", b.C.Command())
+}
+
+// A limiter tracks the executions of a single command.
+type limiter struct {
+	mu      sync.Mutex
+	running int       // executions in progress
+	last    time.Time // when the most recent execution started
+}
+
+// lazyMu guards the creation of the state of a Command that is only
+// allocated when needed.  Keeping that state behind pointers lets a
+// Command be copied.
+var lazyMu sync.Mutex
+
+// limiter returns the limiter of c, creating it if needed.
+func (c *Command) limiter() *limiter {
+	lazyMu.Lock()
+	defer lazyMu.Unlock()
+	if c.limits == nil {
+		c.limits = &limiter{}
+	}
+	return c.limits
+}
+
+// Tests can override this
+var now = time.Now
+
+// acquire checks c's limits and, if c may run, records the start of an
+// execution.  The returned function must be called when the execution
+// completes.
+func (c *Command) acquire() (func(), error) {
+	if c.MaxConcurrent <= 0 && c.MinInterval <= 0 {
+		return func() {}, nil
+	}
+	l := c.limiter()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if c.MaxConcurrent > 0 && l.running >= c.MaxConcurrent {
+		return nil, &BusyError{C: c}
+	}
+	t := now()
+	if c.MinInterval > 0 && !l.last.IsZero() {
+		if d := l.last.Add(c.MinInterval).Sub(t); d > 0 {
+			return nil, &BusyError{C: c, Retry: d}
+		}
+	}
+	l.last = t
+	l.running++
+	return func() {
+		l.mu.Lock()
+		l.running--
+		l.mu.Unlock()
+	}, nil
+}
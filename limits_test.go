@@ -0,0 +1,95 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMaxConcurrent(t *testing.T) {
+	var nested error
+	cmd := &Command{
+		Name:          "busy",
+		MaxConcurrent: 1,
+		Func: func(ctx context.Context, c *Command, args []string, _ ...any) error {
+			if len(args) == 0 {
+				nested = c.Run(ctx, []string{"again"})
+			}
+			return nil
+		},
+	}
+	if err := cmd.Run(context.Background(), nil); err != nil {
+		t.Errorf("first run failed: %v", err)
+	}
+	var be *BusyError
+	if !errors.As(nested, &be) {
+		t.Errorf("got error %v, want a BusyError", nested)
+	} else if got, want := nested.Error(), "busy: busy, too many executions in progress"; got != want {
+		t.Errorf("got error %q, want %q", got, want)
+	}
+	if err := cmd.Run(context.Background(), []string{"again"}); err != nil {
+		t.Errorf("run after completion failed: %v", err)
+	}
+
+	// The limit also applies to RunSubcommands.
+	root := &Command{
+		Name:          "root",
+		MaxConcurrent: 1,
+		SubCommands: []*Command{{
+			Name: "sub",
+			Func: func(ctx context.Context, c *Command, _ []string, _ ...any) error {
+				nested = c.Parent().RunSubcommands(ctx, []string{"sub"})
+				return nil
+			},
+		}},
+	}
+	nested = nil
+	if err := root.RunSubcommands(context.Background(), []string{"sub"}); err != nil {
+		t.Errorf("RunSubcommands failed: %v", err)
+	}
+	if !errors.As(nested, &be) {
+		t.Errorf("nested RunSubcommands got error %v, want a BusyError", nested)
+	}
+}
+
+func TestMinInterval(t *testing.T) {
+	defer func(n func() time.Time) { now = n }(now)
+	start := time.Now()
+	now = func() time.Time { return start }
+
+	cmd := &Command{
+		Name:        "slow",
+		MinInterval: time.Minute,
+		Func:        func(context.Context, *Command, []string, ...any) error { return nil },
+	}
+	if err := cmd.Run(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	now = func() time.Time { return start.Add(20 * time.Second) }
+	err := cmd.Run(context.Background(), nil)
+	var be *BusyError
+	if !errors.As(err, &be) {
+		t.Fatalf("got error %v, want a BusyError", err)
+	}
+	if be.Retry != 40*time.Second {
+		t.Errorf("got retry %v, want 40s", be.Retry)
+	}
+	now = func() time.Time { return start.Add(time.Minute) }
+	if err := cmd.Run(context.Background(), nil); err != nil {
+		t.Errorf("run after interval failed: %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// A Printer formats the messages displayed by commander, making it possible
+// to localize them.  The format strings commander uses are the keys.
+//
+// The *message.Printer type from golang.org/x/text/message can be used as a
+// Printer with PrinterFunc:
+//
+//	p := message.NewPrinter(language.German)
+//	root.Printer = commander.PrinterFunc(func(format string, a ...any) string {
+//		return p.Sprintf(format, a...)
+//	})
+type Printer interface {
+	Sprintf(format string, a ...any) string
+}
+
+// The PrinterFunc type is an adapter to allow the use of ordinary functions
+// as a Printer.
+type PrinterFunc func(format string, a ...any) string
+
+// Sprintf returns f(format, a...).
+func (f PrinterFunc) Sprintf(format string, a ...any) string {
+	return f(format, a...)
+}
+
+// sprintf formats a message using the Printer of c's root command, or
+// fmt.Sprintf if there is no Printer.
+func (c *Command) sprintf(format string, a ...any) string {
+	if c != nil {
+		if p := c.Root().Printer; p != nil {
+			return p.Sprintf(format, a...)
+		}
+	}
+	return fmt.Sprintf(format, a...)
+}
+
+// errorf is like fmt.Errorf but formats the message with c.sprintf.
+func (c *Command) errorf(format string, a ...any) error {
+	return errors.New(c.sprintf(format, a...))
+}
+
+// fprintf is like fmt.Fprintf but formats the message with c.sprintf.  The
+// error, if any, is only returned for the benefit of Printf.
+func (c *Command) fprintf(w io.Writer, format string, a ...any) error {
+	_, err := io.WriteString(w, c.sprintf(format, a...))
+	return err
+}
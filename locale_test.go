@@ -0,0 +1,57 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestPrinter(t *testing.T) {
+	german := map[string]string{
+		"requires at least %d arguments, got %s": "benötigt mindestens %d Argumente, erhalten %s",
+		"%s has no subcommand %s":                "%s hat keinen Unterbefehl %s",
+		"Usage: %s\n":                            "Aufruf: %s\n",
+	}
+	var buf bytes.Buffer
+	root := &Command{
+		Name:   "prog",
+		Stderr: &buf,
+		Printer: PrinterFunc(func(format string, a ...any) string {
+			if f, ok := german[format]; ok {
+				format = f
+			}
+			return fmt.Sprintf(format, a...)
+		}),
+		SubCommands: []*Command{
+			{Name: "sub", MinArgs: 1, Func: func(context.Context, *Command, []string, ...any) error { return nil }},
+			HelpCmd,
+		},
+	}
+	ctx := context.Background()
+	err := root.Run(ctx, []string{"sub"})
+	if want := "prog sub: benötigt mindestens 1 Argumente, erhalten 0"; err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %s", err, want)
+	}
+	if got := buf.String(); !strings.Contains(got, "Aufruf: prog subcommand [...]\n") {
+		t.Errorf("help not localized:\n%s", got)
+	}
+	err = root.Run(ctx, []string{"help", "bad"})
+	if want := "prog hat keinen Unterbefehl bad"; err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %s", err, want)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// Main runs c with the program's command line arguments and exits with
+// the code returned by ExitCode.  An interrupt (Ctrl-C) cancels the context
+// passed to c, which causes prompts to return a *CanceledError.  A second
+// interrupt kills the program as usual.  Errors other than usage errors,
+// which have already been displayed, and an *ExitError without an Err are
+// displayed on c's Stderr.  If c.QuietBrokenPipe is set then SIGPIPE is
+// ignored so a write to a closed standard output fails with EPIPE, which
+// ends the program quietly, rather than killing the program.  A typical
+// program's main function is:
+//
+//	func main() {
+//		commander.Main(rootCmd)
+//	}
+func Main(c *Command) {
+	run(c, os.Args[1:])
+}
+
+// MultiCall is like Main but lets a single binary be installed under the
+// names of its sub commands, as busybox is.  If the base name of the program,
+// without any .exe suffix, is the name of a sub command of c, or is the name
+// of c, a dash, and the name of a sub command, then that sub command is run
+// with the program's command line arguments.  For example, if c is "tool"
+// and has a sub command "fmt" then running a link to the program named
+// either "fmt" or "tool-fmt" is the same as running "tool fmt".
+func MultiCall(c *Command) {
+	run(c, multiCallArgs(c, os.Args))
+}
+
+// multiCallArgs returns the arguments MultiCall passes to c.Run for the
+// command line argv.
+func multiCallArgs(c *Command, argv []string) []string {
+	name := strings.TrimSuffix(filepath.Base(argv[0]), ".exe")
+	if name == c.Name {
+		return argv[1:]
+	}
+	name = strings.TrimPrefix(name, c.Name+"-")
+	c.discover(context.Background())
+	if sc := c.findSub(name); sc == nil || sc.isTopic() {
+		return argv[1:]
+	}
+	return append([]string{name}, argv[1:]...)
+}
+
+// run implements Main and MultiCall.
+func run(c *Command, args []string) {
+	if c.QuietBrokenPipe {
+		signal.Ignore(syscall.SIGPIPE)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	// Once interrupted, restore the default handling of interrupts so a
+	// program that ignores cancellation can still be killed.
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	err := c.Run(ctx, args)
+	stop() // also ends the goroutine above
+	ee, quiet := err.(*ExitError)
+	quiet = quiet && ee.Err == nil
+	if _, ok := err.(*UsageError); !ok && err != nil && !quiet {
+		c.printError(err)
+	}
+	Exit(ExitCode(err))
+}
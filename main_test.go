@@ -0,0 +1,46 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMultiCallArgs(t *testing.T) {
+	root := &Command{
+		Name: "tool",
+		SubCommands: []*Command{
+			{Name: "fmt", Aliases: []string{"format"}},
+			{Name: "topic", Description: "a help topic"},
+		},
+	}
+	for _, tt := range []struct {
+		argv []string
+		want []string
+	}{
+		{[]string{"tool", "fmt", "x"}, []string{"fmt", "x"}},
+		{[]string{"/usr/bin/tool", "-v"}, []string{"-v"}},
+		{[]string{"/usr/bin/fmt", "x"}, []string{"fmt", "x"}},
+		{[]string{"fmt.exe"}, []string{"fmt"}},
+		{[]string{"/bin/tool-fmt", "-w", "x"}, []string{"fmt", "-w", "x"}},
+		{[]string{"format"}, []string{"format"}},
+		{[]string{"topic", "x"}, []string{"x"}},
+		{[]string{"other", "x"}, []string{"x"}},
+	} {
+		if got := multiCallArgs(root, tt.argv); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got %q, want %q", tt.argv, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+// Use adds middleware that wraps the running of c and all of its
+// descendants.  A middleware is passed the next CommandFunc in the chain and
+// returns a CommandFunc that normally calls it, but may instead return
+// without calling it, call it more than once, or call it with a different
+// context or arguments.  This makes middleware suited to concerns such as
+// authorization, tracing, retries, and locking:
+//
+//	root.Use(func(next commander.CommandFunc) commander.CommandFunc {
+//		return func(ctx context.Context, c *commander.Command, args []string, extra ...any) error {
+//			ctx, span := tracer.Start(ctx, c.Command())
+//			defer span.End()
+//			return next(ctx, c, args, extra...)
+//		}
+//	})
+//
+// The CommandFunc at the end of the chain calls the PersistentPreRun hooks,
+// Func, PostRun, and the PersistentPostRun hooks of the command that is
+// running, which is the command passed to each middleware.  Middleware of
+// the root command is outermost and middleware of the running command is
+// innermost.  The middleware of a single command runs in the order it was
+// added.  Middleware is only called when a command's Func is run, after its
+// flags are parsed and its requirements are checked.
+func (c *Command) Use(mw ...func(next CommandFunc) CommandFunc) {
+	c.middleware = append(c.middleware, mw...)
+}
+
+// wrap returns fn wrapped by the middleware of c and its ancestors.
+func (c *Command) wrap(fn CommandFunc) CommandFunc {
+	path := c.Path()
+	for i := len(path) - 1; i >= 0; i-- {
+		mws := path[i].middleware
+		for j := len(mws) - 1; j >= 0; j-- {
+			fn = mws[j](fn)
+		}
+	}
+	return fn
+}
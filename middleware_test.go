@@ -0,0 +1,74 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestUse(t *testing.T) {
+	var calls []string
+	trace := func(name string) func(CommandFunc) CommandFunc {
+		return func(next CommandFunc) CommandFunc {
+			return func(ctx context.Context, c *Command, args []string, extra ...any) error {
+				calls = append(calls, name+" "+c.Name)
+				err := next(ctx, c, args, extra...)
+				calls = append(calls, "end "+name)
+				return err
+			}
+		}
+	}
+	denied := errors.New("denied")
+	fn := func(_ context.Context, c *Command, args []string, _ ...any) error {
+		calls = append(calls, "func "+strings.Join(args, " "))
+		return nil
+	}
+	admin := &Command{Name: "admin", Func: fn}
+	admin.Use(func(next CommandFunc) CommandFunc {
+		return func(ctx context.Context, c *Command, args []string, extra ...any) error {
+			if len(args) > 0 && args[0] == "nobody" {
+				return denied
+			}
+			return next(ctx, c, append(args, "checked"), extra...)
+		}
+	})
+	root := &Command{
+		Name:        "prog",
+		SubCommands: []*Command{admin},
+		PersistentPreRun: func(context.Context, *Command, []string) error {
+			calls = append(calls, "pre")
+			return nil
+		},
+	}
+	root.Use(trace("a"), trace("b"))
+
+	ctx := context.Background()
+	if err := root.Run(ctx, []string{"admin", "bob"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(calls, "|"), "a admin|b admin|pre|func bob checked|end b|end a"; got != want {
+		t.Errorf("got %s\nwant %s", got, want)
+	}
+
+	calls = nil
+	if err := root.Run(ctx, []string{"admin", "nobody"}); err != denied {
+		t.Errorf("got error %v, want %v", err, denied)
+	}
+	if got, want := strings.Join(calls, "|"), "a admin|b admin|end b|end a"; got != want {
+		t.Errorf("got %s\nwant %s", got, want)
+	}
+}
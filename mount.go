@@ -0,0 +1,94 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Mount adds sub as a sub command of the command found at path below c, as
+// AddCommand does.  Path is a list of sub command names separated by spaces
+// or dots, such as "cluster node" or "cluster.node".  An empty path mounts
+// sub directly below c.  Commands along path that do not exist are created
+// as commands that only have sub commands.  Mount returns an error if a
+// command along path has a Func but no sub commands, or if sub's name is
+// already in use.
+//
+// Mount is intended for composing a tree from independent packages, such as
+// plugins, before the tree is run.
+func (c *Command) Mount(path string, sub *Command) error {
+	if sub == nil || sub.Name == "" {
+		return fmt.Errorf("%s: cannot mount a command without a name", c.Command())
+	}
+	parent := c
+	for _, name := range strings.FieldsFunc(path, func(r rune) bool { return r == '.' || r == ' ' }) {
+		next := parent.findSub(name)
+		if next == nil {
+			next = &Command{Name: name}
+			parent.SubCommands = append(parent.SubCommands, next)
+		} else if next.Func != nil && next.SubCommands == nil {
+			return fmt.Errorf("%s %s: cannot mount below a command without sub commands", parent.Command(), name)
+		}
+		next.parent = parent
+		parent = next
+	}
+	return parent.AddCommand(sub)
+}
+
+// AddCommand adds children as sub commands of c and makes c their parent.
+// An error is returned, and no command is added, if a child has no name or
+// if the name or an alias of a child is already used by a sub command of c
+// or another child.  If c has a SubCommandsFunc it is called first so the
+// loaded sub commands are kept.
+func (c *Command) AddCommand(children ...*Command) error {
+	c.discover(context.Background())
+	used := map[string]bool{}
+	for _, sc := range c.SubCommands {
+		used[sc.Name] = true
+		for _, alias := range sc.Aliases {
+			used[alias] = true
+		}
+	}
+	for _, child := range children {
+		if child == nil || child.Name == "" {
+			return fmt.Errorf("%s: cannot add a command without a name", c.Command())
+		}
+		for _, name := range append([]string{child.Name}, child.Aliases...) {
+			if used[name] {
+				return fmt.Errorf("%s: sub command %s already exists", c.Command(), name)
+			}
+			used[name] = true
+		}
+	}
+	for _, child := range children {
+		child.parent = c
+	}
+	c.SubCommands = append(c.SubCommands, children...)
+	return nil
+}
+
+// RemoveCommand removes the sub command of c named name and returns it.  Nil
+// is returned if c has no sub command named name.
+func (c *Command) RemoveCommand(name string) *Command {
+	for i, sc := range c.SubCommands {
+		if sc.Name == name {
+			c.SubCommands = append(c.SubCommands[:i:i], c.SubCommands[i+1:]...)
+			sc.parent = nil
+			return sc
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMount(t *testing.T) {
+	var ran string
+	leaf := func(name string) *Command {
+		return &Command{
+			Name: name,
+			Func: func(_ context.Context, c *Command, _ []string, _ ...any) error {
+				ran = c.Command()
+				return nil
+			},
+		}
+	}
+	root := &Command{Name: "prog", Stderr: &output}
+	if err := root.Mount("cluster node", leaf("add")); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.Mount("cluster.node", leaf("rm")); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.Mount("", leaf("version")); err != nil {
+		t.Fatal(err)
+	}
+	if len(root.SubCommands) != 2 {
+		t.Errorf("got %d sub commands, want 2", len(root.SubCommands))
+	}
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"cluster", "node", "add"}, "prog cluster node add"},
+		{[]string{"cluster", "node", "rm"}, "prog cluster node rm"},
+		{[]string{"version"}, "prog version"},
+	} {
+		ran = ""
+		if err := root.Run(context.Background(), tt.args); err != nil {
+			t.Errorf("%q: %v", tt.args, err)
+		} else if ran != tt.want {
+			t.Errorf("%q: ran %q, want %q", tt.args, ran, tt.want)
+		}
+	}
+
+	for _, tt := range []struct {
+		path string
+		sub  *Command
+		err  string
+	}{
+		{"cluster node", leaf("add"), "prog cluster node: sub command add already exists"},
+		{"version", leaf("x"), "prog version: cannot mount below a command without sub commands"},
+		{"", &Command{}, "prog: cannot mount a command without a name"},
+	} {
+		err := root.Mount(tt.path, tt.sub)
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("Mount(%q) got error %v, want %s", tt.path, err, tt.err)
+		}
+	}
+}
+
+func TestAddCommand(t *testing.T) {
+	root := &Command{Name: "prog"}
+	list := &Command{Name: "list", Aliases: []string{"ls"}}
+	if err := root.AddCommand(list, &Command{Name: "add"}); err != nil {
+		t.Fatal(err)
+	}
+	if list.parent != root || list.Command() != "prog list" {
+		t.Errorf("parent not set: %q", list.Command())
+	}
+	for _, tt := range []struct {
+		children []*Command
+		err      string
+	}{
+		{[]*Command{{Name: "list"}}, "prog: sub command list already exists"},
+		{[]*Command{{Name: "dir", Aliases: []string{"ls"}}}, "prog: sub command ls already exists"},
+		{[]*Command{{Name: "x"}, {Name: "y", Aliases: []string{"x"}}}, "prog: sub command x already exists"},
+		{[]*Command{{Name: "z"}, nil}, "prog: cannot add a command without a name"},
+	} {
+		err := root.AddCommand(tt.children...)
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("got error %v, want %s", err, tt.err)
+		}
+	}
+	if len(root.SubCommands) != 2 {
+		t.Errorf("failed AddCommand added commands: %d sub commands", len(root.SubCommands))
+	}
+
+	if got := root.RemoveCommand("list"); got != list || list.parent != nil {
+		t.Errorf("RemoveCommand got %v", got)
+	}
+	if got := root.RemoveCommand("list"); got != nil {
+		t.Errorf("RemoveCommand of missing command got %v", got)
+	}
+	if len(root.SubCommands) != 1 || root.SubCommands[0].Name != "add" {
+		t.Errorf("got sub commands %v", root.subCommands())
+	}
+	if err := root.AddCommand(&Command{Name: "ls"}); err != nil {
+		t.Errorf("alias of removed command still in use: %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"time"
+)
+
+// An UpdateNotifier is used by the root command to tell the user a newer
+// version of the program is available.  UpdateNotice is called with the root
+// command after each run and returns the notice to display, or "".  An
+// UpdateNotifier should limit how often it looks for a newer version as it
+// delays the program from exiting.  An *Updater is an UpdateNotifier.
+type UpdateNotifier interface {
+	UpdateNotice(ctx context.Context, root *Command) string
+}
+
+// updateNoticeTimeout limits how long the root command waits for its
+// UpdateNotifier.
+const updateNoticeTimeout = 2 * time.Second
+
+// printUpdateNotice displays the notice, if any, returned by c's
+// UpdateNotifier on c's Stderr.  The UpdateNotifier is given at most
+// updateNoticeTimeout to find it.  Nothing is displayed in quiet mode.
+func (c *Command) printUpdateNotice(ctx context.Context) {
+	if c.Level() < Normal {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, updateNoticeTimeout)
+	defer cancel()
+	if notice := c.UpdateNotifier.UpdateNotice(ctx, c); notice != "" {
+		c.printf("%s\n", notice)
+	}
+}
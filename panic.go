@@ -0,0 +1,80 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+// A PanicError is returned by ReportPanic in place of a panic.
+type PanicError struct {
+	Value any    // the value passed to panic
+	Stack []byte // the stack of the goroutine that panicked
+}
+
+// Implements the error interface.
+func (p *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", p.Value)
+}
+
+// ReportPanic is an OnPanic func that converts the panic into a *PanicError.
+// The stack is displayed on c's Stderr at the Debug level, so a user only
+// sees the error unless debugging.
+func ReportPanic(c *Command, v any) error {
+	stack := debug.Stack()
+	c.Debugf("%s: panic: %v\n%s", c.Command(), v, stack)
+	return &PanicError{Value: v, Stack: stack}
+}
+
+// EffectiveOnPanic returns the OnPanic func used by c.  It is c.OnPanic, if
+// set, otherwise the effective OnPanic of c's parent.  Nil is returned if
+// neither c nor any of its parents have an OnPanic func.
+func (c *Command) EffectiveOnPanic() func(*Command, any) error {
+	for c != nil {
+		if c.OnPanic != nil {
+			return c.OnPanic
+		}
+		c = c.parent
+	}
+	return nil
+}
+
+// callRecovered calls c.Func with the arguments.  If c has an effective
+// OnPanic then a panic in Func is recovered and the error returned by
+// OnPanic is returned.
+func (c *Command) callRecovered(ctx context.Context, args []string, extra ...any) (err error) {
+	if onPanic := c.EffectiveOnPanic(); onPanic != nil {
+		defer func() {
+			if v := recover(); v != nil {
+				err = onPanic(c, v)
+			}
+		}()
+	}
+	return c.Func(ctx, c, args, extra...)
+}
+
+// goRecovered calls fn, started by c.Go, with ctx.  A panic in fn is
+// handled as callRecovered handles a panic in Func.
+func (c *Command) goRecovered(ctx context.Context, fn func(context.Context) error) (err error) {
+	if onPanic := c.EffectiveOnPanic(); onPanic != nil {
+		defer func() {
+			if v := recover(); v != nil {
+				err = onPanic(c, v)
+			}
+		}()
+	}
+	return fn(ctx)
+}
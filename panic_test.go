@@ -0,0 +1,79 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestOnPanic(t *testing.T) {
+	var stderr bytes.Buffer
+	var handled error
+	root := &Command{
+		Name:    "prog",
+		Stderr:  &stderr,
+		OnPanic: ReportPanic,
+		OnError: func(_ *Command, _ []string, _ []any, err error) error {
+			handled = err
+			return err
+		},
+		SubCommands: []*Command{
+			{Name: "boom", Func: func(context.Context, *Command, []string, ...any) error {
+				var m map[string]int
+				m["x"] = 1
+				return nil
+			}},
+			{Name: "go", Func: func(_ context.Context, c *Command, _ []string, _ ...any) error {
+				c.Go(func(context.Context) error { panic("in goroutine") })
+				return nil
+			}},
+		},
+	}
+	ctx := context.Background()
+
+	err := root.Run(ctx, []string{"boom"})
+	pe, ok := err.(*PanicError)
+	if !ok {
+		t.Fatalf("got error %v (%T), want a *PanicError", err, err)
+	}
+	if want := "panic: assignment to entry in nil map"; pe.Error() != want {
+		t.Errorf("got %q, want %q", pe.Error(), want)
+	}
+	if handled != err {
+		t.Errorf("OnError was passed %v", handled)
+	}
+	if !bytes.Contains(pe.Stack, []byte("panic_test.go")) {
+		t.Errorf("stack does not include the panic:\n%s", pe.Stack)
+	}
+	if stderr.Len() != 0 {
+		t.Errorf("stack displayed below the Debug level:\n%s", stderr.String())
+	}
+
+	err = root.Run(ctx, []string{"go"})
+	if err == nil || err.Error() != "panic: in goroutine" {
+		t.Errorf("got error %v, want panic: in goroutine", err)
+	}
+
+	// Without OnPanic the panic is not recovered.
+	root.OnPanic = nil
+	defer func() {
+		if v := recover(); v == nil || !strings.Contains(v.(error).Error(), "nil map") {
+			t.Errorf("got panic %v", v)
+		}
+	}()
+	root.Run(ctx, []string{"boom"})
+}
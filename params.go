@@ -0,0 +1,93 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A Param describes a single positional parameter of a command.
+type Param struct {
+	Name     string `json:"name"`
+	Optional bool   `json:"optional,omitempty"` // the parameter may be left out
+	Repeated bool   `json:"repeated,omitempty"` // the parameter may be given more than once
+}
+
+// ParseParameters parses the conventional description of positional
+// parameters used by the Parameters field of a Command and returns the
+// parameters it describes.  Names are separated by white space.  Names in
+// square brackets are optional and a name followed by "...", either
+// directly, as a separate word, or in brackets as in "[...]", may be
+// repeated.  Angle brackets around a name are removed.  For example,
+// "SRC... [DST]" describes a repeated SRC followed by an optional DST and
+// "[FILE ...]" describes any number of FILEs.
+func ParseParameters(s string) []Param {
+	var params []Param
+	depth := 0
+	for _, tok := range paramTokens(s) {
+		switch tok {
+		case "[":
+			depth++
+		case "]":
+			if depth > 0 {
+				depth--
+			}
+		case "...":
+			if len(params) > 0 {
+				params[len(params)-1].Repeated = true
+			}
+		default:
+			p := Param{Optional: depth > 0}
+			if strings.HasSuffix(tok, "...") {
+				tok, p.Repeated = strings.TrimSuffix(tok, "..."), true
+			}
+			p.Name = strings.TrimSuffix(strings.TrimPrefix(tok, "<"), ">")
+			params = append(params, p)
+		}
+	}
+	return params
+}
+
+// paramTokens splits s into words and the brackets "[" and "]".
+func paramTokens(s string) []string {
+	s = strings.NewReplacer("[", " [ ", "]", " ] ").Replace(s)
+	return strings.Fields(s)
+}
+
+// Params returns the positional parameters of c.  They are parsed from
+// Parameters, with ParseParameters, if it is set.  Otherwise they are
+// derived from the number of arguments c accepts and are named arg0, arg1,
+// and so on, with a repeated arg for any number of additional arguments.  A
+// command without a Func has no parameters unless Parameters is set.
+func (c *Command) Params() []Param {
+	if c.Parameters != "" {
+		return ParseParameters(c.Parameters)
+	}
+	min, max, err := c.arity()
+	if err != nil || max == 0 || c.Func == nil {
+		return nil
+	}
+	var params []Param
+	for i := 0; i < min; i++ {
+		params = append(params, Param{Name: fmt.Sprintf("arg%d", i)})
+	}
+	if max < 0 {
+		return append(params, Param{Name: "arg", Optional: true, Repeated: true})
+	}
+	for i := min; i < max; i++ {
+		params = append(params, Param{Name: fmt.Sprintf("arg%d", i), Optional: true})
+	}
+	return params
+}
@@ -0,0 +1,60 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestParseParameters(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want string
+	}{
+		{"", "[]"},
+		{"NAME", "[{NAME false false}]"},
+		{"NAME [VALUE]", "[{NAME false false} {VALUE true false}]"},
+		{"[FILE ...]", "[{FILE true true}]"},
+		{"[subcommand [...]]", "[{subcommand true true}]"},
+		{"SRC... DST", "[{SRC false true} {DST false false}]"},
+		{"<src> <dst>", "[{src false false} {dst false false}]"},
+		{"[start|stop]", "[{start|stop true false}]"},
+		{"[NAME=VALUE] ...", "[{NAME=VALUE true true}]"},
+	} {
+		if got := fmt.Sprint(ParseParameters(tt.in)); got != tt.want {
+			t.Errorf("ParseParameters(%q) got %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParams(t *testing.T) {
+	noop := func(context.Context, *Command, []string, ...any) error { return nil }
+	for _, tt := range []struct {
+		c    *Command
+		want string
+	}{
+		{&Command{Name: "a", Parameters: "NAME [...]"}, "[{NAME false true}]"},
+		{&Command{Name: "a", Func: noop}, "[{arg true true}]"},
+		{&Command{Name: "a", Func: noop, Arity: "0"}, "[]"},
+		{&Command{Name: "a", Func: noop, Arity: "1..2"}, "[{arg0 false false} {arg1 true false}]"},
+		{&Command{Name: "a", Func: noop, MinArgs: 1}, "[{arg0 false false} {arg true true}]"},
+		{&Command{Name: "a", SubCommands: []*Command{{Name: "b", Func: noop}}}, "[]"},
+	} {
+		if got := fmt.Sprint(tt.c.Params()); got != tt.want {
+			t.Errorf("%+v: got %s, want %s", tt.c, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"errors"
+	"syscall"
+)
+
+// IsBrokenPipe reports whether err is, or wraps, the EPIPE error returned
+// when writing to a pipe whose reader has gone away.
+//
+// When QuietBrokenPipe is set, an EPIPE error returned by Func is treated as
+// success and Printf calls Exit(0) when its write fails with EPIPE.  Note
+// that, unless SIGPIPE is ignored as Main does, the Go runtime kills a
+// program that writes to a broken pipe on its standard output or standard
+// error before the write can return EPIPE.
+func IsBrokenPipe(err error) bool {
+	return errors.Is(err, syscall.EPIPE)
+}
+
+// quietBrokenPipe returns true if err is a broken pipe and c's root has
+// QuietBrokenPipe set.
+func (c *Command) quietBrokenPipe(err error) bool {
+	return err != nil && c.Root().QuietBrokenPipe && IsBrokenPipe(err)
+}
+
+// checkPipe calls Exit(0) if err is a broken pipe that should end the
+// program quietly.
+func (c *Command) checkPipe(err error) {
+	if c.quietBrokenPipe(err) {
+		Exit(0)
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestQuietBrokenPipe(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	r.Close()
+
+	defer func(exit func(int)) { Exit = exit }(Exit)
+	var exited []int
+	Exit = func(x int) { exited = append(exited, x) }
+
+	root := &Command{
+		Name:   "prog",
+		Stdout: w,
+		Stderr: io.Discard,
+		SubCommands: []*Command{
+			{
+				Name: "write",
+				Func: func(_ context.Context, c *Command, _ []string, _ ...any) error {
+					_, err := io.WriteString(c.EffectiveStdout(), "hello\n")
+					return err
+				},
+			},
+			{
+				Name: "print",
+				Func: func(_ context.Context, c *Command, _ []string, _ ...any) error {
+					c.Printf("hello\n")
+					return nil
+				},
+			},
+		},
+	}
+	ctx := context.Background()
+	err = root.Run(ctx, []string{"write"})
+	if !IsBrokenPipe(err) {
+		t.Errorf("got error %v, want a broken pipe", err)
+	}
+	if err := root.Run(ctx, []string{"print"}); err != nil || len(exited) != 0 {
+		t.Errorf("got error %v and exits %v, want neither", err, exited)
+	}
+
+	root.QuietBrokenPipe = true
+	if err := root.Run(ctx, []string{"write"}); err != nil {
+		t.Errorf("got error %v, want nil", err)
+	}
+	if err := root.Run(ctx, []string{"print"}); err != nil {
+		t.Errorf("got error %v, want nil", err)
+	}
+	if got, want := fmt.Sprint(exited), "[0]"; got != want {
+		t.Errorf("got exits %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"runtime"
+	"strings"
+)
+
+// The platform the program is running on.  Tests can override these.
+var (
+	goos   = runtime.GOOS
+	goarch = runtime.GOARCH
+)
+
+// supported returns true if c can run on the current platform, that is if
+// c.Platforms is empty or one of its entries is either the current operating
+// system, such as "linux", or the current operating system and architecture,
+// such as "darwin/arm64".
+func (c *Command) supported() bool {
+	if len(c.Platforms) == 0 {
+		return true
+	}
+	for _, p := range c.Platforms {
+		if p == goos || p == goos+"/"+goarch {
+			return true
+		}
+	}
+	return false
+}
+
+// hidden returns true if c should not be listed by help, either because it
+// is Hidden or because it is not supported on the current platform.
+func (c *Command) hidden() bool {
+	return c.Hidden || !c.supported()
+}
+
+// checkPlatform returns an error if c is not supported on the current
+// platform.  The architecture is only mentioned if c supports the current
+// operating system on other architectures.
+func (c *Command) checkPlatform() error {
+	if c.supported() {
+		return nil
+	}
+	platform := goos
+	for _, p := range c.Platforms {
+		if strings.HasPrefix(p, goos+"/") {
+			platform = goos + "/" + goarch
+		}
+	}
+	return c.errorf("%s: not supported on %s", c.Command(), platform)
+}
@@ -0,0 +1,72 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestPlatforms(t *testing.T) {
+	defer func(os, arch string) { goos, goarch = os, arch }(goos, goarch)
+	goos, goarch = "windows", "amd64"
+
+	var out bytes.Buffer
+	var ran []string
+	run := func(_ context.Context, c *Command, _ []string, _ ...any) error {
+		ran = append(ran, c.Name)
+		return nil
+	}
+	root := &Command{
+		Name:   "prog",
+		Stderr: &out,
+		SubCommands: []*Command{
+			{Name: "any", Help: "runs anywhere", Func: run},
+			{Name: "unix", Help: "unix only", Platforms: []string{"linux", "darwin"}, Func: run},
+			{Name: "arm", Help: "arm only", Platforms: []string{"windows/arm64", "darwin/arm64"}, Func: run},
+			{Name: "win", Help: "windows only", Platforms: []string{"windows/amd64"}, Func: run},
+			HelpCmd,
+		},
+	}
+	ctx := context.Background()
+	if err := root.Run(ctx, []string{"help"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"unix", "arm"} {
+		if strings.Contains(out.String(), name) {
+			t.Errorf("help lists %s:\n%s", name, out.String())
+		}
+	}
+	for _, tt := range []struct {
+		name, err string
+	}{
+		{"any", ""},
+		{"win", ""},
+		{"unix", "prog unix: not supported on windows"},
+		{"arm", "prog arm: not supported on windows/amd64"},
+	} {
+		err := root.Run(ctx, []string{tt.name})
+		switch {
+		case err == nil && tt.err != "":
+			t.Errorf("%s: got no error, want %s", tt.name, tt.err)
+		case err != nil && err.Error() != tt.err:
+			t.Errorf("%s: got error %v, want %s", tt.name, err, tt.err)
+		}
+	}
+	if got, want := strings.Join(ran, ","), "any,win"; got != want {
+		t.Errorf("ran %s, want %s", got, want)
+	}
+}
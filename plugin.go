@@ -0,0 +1,154 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// RunPlugin is an OnUnknownCommand func that runs an external program as the
+// unknown sub command, as git does.  The program's name is the command name
+// of c, with spaces replaced by dashes, a dash, and name.  For example, with
+//
+//	root.OnUnknownCommand = commander.RunPlugin
+//
+// "prog foo a b" runs "prog-foo a b" if prog-foo is found in PATH.  The
+// program is run with the Stdin, Stdout, and Stderr of c.  If the program
+// exits with a non-zero code RunPlugin returns an *ExitError with the code
+// and no Err, which Main does not display.  If the program is not found
+// RunPlugin returns the same *UsageError Run would without OnUnknownCommand.
+func RunPlugin(ctx context.Context, c *Command, name string, args []string) error {
+	path, err := exec.LookPath(strings.ReplaceAll(c.Command(), " ", "-") + "-" + name)
+	if err != nil {
+		return &UsageError{C: c, Err: c.unknownCommand(name)}
+	}
+	return c.runProgram(ctx, path, args)
+}
+
+// runProgram runs the program path with args using the Stdin, Stdout, and
+// Stderr of c.  A non-zero exit code is returned as an *ExitError without an
+// Err.
+func (c *Command) runProgram(ctx context.Context, path string, args []string) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = c.stdin(), c.stdout(), c.stderr()
+	err := cmd.Run()
+	var ee *exec.ExitError
+	if errors.As(err, &ee) && ee.ExitCode() > 0 {
+		return &ExitError{Code: ee.ExitCode()}
+	}
+	return err
+}
+
+// A PluginManifest describes a sub command implemented by an external
+// program.  Manifests are read from a directory by LoadPlugins.  A manifest
+// is a JSON file, such as:
+//
+//	{
+//		"name": "deploy",
+//		"help": "deploy the service",
+//		"parameters": "[--dry-run] ENVIRONMENT",
+//		"path": "bin/deploy",
+//		"mount": "service"
+//	}
+type PluginManifest struct {
+	Name        string   `json:"name"`
+	Aliases     []string `json:"aliases,omitempty"`
+	Help        string   `json:"help,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Parameters  string   `json:"parameters,omitempty"`
+	Category    string   `json:"category,omitempty"`
+
+	// Path is the program to run.  A relative path is relative to the
+	// directory the manifest is in.
+	Path string `json:"path"`
+
+	// Mount is the path below the command the plugin is mounted at, see
+	// Mount.  The plugin is added directly below the command if Mount is
+	// empty.
+	Mount string `json:"mount,omitempty"`
+}
+
+// Command returns a new command described by m.  Its Func runs the program
+// m.Path with the command's arguments, flags included, as RunPlugin does.
+func (m *PluginManifest) Command() *Command {
+	path := m.Path
+	return &Command{
+		Name:        m.Name,
+		Aliases:     m.Aliases,
+		Help:        m.Help,
+		Description: m.Description,
+		Parameters:  m.Parameters,
+		Category:    m.Category,
+		Func: func(ctx context.Context, c *Command, args []string, _ ...any) error {
+			return c.runProgram(ctx, path, args)
+		},
+	}
+}
+
+// LoadPlugins reads the plugin manifests, the files ending in .json, in dir
+// and adds the commands they describe to the tree rooted at c, in the order
+// of the file names, using Mount.  It is not an error for dir to not exist.
+// LoadPlugins stops at the first manifest that is invalid or cannot be
+// mounted and returns an error naming the file.  LoadPlugins is normally
+// called before the tree is run:
+//
+//	if err := root.LoadPlugins(pluginDir); err != nil {
+//		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+//	}
+//	commander.Main(root)
+func (c *Command) LoadPlugins(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		if err := c.loadPlugin(file); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	return nil
+}
+
+// loadPlugin adds the plugin described by the manifest in file to c.
+func (c *Command) loadPlugin(file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	var m PluginManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	switch {
+	case m.Name == "":
+		return errors.New("missing name")
+	case m.Path == "":
+		return errors.New("missing path")
+	case !filepath.IsAbs(m.Path):
+		m.Path = filepath.Join(filepath.Dir(file), m.Path)
+	}
+	return c.Mount(m.Mount, m.Command())
+}
@@ -0,0 +1,124 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestRunPlugin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin is a shell script")
+	}
+	dir := t.TempDir()
+	script := "#!/bin/sh\necho \"hello $*\"\nexit 3\n"
+	if err := os.WriteFile(filepath.Join(dir, "prog-sub-hello"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir)
+
+	var out bytes.Buffer
+	root := &Command{
+		Name:   "prog",
+		Stdout: &out,
+		Stderr: io.Discard,
+		SubCommands: []*Command{{
+			Name:             "sub",
+			OnUnknownCommand: RunPlugin,
+			SubCommands:      []*Command{{Name: "list"}},
+		}},
+	}
+	ctx := context.Background()
+	err := root.Run(ctx, []string{"sub", "hello", "a", "b"})
+	if ee, ok := err.(*ExitError); !ok || ee.Code != 3 || ee.Err != nil {
+		t.Errorf("got error %#v, want exit status 3", err)
+	}
+	if got, want := out.String(), "hello a b\n"; got != want {
+		t.Errorf("got output %q, want %q", got, want)
+	}
+
+	err = root.Run(ctx, []string{"sub", "goodbye"})
+	if _, ok := err.(*UsageError); !ok {
+		t.Errorf("got error %v, want a *UsageError", err)
+	}
+}
+
+func TestLoadPlugins(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin is a shell script")
+	}
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		"bin/deploy": "#!/bin/sh\necho \"deploy $*\"\n",
+		"deploy.json": `{
+	"name": "deploy",
+	"help": "deploy the service",
+	"parameters": "[--dry-run] ENVIRONMENT",
+	"path": "bin/deploy",
+	"mount": "service"
+}`,
+		"notes.txt": "not a manifest",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out bytes.Buffer
+	root := &Command{
+		Name:        "prog",
+		Stdout:      &out,
+		Stderr:      &out,
+		SubCommands: []*Command{HelpCmd},
+	}
+	if err := root.LoadPlugins(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.LoadPlugins(filepath.Join(dir, "missing")); err != nil {
+		t.Errorf("missing directory: %v", err)
+	}
+	ctx := context.Background()
+	if err := root.Run(ctx, []string{"service", "deploy", "--dry-run", "prod"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "deploy --dry-run prod\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	out.Reset()
+	if err := root.Run(ctx, []string{"help", "service"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "deploy [--dry-run] ENVIRONMENT\n    deploy the service\n"; !strings.Contains(out.String(), want) {
+		t.Errorf("help does not contain %q:\n%s", want, out.String())
+	}
+
+	bad := filepath.Join(dir, "z.json")
+	if err := os.WriteFile(bad, []byte(`{"name": "x"}`), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&Command{Name: "prog"}).LoadPlugins(dir); err == nil || err.Error() != bad+": missing path" {
+		t.Errorf("got error %v, want %s: missing path", err, bad)
+	}
+}
@@ -0,0 +1,326 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// A CanceledError is returned by the prompt helpers, such as Prompt and
+// Confirm, when the context is canceled (e.g., by an interrupt) before the
+// user answered.  Main exits with code 130 when a command returns a
+// CanceledError.
+type CanceledError struct {
+	Err error // the error from the context
+}
+
+func (e *CanceledError) Error() string {
+	return "canceled: " + e.Err.Error()
+}
+
+func (e *CanceledError) Unwrap() error {
+	return e.Err
+}
+
+// A Prompter asks the user questions.  Setting the Prompter of the root
+// command makes it possible to replace the basic TerminalPrompter, e.g., with
+// one based on a terminal UI library.  Each method must return promptly with
+// an error when ctx is canceled.
+type Prompter interface {
+	// Input returns a line of text entered by the user.
+	Input(ctx context.Context, question string) (string, error)
+
+	// Confirm returns the answer to a yes or no question.  def is the
+	// answer if the user just presses return.
+	Confirm(ctx context.Context, question string, def bool) (bool, error)
+
+	// Select returns the index of the option the user selects.
+	Select(ctx context.Context, question string, options []string) (int, error)
+
+	// MultiSelect returns the indexes of the options the user selects, in
+	// increasing order.
+	MultiSelect(ctx context.Context, question string, options []string) ([]int, error)
+}
+
+// prompter returns the Prompter used by c.  It is the Prompter of the root
+// command or a TerminalPrompter using c's Stdin and Stderr.  The root keeps
+// the TerminalPrompter for as long as Stdin is unchanged so a line read for
+// a canceled question answers the next one.
+func (c *Command) prompter() Prompter {
+	r := c.Root()
+	if r.Prompter != nil {
+		return r.Prompter
+	}
+	in := c.stdin()
+	lazyMu.Lock()
+	defer lazyMu.Unlock()
+	if r.terminal == nil || !sameReader(r.terminal.In, in) {
+		r.terminal = &TerminalPrompter{In: in}
+	}
+	r.terminal.Out, r.terminal.Printer = c.stderr(), r.Printer
+	return r.terminal
+}
+
+// sameReader returns true if a and b are known to be the same reader.
+func sameReader(a, b io.Reader) bool {
+	t := reflect.TypeOf(a)
+	if t == nil || t != reflect.TypeOf(b) || !t.Comparable() {
+		return false
+	}
+	return a == b
+}
+
+// canceled returns err as a *CanceledError if ctx has been canceled.
+func canceled(ctx context.Context, err error) error {
+	var ce *CanceledError
+	if err == nil || ctx.Err() == nil || errors.As(err, &ce) {
+		return err
+	}
+	return &CanceledError{Err: ctx.Err()}
+}
+
+// Prompt asks question using c's Prompter and returns the text entered by
+// the user.  If ctx is canceled before the user answers then Prompt returns
+// a *CanceledError.  If c is not Interactive then Prompt, and the other
+// prompt helpers, return a *NonInteractiveError without asking.
+func (c *Command) Prompt(ctx context.Context, question string) (string, error) {
+	if err := c.checkInteractive(ctx, question); err != nil {
+		return "", err
+	}
+	s, err := c.prompter().Input(ctx, question)
+	return s, canceled(ctx, err)
+}
+
+// Confirm asks the yes or no question using c's Prompter and returns the
+// answer.  def is returned if the user just presses return.  Like Prompt,
+// Confirm returns a *CanceledError if ctx is canceled.
+func (c *Command) Confirm(ctx context.Context, question string, def bool) (bool, error) {
+	if err := c.checkInteractive(ctx, question); err != nil {
+		return false, err
+	}
+	ok, err := c.prompter().Confirm(ctx, question, def)
+	return ok, canceled(ctx, err)
+}
+
+// Select asks the user to select one of options using c's Prompter and
+// returns its index.  Like Prompt, Select returns a *CanceledError if ctx is
+// canceled.
+func (c *Command) Select(ctx context.Context, question string, options []string) (int, error) {
+	if err := c.checkInteractive(ctx, question); err != nil {
+		return -1, err
+	}
+	i, err := c.prompter().Select(ctx, question, options)
+	return i, canceled(ctx, err)
+}
+
+// MultiSelect asks the user to select any number of options using c's
+// Prompter and returns their indexes.  Like Prompt, MultiSelect returns a
+// *CanceledError if ctx is canceled.
+func (c *Command) MultiSelect(ctx context.Context, question string, options []string) ([]int, error) {
+	if err := c.checkInteractive(ctx, question); err != nil {
+		return nil, err
+	}
+	is, err := c.prompter().MultiSelect(ctx, question, options)
+	return is, canceled(ctx, err)
+}
+
+// A TerminalPrompter is a basic Prompter that displays questions on Out and
+// reads lines of answers from In.  The messages it displays are formatted by
+// Printer, if not nil.  The read from In cannot be stopped when the context
+// is canceled, instead the line it reads answers the next question asked.
+// A TerminalPrompter asks one question at a time, it must not be used by
+// more than one goroutine at once.
+type TerminalPrompter struct {
+	In      io.Reader
+	Out     io.Writer
+	Printer Printer
+
+	pending chan lineResult // the read of a canceled question
+}
+
+// A lineResult is the result of reading a line.
+type lineResult struct {
+	line string
+	err  error
+}
+
+func (t *TerminalPrompter) printf(format string, a ...any) {
+	if t.Printer != nil {
+		io.WriteString(t.Out, t.Printer.Sprintf(format, a...))
+	} else {
+		fmt.Fprintf(t.Out, format, a...)
+	}
+}
+
+// Input implements Prompter.
+func (t *TerminalPrompter) Input(ctx context.Context, question string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", &CanceledError{Err: err}
+	}
+	io.WriteString(t.Out, question)
+	ch := t.pending
+	t.pending = nil
+	if ch == nil {
+		ch = make(chan lineResult, 1)
+		go func() {
+			line, err := readLine(t.In)
+			ch <- lineResult{line, err}
+		}()
+	}
+	select {
+	case r := <-ch:
+		return r.line, r.err
+	case <-ctx.Done():
+		t.pending = ch
+		io.WriteString(t.Out, "\n")
+		return "", &CanceledError{Err: ctx.Err()}
+	}
+}
+
+// Confirm implements Prompter.  The question is asked again if the answer is
+// not understood.
+func (t *TerminalPrompter) Confirm(ctx context.Context, question string, def bool) (bool, error) {
+	choices := "[y/N]"
+	if def {
+		choices = "[Y/n]"
+	}
+	for {
+		answer, err := t.Input(ctx, question+" "+choices+" ")
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "":
+			return def, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+		t.printf("Please answer yes or no.\n")
+	}
+}
+
+// Select implements Prompter.  The options are numbered starting at 1.  The
+// question is asked again if the answer is not the number of an option.
+func (t *TerminalPrompter) Select(ctx context.Context, question string, options []string) (int, error) {
+	if len(options) == 0 {
+		return 0, errors.New("no options to select from")
+	}
+	t.listOptions(question, options)
+	for {
+		answer, err := t.Input(ctx, fmt.Sprintf("[1-%d]: ", len(options)))
+		if err != nil {
+			return 0, err
+		}
+		if n, err := strconv.Atoi(strings.TrimSpace(answer)); err == nil && n >= 1 && n <= len(options) {
+			return n - 1, nil
+		}
+		t.printf("Please enter a number between 1 and %d.\n", len(options))
+	}
+}
+
+// MultiSelect implements Prompter.  The options are numbered starting at 1
+// and selected by entering their numbers separated by spaces or commas.  The
+// question is asked again if the answer is not a list of option numbers.
+func (t *TerminalPrompter) MultiSelect(ctx context.Context, question string, options []string) ([]int, error) {
+	t.listOptions(question, options)
+	for {
+		answer, err := t.Input(ctx, fmt.Sprintf("[1-%d, ...]: ", len(options)))
+		if err != nil {
+			return nil, err
+		}
+		if indexes, ok := parseSelection(answer, len(options)); ok {
+			return indexes, nil
+		}
+		t.printf("Please enter numbers between 1 and %d.\n", len(options))
+	}
+}
+
+// parseSelection parses a list of option numbers between 1 and n separated
+// by spaces or commas and returns their indexes in increasing order.
+func parseSelection(s string, n int) ([]int, bool) {
+	selected := make([]bool, n)
+	for _, f := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+		i, err := strconv.Atoi(f)
+		if err != nil || i < 1 || i > n {
+			return nil, false
+		}
+		selected[i-1] = true
+	}
+	var indexes []int
+	for i, ok := range selected {
+		if ok {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes, true
+}
+
+// listOptions displays question followed by the numbered options.
+func (t *TerminalPrompter) listOptions(question string, options []string) {
+	io.WriteString(t.Out, question+"\n")
+	for i, opt := range options {
+		fmt.Fprintf(t.Out, "  %d) %s\n", i+1, opt)
+	}
+}
+
+// readLine reads a single line from r without reading beyond the newline.
+// io.ErrUnexpectedEOF is returned if r ends before any input is read.
+func readLine(r io.Reader) (string, error) {
+	var line []byte
+	var b [1]byte
+	for {
+		n, err := r.Read(b[:])
+		if n > 0 {
+			if b[0] == '\n' {
+				return strings.TrimSuffix(string(line), "\r"), nil
+			}
+			line = append(line, b[0])
+			continue
+		}
+		if err == io.EOF && len(line) > 0 {
+			return string(line), nil
+		}
+		if err == io.EOF {
+			return "", io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+// ExitCode returns the exit code Main uses for err: 0 if err is nil, the
+// code of an *ExitError, 130 (the code used by shells for an interrupted
+// command) if err is a *CanceledError, and 1 otherwise.
+func ExitCode(err error) int {
+	var ee *ExitError
+	var ce *CanceledError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &ee):
+		return ee.Code
+	case errors.As(err, &ce):
+		return 130
+	}
+	return 1
+}
@@ -0,0 +1,198 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrompt(t *testing.T) {
+	var out bytes.Buffer
+	c := &Command{
+		Name:   "test",
+		Stdin:  strings.NewReader("bob\r\nmaybe\ny\n\nlast"),
+		Stderr: &out,
+	}
+	ctx := context.Background()
+	if got, err := c.Prompt(ctx, "Name? "); err != nil || got != "bob" {
+		t.Errorf("Prompt got %q, %v, want bob", got, err)
+	}
+	if got, err := c.Confirm(ctx, "Continue?", false); err != nil || !got {
+		t.Errorf("Confirm got %v, %v, want true", got, err)
+	}
+	if got, err := c.Confirm(ctx, "Again?", true); err != nil || !got {
+		t.Errorf("Confirm of empty line got %v, %v, want true", got, err)
+	}
+	want := "Name? Continue? [y/N] Please answer yes or no.\nContinue? [y/N] Again? [Y/n] "
+	if got := out.String(); got != want {
+		t.Errorf("got output %q, want %q", got, want)
+	}
+	if got, err := c.Prompt(ctx, ""); err != nil || got != "last" {
+		t.Errorf("Prompt got %q, %v, want last", got, err)
+	}
+	if _, err := c.Prompt(ctx, ""); err != io.ErrUnexpectedEOF {
+		t.Errorf("Prompt at EOF got error %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestPromptCanceled(t *testing.T) {
+	r, w := io.Pipe()
+	defer w.Close()
+	c := &Command{Name: "test", Stdin: r, Stderr: io.Discard}
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error)
+	go func() {
+		_, err := c.Confirm(ctx, "Delete?", false)
+		errc <- err
+	}()
+	w.Write([]byte("ma"))
+	cancel()
+	err := <-errc
+	var ce *CanceledError
+	if !errors.As(err, &ce) {
+		t.Fatalf("got error %v, want a CanceledError", err)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("%v does not wrap context.Canceled", err)
+	}
+	if _, err := c.Prompt(ctx, ""); !errors.As(err, &ce) {
+		t.Errorf("Prompt with canceled context got error %v, want a CanceledError", err)
+	}
+
+	// The line being read when the question was canceled answers the
+	// next question.
+	go w.Write([]byte("ybe\n"))
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if got, err := c.Prompt(ctx, ""); err != nil || got != "maybe" {
+		t.Errorf("Prompt after cancel got %q, %v, want maybe", got, err)
+	}
+}
+
+func TestMainExitCode(t *testing.T) {
+	defer func(args []string) { os.Args = args }(os.Args)
+	defer func(exit func(int)) { Exit = exit }(Exit)
+	Exit = func(x int) { panic(exitStr{fmt.Sprintf("Exit(%d)", x)}) }
+	for _, tt := range []struct {
+		err  error
+		want string
+	}{
+		{nil, "Exit(0)"},
+		{errors.New("failed"), "Exit(1)"},
+		{&CanceledError{Err: context.Canceled}, "Exit(130)"},
+	} {
+		os.Args = []string{"test"}
+		var out bytes.Buffer
+		c := &Command{
+			Name:   "test",
+			Stderr: &out,
+			Func:   func(context.Context, *Command, []string, ...any) error { return tt.err },
+		}
+		func() {
+			defer func() {
+				if p := recover(); p != nil {
+					e, ok := p.(exitStr)
+					if !ok {
+						panic(p)
+					}
+					if e.msg != tt.want {
+						t.Errorf("%v: got %s, want %s", tt.err, e.msg, tt.want)
+					}
+				}
+			}()
+			Main(c)
+			t.Errorf("%v: Main did not exit", tt.err)
+		}()
+		if tt.err != nil && !strings.Contains(out.String(), tt.err.Error()) {
+			t.Errorf("%v: error not displayed: %q", tt.err, out.String())
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+	var out bytes.Buffer
+	c := &Command{
+		Name:   "test",
+		Stdin:  strings.NewReader("4\n2\n3, 1 x\n3,1\n\n"),
+		Stderr: &out,
+	}
+	ctx := context.Background()
+	options := []string{"red", "green", "blue"}
+	if got, err := c.Select(ctx, "Color?", options); err != nil || got != 1 {
+		t.Errorf("Select got %d, %v, want 1", got, err)
+	}
+	if got, err := c.MultiSelect(ctx, "Colors?", options); err != nil || !reflect.DeepEqual(got, []int{0, 2}) {
+		t.Errorf("MultiSelect got %v, %v, want [0 2]", got, err)
+	}
+	if got, err := c.MultiSelect(ctx, "Colors?", options); err != nil || got != nil {
+		t.Errorf("MultiSelect got %v, %v, want none", got, err)
+	}
+	want := "Color?\n  1) red\n  2) green\n  3) blue\n[1-3]: Please enter a number between 1 and 3.\n[1-3]: " +
+		"Colors?\n  1) red\n  2) green\n  3) blue\n[1-3, ...]: Please enter numbers between 1 and 3.\n[1-3, ...]: " +
+		"Colors?\n  1) red\n  2) green\n  3) blue\n[1-3, ...]: "
+	if got := out.String(); got != want {
+		t.Errorf("got output:\n%q\nwant:\n%q", got, want)
+	}
+}
+
+// A fakePrompter always gives the same answers.
+type fakePrompter struct{ err error }
+
+func (f fakePrompter) Input(context.Context, string) (string, error)         { return "input", f.err }
+func (f fakePrompter) Confirm(context.Context, string, bool) (bool, error)   { return true, f.err }
+func (f fakePrompter) Select(context.Context, string, []string) (int, error) { return 1, f.err }
+func (f fakePrompter) MultiSelect(context.Context, string, []string) ([]int, error) {
+	return []int{0}, f.err
+}
+
+func TestPrompter(t *testing.T) {
+	root := &Command{Name: "prog", Prompter: fakePrompter{}}
+	sub := &Command{Name: "sub"}
+	sub.parent = root
+	ctx := context.Background()
+	if got, _ := sub.Prompt(ctx, ""); got != "input" {
+		t.Errorf("Prompt got %q, want input", got)
+	}
+	if got, _ := sub.Confirm(ctx, "", false); !got {
+		t.Errorf("Confirm got false, want true")
+	}
+	if got, _ := sub.Select(ctx, "", nil); got != 1 {
+		t.Errorf("Select got %d, want 1", got)
+	}
+	if got, _ := sub.MultiSelect(ctx, "", nil); !reflect.DeepEqual(got, []int{0}) {
+		t.Errorf("MultiSelect got %v, want [0]", got)
+	}
+
+	// Errors from a Prompter are returned as a CanceledError if the
+	// context was canceled.
+	root.Prompter = fakePrompter{err: errors.New("interrupted")}
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	var ce *CanceledError
+	if _, err := sub.Confirm(cctx, "", false); !errors.As(err, &ce) {
+		t.Errorf("got error %v, want a CanceledError", err)
+	}
+	if _, err := sub.Confirm(ctx, "", false); err == nil || errors.As(err, &ce) {
+		t.Errorf("got error %v, want interrupted", err)
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"reflect"
+	"strings"
+)
+
+// A Querier evaluates a query expression, such as a JMESPath or JSONPath
+// expression, against a record written to a Sink.  It returns the result of
+// the query, which is written in place of the record.  Any JMESPath or
+// JSONPath package can be used with a small adapter.
+type Querier interface {
+	Query(expr string, record any) (any, error)
+}
+
+// The QuerierFunc type is an adapter to allow the use of ordinary functions
+// as a Querier.
+type QuerierFunc func(expr string, record any) (any, error)
+
+// Query calls f(expr, record).
+func (f QuerierFunc) Query(expr string, record any) (any, error) {
+	return f(expr, record)
+}
+
+// PathQuery is a Querier whose expressions are dotted paths, such as
+// "owner.name", naming a single field of the record as described by Sink.
+// The result is nil if the record does not have the field.
+var PathQuery = QuerierFunc(func(expr string, record any) (any, error) {
+	if expr = strings.TrimSpace(expr); expr == "" || expr == "." {
+		return record, nil
+	}
+	return fieldValue(record, expr), nil
+})
+
+// A querySink writes the result of a query of each record to another Sink.
+// A nil result, including a nil pointer, is not written and each element of
+// a []any result is written as a separate record.
+type querySink struct {
+	Sink
+	q    Querier
+	expr string
+	c    *Command
+}
+
+func (s *querySink) Write(record any) error {
+	result, err := s.q.Query(s.expr, record)
+	if err != nil {
+		return s.c.errorf("query %q: %v", s.expr, err)
+	}
+	if v := reflect.ValueOf(result); !v.IsValid() || v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil
+	}
+	switch result := result.(type) {
+	case []any:
+		for _, r := range result {
+			if err := s.Sink.Write(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return s.Sink.Write(result)
+	}
+}
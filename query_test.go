@@ -0,0 +1,93 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	type owner struct {
+		Name string `json:"name"`
+	}
+	type repo struct {
+		Name  string `json:"name"`
+		Owner *owner `json:"owner"`
+	}
+	records := []any{
+		repo{Name: "a", Owner: &owner{Name: "bob"}},
+		repo{Name: "b"},
+		[]any{repo{Name: "c"}, repo{Name: "d"}},
+	}
+	var out bytes.Buffer
+	newRoot := func(q Querier) *Command {
+		out.Reset()
+		return &Command{
+			Name:       "prog",
+			Stdout:     &out,
+			OutputFlag: true,
+			Querier:    q,
+			Func: func(_ context.Context, c *Command, _ []string, _ ...any) error {
+				sink := c.NewSink()
+				for _, r := range records {
+					if err := sink.Write(r); err != nil {
+						return err
+					}
+				}
+				return sink.Flush()
+			},
+		}
+	}
+	ctx := context.Background()
+
+	if err := newRoot(PathQuery).Run(ctx, []string{"--output=jsonl", "--query=owner"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "{\"name\":\"bob\"}\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// Elements of a []any result are separate records.
+	flatten := QuerierFunc(func(expr string, record any) (any, error) {
+		if r, ok := record.([]any); ok {
+			return r, nil
+		}
+		return PathQuery(expr, record)
+	})
+	if err := newRoot(flatten).Run(ctx, []string{"--output=csv", "--query=.", "--columns=name"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "name\na\nb\nc\nd\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	bad := QuerierFunc(func(string, any) (any, error) { return nil, errors.New("syntax error") })
+	err := newRoot(bad).Run(ctx, []string{"--query=[["})
+	if want := `query "[[": syntax error`; err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %s", err, want)
+	}
+
+	// Without a Querier there is no --query flag.
+	err = newRoot(nil).Run(ctx, []string{"--query=owner"})
+	if err == nil || !strings.Contains(err.Error(), "query") {
+		t.Errorf("got error %v, want unknown flag", err)
+	}
+	if help, _ := newRoot(PathQuery).HelpText(); !strings.Contains(help, "--query=EXPR") {
+		t.Errorf("help does not list --query:\n%s", help)
+	}
+}
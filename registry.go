@@ -0,0 +1,87 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// A Registry collects commands registered by independent packages, normally
+// from their init functions, so they can later be added to a command tree
+// with Resolve.  A Registry is safe for concurrent use.  The zero value is
+// an empty registry ready to use.
+type Registry struct {
+	mu       sync.Mutex
+	entries  []registryEntry
+	resolved bool
+}
+
+// A registryEntry is a command registered with Register.
+type registryEntry struct {
+	path  string
+	depth int // number of names in path
+	cmd   *Command
+}
+
+// DefaultRegistry is the Registry used by Register.
+var DefaultRegistry = &Registry{}
+
+// Register registers cmd with DefaultRegistry.  See Registry.Register.
+func Register(path string, cmd *Command) {
+	DefaultRegistry.Register(path, cmd)
+}
+
+// Register registers cmd to be mounted at path, as Mount does, when r is
+// resolved.  For example:
+//
+//	func init() {
+//		commander.Register("cluster", nodeCmd)
+//	}
+//
+// Register panics if r has already been resolved.
+func (r *Registry) Register(path string, cmd *Command) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.resolved {
+		panic("commander: Register called after the registry was resolved")
+	}
+	depth := len(strings.FieldsFunc(path, func(c rune) bool { return c == '.' || c == ' ' }))
+	r.entries = append(r.entries, registryEntry{path: path, depth: depth, cmd: cmd})
+}
+
+// Resolve mounts the commands registered with r in the tree rooted at root.
+// Commands with shorter paths are mounted first so a command named "node"
+// registered at "cluster" becomes the parent of the commands registered at
+// "cluster node" no matter the order they were registered in.  Otherwise
+// commands are mounted in the order they were registered.  Once resolved r
+// cannot be changed.  Resolve returns an error if r has already been
+// resolved or if any command cannot be mounted.
+func (r *Registry) Resolve(root *Command) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.resolved {
+		return errors.New("registry already resolved")
+	}
+	r.resolved = true
+	sort.SliceStable(r.entries, func(i, j int) bool { return r.entries[i].depth < r.entries[j].depth })
+	for _, e := range r.entries {
+		if err := root.Mount(e.path, e.cmd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	noop := func(context.Context, *Command, []string, ...any) error { return nil }
+	var r Registry
+	var wg sync.WaitGroup
+	for _, e := range []struct {
+		path, name string
+	}{
+		{"cluster node", "add"},
+		{"cluster", "node"},
+		{"", "version"},
+		{"cluster.node", "rm"},
+	} {
+		wg.Add(1)
+		go func(path, name string) {
+			defer wg.Done()
+			c := &Command{Name: name, Func: noop}
+			if name == "node" {
+				c = &Command{Name: name, Help: "manage nodes"}
+			}
+			r.Register(path, c)
+		}(e.path, e.name)
+	}
+	wg.Wait()
+
+	root := &Command{Name: "prog"}
+	if err := r.Resolve(root); err != nil {
+		t.Fatal(err)
+	}
+	node, _, err := root.Resolve([]string{"cluster", "node"})
+	if node.Help != "manage nodes" {
+		t.Errorf("got %s (%v), want the registered node command", node.Command(), err)
+	}
+	for _, args := range [][]string{{"version"}, {"cluster", "node", "add"}, {"cluster", "node", "rm"}} {
+		if _, _, err := root.Resolve(args); err != nil {
+			t.Errorf("%q: %v", args, err)
+		}
+	}
+
+	if err := r.Resolve(&Command{Name: "other"}); err == nil {
+		t.Errorf("second Resolve did not fail")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Register after Resolve did not panic")
+		}
+	}()
+	r.Register("", &Command{Name: "late"})
+}
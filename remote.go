@@ -0,0 +1,149 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+//go:build !commander_nonet
+
+package commander
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultTTL is the TTL used by RemoteDefaults when its TTL is 0.
+const DefaultTTL = time.Hour
+
+// DefaultFetchTimeout is how long RemoteDefaults waits for the remote
+// defaults when its Timeout is 0.
+const DefaultFetchTimeout = 10 * time.Second
+
+// maxRemoteSize is the largest remote defaults RemoteDefaults will accept.
+const maxRemoteSize = 1 << 20
+
+// RemoteDefaults fetches flag defaults from an HTTPS endpoint.  The endpoint
+// must return a configuration in the format described by Config.  This
+// enables an organization to centrally manage defaults, such as the URL of a
+// registry, for all users of a program.
+//
+// Remote defaults have lower precedence than the ConfigFile, which has lower
+// precedence than the command line.  Unlike a ConfigFile, unknown keys and
+// invalid values are silently ignored so the remote defaults can be shared by
+// different versions of a program.
+//
+// A fetched configuration is used until its TTL expires.  If CacheFile is set
+// the configuration is also cached in that file so it can be shared by
+// multiple runs of the program.  If the configuration cannot be fetched then
+// an expired cached copy, if any, is used.
+type RemoteDefaults struct {
+	URL       string        // The https URL of the defaults
+	TTL       time.Duration // How long to cache the defaults (DefaultTTL if 0)
+	Timeout   time.Duration // How long to wait for a fetch (DefaultFetchTimeout if 0)
+	CacheFile string        // Optional file to cache the defaults in
+	Client    *http.Client  // Defaults to http.DefaultClient
+
+	mu      sync.Mutex
+	cfg     *Config
+	fetched time.Time
+}
+
+func (r *RemoteDefaults) ttl() time.Duration {
+	if r.TTL > 0 {
+		return r.TTL
+	}
+	return DefaultTTL
+}
+
+func (r *RemoteDefaults) timeout() time.Duration {
+	if r.Timeout > 0 {
+		return r.Timeout
+	}
+	return DefaultFetchTimeout
+}
+
+// Fetch returns the remote defaults, fetching them if the cached copy has
+// expired.
+func (r *RemoteDefaults) Fetch(ctx context.Context) (*Config, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cfg == nil && r.CacheFile != "" {
+		if fi, err := os.Stat(r.CacheFile); err == nil {
+			if cfg, err := ReadConfig(r.CacheFile); err == nil {
+				r.cfg, r.fetched = cfg, fi.ModTime()
+			}
+		}
+	}
+	if r.cfg != nil && now().Sub(r.fetched) < r.ttl() {
+		return r.cfg, nil
+	}
+	cfg, data, err := r.fetch(ctx)
+	if err != nil {
+		if r.cfg != nil {
+			return r.cfg, nil
+		}
+		return nil, err
+	}
+	r.cfg, r.fetched = cfg, now()
+	if r.CacheFile != "" {
+		if os.WriteFile(r.CacheFile, data, 0644) == nil {
+			os.Chtimes(r.CacheFile, r.fetched, r.fetched)
+		}
+	}
+	return cfg, nil
+}
+
+func (r *RemoteDefaults) fetch(ctx context.Context) (*Config, []byte, error) {
+	u, err := url.Parse(r.URL)
+	if err != nil {
+		return nil, nil, err
+	}
+	if u.Scheme != "https" {
+		return nil, nil, fmt.Errorf("%s: remote defaults must use https", r.URL)
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.timeout())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.URL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("%s: %s", r.URL, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteSize+1))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(data) > maxRemoteSize {
+		return nil, nil, fmt.Errorf("%s: remote defaults larger than %d bytes", r.URL, maxRemoteSize)
+	}
+	cfg, err := ParseConfig(r.URL, bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	return cfg, data, nil
+}
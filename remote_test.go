@@ -0,0 +1,127 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+//go:build !commander_nonet
+
+package commander
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRemoteDefaults(t *testing.T) {
+	defer func(n func() time.Time) { now = n }(now)
+	start := time.Now()
+	now = func() time.Time { return start }
+
+	fetches := 0
+	value := 3
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		fmt.Fprintf(w, "sub.value = %d\nname = remote\nunknown.key = ignored\n", value)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	root, got := configTree()
+	root.ConfigFile = filepath.Join(dir, "config")
+	root.RemoteDefaults = &RemoteDefaults{
+		URL:       srv.URL,
+		TTL:       time.Minute,
+		CacheFile: filepath.Join(dir, "cache"),
+		Client:    srv.Client(),
+	}
+	os.WriteFile(root.ConfigFile, []byte("name = local\n"), 0644)
+	ctx := context.Background()
+
+	if err := root.Run(ctx, []string{"sub"}); err != nil {
+		t.Fatal(err)
+	}
+	if got.Value != 3 {
+		t.Errorf("got value %d, want 3", got.Value)
+	}
+	// The local configuration file overrides the remote defaults.
+	if name := root.Lookup("", "name"); name != "local" {
+		t.Errorf("got name %q, want local", name)
+	}
+
+	// The defaults are cached until the TTL expires.
+	value = 4
+	root.Run(ctx, []string{"sub"})
+	if fetches != 1 || got.Value != 3 {
+		t.Errorf("got %d fetches and value %d, want 1 and 3", fetches, got.Value)
+	}
+	now = func() time.Time { return start.Add(2 * time.Minute) }
+	root.Run(ctx, []string{"sub"})
+	if fetches != 2 || got.Value != 4 {
+		t.Errorf("got %d fetches and value %d, want 2 and 4", fetches, got.Value)
+	}
+
+	// A new instance uses the cache file.
+	root.RemoteDefaults = &RemoteDefaults{
+		URL:       srv.URL,
+		TTL:       time.Hour,
+		CacheFile: filepath.Join(dir, "cache"),
+		Client:    srv.Client(),
+	}
+	root.Run(ctx, []string{"sub"})
+	if fetches != 2 || got.Value != 4 {
+		t.Errorf("got %d fetches and value %d, want 2 and 4", fetches, got.Value)
+	}
+
+	// When the server fails the expired copy is used.
+	srv.Close()
+	now = func() time.Time { return start.Add(48 * time.Hour) }
+	root.Run(ctx, []string{"sub"})
+	if got.Value != 4 {
+		t.Errorf("got value %d, want 4", got.Value)
+	}
+
+	if _, err := (&RemoteDefaults{URL: "http://example.com"}).Fetch(ctx); err == nil {
+		t.Errorf("http URL did not fail")
+	}
+}
+
+func TestRemoteDefaultsLimits(t *testing.T) {
+	done := make(chan struct{})
+	slow := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	defer slow.Close()
+	defer close(done)
+	ctx := context.Background()
+	r := &RemoteDefaults{URL: slow.URL, Timeout: 50 * time.Millisecond, Client: slow.Client()}
+	if _, err := r.Fetch(ctx); err == nil {
+		t.Errorf("slow server did not time out")
+	}
+
+	big := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, strings.Repeat("# padding\n", maxRemoteSize/10+1))
+	}))
+	defer big.Close()
+	r = &RemoteDefaults{URL: big.URL, Client: big.Client()}
+	if _, err := r.Fetch(ctx); err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Errorf("got error %v, want too large", err)
+	}
+}
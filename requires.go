@@ -0,0 +1,69 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"errors"
+	"os"
+)
+
+// A RequirementError is returned by Run when a requirement listed in the
+// Requires field of a command is not met.
+type RequirementError struct {
+	C           *Command
+	Requirement string
+	Err         error
+}
+
+func (e *RequirementError) Error() string {
+	return e.C.sprintf("%s: requires %s: %v", e.C.Command(), e.Requirement, e.Err)
+}
+
+func (e *RequirementError) Unwrap() error {
+	return e.Err
+}
+
+// DefaultRequirementChecker is the RequirementChecker used when the root
+// command does not have one.  It checks the "root" requirement, which is met
+// when running as the super user, and accepts all other requirements.  The
+// "root" requirement is not checked on Windows, which has no super user;
+// use a RequirementChecker to check for an elevated process there.
+func DefaultRequirementChecker(ctx context.Context, c *Command, requirement string) error {
+	if requirement == "root" && goos != "windows" && os.Geteuid() != 0 {
+		return errors.New(c.sprintf("not running as root"))
+	}
+	return nil
+}
+
+// checkRequirements checks the requirements of c and its parents, returning
+// a *RequirementError for the first one that is not met.
+func (c *Command) checkRequirements(ctx context.Context) error {
+	check := c.Root().RequirementChecker
+	if check == nil {
+		check = DefaultRequirementChecker
+	}
+	var chain []*Command
+	for p := c; p != nil; p = p.parent {
+		chain = append([]*Command{p}, chain...)
+	}
+	for _, p := range chain {
+		for _, req := range p.Requires {
+			if err := check(ctx, c, req); err != nil {
+				return &RequirementError{C: c, Requirement: req, Err: err}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRequires(t *testing.T) {
+	var buf bytes.Buffer
+	have := map[string]bool{"network": true}
+	var checked []string
+	ran := false
+	sub := &Command{
+		Name:     "deploy",
+		Requires: []string{"docker"},
+		Func: func(context.Context, *Command, []string, ...any) error {
+			ran = true
+			return nil
+		},
+	}
+	root := &Command{
+		Name:     "prog",
+		Stderr:   &buf,
+		Requires: []string{"network"},
+		RequirementChecker: func(_ context.Context, c *Command, req string) error {
+			checked = append(checked, c.Name+":"+req)
+			if !have[req] {
+				return errors.New("not available")
+			}
+			return nil
+		},
+		SubCommands: []*Command{sub},
+	}
+	ctx := context.Background()
+	err := root.Run(ctx, []string{"deploy"})
+	var re *RequirementError
+	if !errors.As(err, &re) || re.Requirement != "docker" {
+		t.Fatalf("got error %v, want a RequirementError for docker", err)
+	}
+	if want := "prog deploy: requires docker: not available"; err.Error() != want {
+		t.Errorf("got error %q, want %q", err, want)
+	}
+	if ran {
+		t.Errorf("Func called with an unmet requirement")
+	}
+	if want := "deploy:network deploy:docker"; strings.Join(checked, " ") != want {
+		t.Errorf("checked %q, want %q", checked, want)
+	}
+	have["docker"] = true
+	if err := root.Run(ctx, []string{"deploy"}); err != nil || !ran {
+		t.Errorf("got error %v, ran %v", err, ran)
+	}
+
+	Help(ctx, root, []string{"deploy"})
+	if !strings.Contains(buf.String(), "Requires: docker\n") {
+		t.Errorf("help does not show requirements:\n%s", buf.String())
+	}
+}
+
+func TestDefaultRequirementCheckerWindows(t *testing.T) {
+	defer func(os, arch string) { goos, goarch = os, arch }(goos, goarch)
+	goos, goarch = "windows", "amd64"
+	if err := DefaultRequirementChecker(context.Background(), &Command{Name: "prog"}, "root"); err != nil {
+		t.Errorf("root requirement on windows: %v", err)
+	}
+}
@@ -0,0 +1,224 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Resolve determines which command in the tree rooted at c would be run by
+// Run with args, without running it.  It returns the command along with the
+// positional parameters that would be passed to its Func.  Flags are parsed
+// at each level, as they would be by Run, but the parsed values are
+// discarded; neither Flags nor the configuration of any command is altered.
+// If the arguments would be passed to an OnUnknownCommand func then that
+// command is returned along with the arguments, starting with the unknown
+// name.  The returned error, if any, is a *UsageError.
+func (c *Command) Resolve(args []string) (*Command, []string, error) {
+	return c.resolve(args, func(string, ...any) {})
+}
+
+// resolve implements Resolve.  explain is called to describe each step taken
+// in resolving args.
+func (c *Command) resolve(args []string, explain func(format string, a ...any)) (*Command, []string, error) {
+	for {
+		c.discover(context.Background())
+		if c.NormalizeArgs != nil {
+			normalized := c.NormalizeArgs(args)
+			if !equalArgs(args, normalized) {
+				explain("%s: normalizes %s to %s", c.Command(), quoteArgs(args), quoteArgs(normalized))
+			}
+			args = normalized
+		}
+		if _, set := c.newFlagSet(); set != nil {
+			var rest []string
+			args, rest = c.splitArgs(set, args)
+			if err := set.Parse(args); err != nil {
+				return c, args, &UsageError{C: c, Err: err}
+			}
+			if n := len(args) - len(set.Args()); n > 0 {
+				explain("%s: flags %s", c.Command(), quoteArgs(args[:n]))
+			}
+			args = append(set.Args(), rest...)
+		}
+		if err := c.checkArgs(args); err != nil {
+			return c, args, err
+		}
+		if c.SubCommands == nil || (len(args) == 0 && c.Func != nil) {
+			if c.Func != nil {
+				explain("%s: runs with arguments %s", c.Command(), quoteArgs(args))
+			}
+			return c, args, nil
+		}
+		if len(args) == 0 && c.DefaultSubCommand != "" {
+			explain("%s: runs default sub command %s", c.Command(), c.DefaultSubCommand)
+			args = []string{c.DefaultSubCommand}
+		}
+		if len(args) == 0 {
+			return c, args, &UsageError{
+				C:   c,
+				Err: c.errorf("sub command required {%s}", strings.Join(c.subCommands(), ", ")),
+			}
+		}
+		sc, err := c.matchSub(args[0], c.Root().AllowPrefixMatch)
+		if err != nil {
+			return c, args, &UsageError{C: c, Err: err}
+		}
+		if sc == nil && c.Root().AllowLeafMatch {
+			path, err := c.matchLeaf(context.Background(), args[0])
+			if err != nil {
+				return c, args, &UsageError{C: c, Err: err}
+			}
+			if path != nil {
+				explain("%s: %q is the leaf command %s", c.Command(), args[0], strings.Join(path, " "))
+				args = append(path, args[1:]...)
+				sc = c.findSub(args[0])
+			}
+		}
+		if sc == nil && c.OnUnknownCommand != nil {
+			explain("%s: %q is passed to OnUnknownCommand with arguments %s", c.Command(), args[0], quoteArgs(args[1:]))
+			return c, args, nil
+		}
+		if sc == nil {
+			return c, args, &UsageError{
+				C:   c,
+				Err: c.unknownCommand(args[0]),
+			}
+		}
+		switch {
+		case sc.Name == args[0]:
+			explain("%s: %q is a sub command", c.Command(), args[0])
+		case c.findSub(args[0]) == sc:
+			explain("%s: %q is an alias of sub command %s", c.Command(), args[0], sc.Name)
+		default:
+			explain("%s: %q is a prefix of sub command %s", c.Command(), args[0], sc.Name)
+		}
+		sc.parent = c
+		c, args = sc, args[1:]
+	}
+}
+
+// equalArgs returns true if a and b are the same arguments.
+func equalArgs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// quoteArgs returns args as a bracketed list of quoted strings.
+func quoteArgs(args []string) string {
+	q := make([]string, len(args))
+	for i, arg := range args {
+		q[i] = strconv.Quote(arg)
+	}
+	return "[" + strings.Join(q, " ") + "]"
+}
+
+// WhichCmd is a sub command that calls the Which function.
+var WhichCmd = &Command{
+	Name:       "which",
+	Help:       "display which command handles a command line",
+	Parameters: "[subcommand [...]]",
+	Func:       Which,
+}
+
+// Which implements the which command.
+//
+//	Usage: which [subcommand [...]] [arguments]
+//
+// Which displays the full name of the command that would be run by the root
+// command when given args, followed by how it was chosen.  Nothing is run.
+// This is similar to the type and which commands found in shells.
+func Which(ctx context.Context, c *Command, args []string, extra ...any) error {
+	w := c.stdout()
+	var steps []string
+	rc, _, err := c.Root().resolve(args, func(format string, a ...any) {
+		steps = append(steps, c.sprintf(format, a...))
+	})
+	if err != nil {
+		return err
+	}
+	c.fprintf(w, "%s\n", rc.Command())
+	for _, step := range steps {
+		c.fprintf(w, "  %s\n", step)
+	}
+	return nil
+}
+
+// CheckExamples verifies the Examples of every command in the tree rooted at
+// c.  Each example is split with SplitLine and must start with the name of c.
+// The remainder of the example must resolve, as by Resolve, to the command
+// that lists the example; an example that names an unknown flag or sub
+// command, or has the wrong number of parameters, is reported.  If run is not
+// nil, it is then called with the words of each valid example, excluding the
+// name of c, and any error it returns is reported.  Typically run executes
+// the example in a sandbox, such as c.Run with temporary directories and
+// fake inputs.  Examples that contain placeholders, such as <FILE>, are
+// templates and are not passed to run.
+//
+// CheckExamples is intended to be called from tests:
+//
+//	func TestExamples(t *testing.T) {
+//		for _, err := range cmd.CheckExamples(nil) {
+//			t.Error(err)
+//		}
+//	}
+func (c *Command) CheckExamples(run func(args []string) error) []error {
+	var errs []error
+	var walk func(*Command)
+	walk = func(ec *Command) {
+		for _, ex := range ec.Examples {
+			if err := c.checkExample(ec, ex, run); err != nil {
+				errs = append(errs, fmt.Errorf("%s: example %q: %w", ec.Command(), ex, err))
+			}
+		}
+		for _, sc := range ec.SubCommands {
+			sc.parent = ec
+			walk(sc)
+		}
+	}
+	walk(c)
+	return errs
+}
+
+// checkExample checks the single example ex listed by ec.
+func (c *Command) checkExample(ec *Command, ex string, run func([]string) error) error {
+	words, err := SplitLine(ex)
+	if err != nil {
+		return err
+	}
+	if len(words) == 0 || words[0] != c.Name {
+		return fmt.Errorf("does not start with %s", c.Name)
+	}
+	rc, _, err := c.Resolve(words[1:])
+	if err != nil {
+		return err
+	}
+	if rc != ec {
+		return fmt.Errorf("runs %s", rc.Command())
+	}
+	if run == nil || hasPlaceholder(ex) {
+		return nil
+	}
+	return run(words[1:])
+}
@@ -0,0 +1,155 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func exampleTree() *Command {
+	return &Command{
+		Name: "prog",
+		Defaults: &struct {
+			Verbose bool `flag:"-v be verbose"`
+		}{},
+		SubCommands: []*Command{
+			{
+				Name:     "add",
+				MinArgs:  1,
+				Examples: []string{`prog -v add "a file"`, "prog add --force x y"},
+				Defaults: &struct {
+					Force bool `flag:"--force overwrite"`
+				}{},
+				Func: func(context.Context, *Command, []string, ...any) error { return nil },
+			},
+			{
+				Name:    "list",
+				MaxArgs: NoArgs,
+				Defaults: &struct {
+					Long bool `flag:"-l long listing"`
+				}{},
+				Examples: []string{"prog list", "prog list --all", "prog list x", "prog lsit", "other list"},
+				Func:     func(context.Context, *Command, []string, ...any) error { return nil },
+			},
+		},
+	}
+}
+
+func TestResolve(t *testing.T) {
+	root := exampleTree()
+	c, args, err := root.Resolve([]string{"-v", "add", "--force", "a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Name != "add" || !reflect.DeepEqual(args, []string{"a", "b"}) {
+		t.Errorf("got %s %q, want add [a b]", c.Name, args)
+	}
+	if root.Flags != nil || c.Flags != nil {
+		t.Errorf("Resolve altered Flags")
+	}
+	for _, tt := range []struct {
+		args []string
+		err  string
+	}{
+		{nil, "prog: sub command required {add, list}"},
+		{[]string{"rm"}, `prog: "rm": unknown command`},
+		{[]string{"add"}, "prog add: requires at least 1 arguments, got 0"},
+		{[]string{"add", "--bad", "x"}, "flag provided but not defined: -bad"},
+	} {
+		_, _, err := root.Resolve(tt.args)
+		var ue *UsageError
+		if !errors.As(err, &ue) {
+			t.Errorf("Resolve(%q) got error %v, want a UsageError", tt.args, err)
+		} else if got := err.Error(); !strings.Contains(got, tt.err) {
+			t.Errorf("Resolve(%q) got error %q, want %q", tt.args, got, tt.err)
+		}
+	}
+}
+
+func TestCheckExamples(t *testing.T) {
+	root := exampleTree()
+	var ran [][]string
+	errs := root.CheckExamples(func(args []string) error {
+		ran = append(ran, args)
+		return nil
+	})
+	want := []string{
+		`prog list: example "prog list --all": prog list: flag provided but not defined: -all`,
+		`prog list: example "prog list x": prog list: takes no arguments, got 1: ["x"]`,
+		`prog list: example "prog lsit": prog: "lsit": unknown command, did you mean "list"?`,
+		`prog list: example "other list": does not start with prog`,
+	}
+	var got []string
+	for _, err := range errs {
+		got = append(got, err.Error())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got errors:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	wantRan := [][]string{{"-v", "add", "a file"}, {"add", "--force", "x", "y"}, {"list"}}
+	if !reflect.DeepEqual(ran, wantRan) {
+		t.Errorf("ran %q, want %q", ran, wantRan)
+	}
+
+	root = exampleTree()
+	root.SubCommands[1].Examples = nil
+	errs = root.CheckExamples(func(args []string) error { return errors.New("failed") })
+	if len(errs) != 2 || !strings.HasSuffix(errs[0].Error(), ": failed") {
+		t.Errorf("got errors %v, want two failures", errs)
+	}
+}
+
+func TestHelpExamples(t *testing.T) {
+	var buf bytes.Buffer
+	root := exampleTree()
+	root.Stderr = &buf
+	if err := Help(context.Background(), root, []string{"add"}); err != nil {
+		t.Fatal(err)
+	}
+	want := "\nExamples:\n    prog -v add \"a file\"\n    prog add --force x y\n"
+	if got := buf.String(); !strings.HasSuffix(got, want) {
+		t.Errorf("got:\n%s\nwant suffix:\n%s", got, want)
+	}
+}
+
+func TestWhich(t *testing.T) {
+	var buf bytes.Buffer
+	root := exampleTree()
+	root.Stdout = &buf
+	root.SubCommands = append(root.SubCommands, WhichCmd)
+	if err := root.Run(context.Background(), []string{"which", "-v", "add", "--force", "a"}); err != nil {
+		t.Fatal(err)
+	}
+	want := `prog add
+  prog: flags ["-v"]
+  prog: "add" is a sub command
+  prog add: flags ["--force"]
+  prog add: runs with arguments ["a"]
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if root.SubCommands[0].Flags != nil {
+		t.Errorf("which altered the flags of add")
+	}
+	err := root.Run(context.Background(), []string{"which", "rm"})
+	if want := `prog: "rm": unknown command`; err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %s", err, want)
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// maxScriptLine is the longest line, after joining continued lines, that
+// RunScript will read.
+const maxScriptLine = 1 << 20
+
+// RunFromReader runs the commands read from r with c as the root command.
+// r may be any source of commands, such as a network connection, an
+// embedded script, or a here document.  It is the same as c.RunScript(ctx, r).
+func RunFromReader(ctx context.Context, c *Command, r io.Reader) error {
+	return c.RunScript(ctx, r)
+}
+
+// RunScript runs each line read from r as the arguments to c.Run.  Lines
+// are split into words with the quoting rules of SplitLine.  A line that ends
+// with a backslash is continued on the next line.  Blank lines and lines
+// starting with # are ignored.  RunScript stops at the first command that
+// fails and returns its error, prefixed by the line number.
+//
+// A line may redirect the Stdin and Stdout of its command:
+//
+//	< file   read Stdin from file
+//	> file   write Stdout to file, truncating it
+//	>> file  append Stdout to file
+//
+// The file name may follow the operator directly, as in >file.  A quoted
+// operator, such as '>', is a normal argument.
+func (c *Command) RunScript(ctx context.Context, r io.Reader) error {
+	return scanScript(r, func(_ int, line string) error {
+		words, err := splitLine(line)
+		if err != nil {
+			return err
+		}
+		return c.runScriptLine(ctx, words)
+	})
+}
+
+// scanScript calls fn with each line of the script read from r, along with
+// its line number, joining continued lines and skipping blank lines and
+// comments.  It stops at the first error returned by fn and returns it,
+// prefixed by the line number.
+func scanScript(r io.Reader, fn func(n int, line string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxScriptLine)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		start := n
+		for !strings.HasPrefix(line, "#") && continued(line) && scanner.Scan() {
+			n++
+			line = line[:len(line)-1] + " " + strings.TrimSpace(scanner.Text())
+			if len(line) > maxScriptLine {
+				return fmt.Errorf("line %d: %w", start, bufio.ErrTooLong)
+			}
+		}
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if err := fn(start, line); err != nil {
+			return fmt.Errorf("line %d: %w", start, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// continued reports whether line ends with an unescaped backslash.
+func continued(line string) bool {
+	n := len(line) - len(strings.TrimRight(line, "\\"))
+	return n%2 == 1
+}
+
+// runScriptLine runs a single line of a script.
+func (c *Command) runScriptLine(ctx context.Context, words []lineWord) (err error) {
+	var args []string
+	var in, out string
+	appendOut := false
+	for i := 0; i < len(words); i++ {
+		w := words[i]
+		var op string
+		switch {
+		case w.quoted:
+		case strings.HasPrefix(w.text, ">>"):
+			op = ">>"
+		case strings.HasPrefix(w.text, ">"):
+			op = ">"
+		case strings.HasPrefix(w.text, "<"):
+			op = "<"
+		}
+		if op == "" {
+			args = append(args, w.text)
+			continue
+		}
+		file := strings.TrimPrefix(w.text, op)
+		if file == "" {
+			if i++; i == len(words) {
+				return fmt.Errorf("missing file name after %s", op)
+			}
+			file = words[i].text
+		}
+		if op == "<" {
+			if in != "" {
+				return fmt.Errorf("multiple input redirections")
+			}
+			in = file
+			continue
+		}
+		if out != "" {
+			return fmt.Errorf("multiple output redirections")
+		}
+		out, appendOut = file, op == ">>"
+	}
+
+	defer func(stdin io.Reader, stdout io.Writer) {
+		c.Stdin, c.Stdout = stdin, stdout
+	}(c.Stdin, c.Stdout)
+	if in != "" {
+		f, err := os.Open(in)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		c.Stdin = f
+	}
+	if out != "" {
+		flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		if appendOut {
+			flag = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+		}
+		f, err := os.OpenFile(out, flag, 0666)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+		}()
+		c.Stdout = f
+	}
+	return c.Run(ctx, args)
+}
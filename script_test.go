@@ -0,0 +1,103 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunScript(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in")
+	out := filepath.Join(dir, "out")
+	if err := os.WriteFile(in, []byte("from file\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	var echoed []string
+	root := &Command{
+		Name:   "prog",
+		Stderr: io.Discard,
+		SubCommands: []*Command{
+			{
+				Name: "echo",
+				Func: func(_ context.Context, c *Command, args []string, _ ...any) error {
+					echoed = append(echoed, strings.Join(args, "|"))
+					c.Printf("%s\n", strings.Join(args, " "))
+					return nil
+				},
+			},
+			{
+				Name: "cat",
+				Func: func(_ context.Context, c *Command, _ []string, _ ...any) error {
+					_, err := io.Copy(c.EffectiveStdout(), c.stdin())
+					return err
+				},
+			},
+		},
+	}
+	script := `
+# a comment
+echo 'a b' c
+echo one >` + out + `
+echo two >> ` + out + `
+echo '>' "<"
+cat < ` + in + ` >>` + out + `
+`
+	if err := root.RunScript(context.Background(), strings.NewReader(script)); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a b|c", "one", "two", ">|<"}; strings.Join(echoed, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got %q, want %q", echoed, want)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), "one\ntwo\nfrom file\n"; got != want {
+		t.Errorf("got output file %q, want %q", got, want)
+	}
+	if root.Stdin != nil || root.Stdout != nil {
+		t.Errorf("redirections not undone")
+	}
+
+	echoed = nil
+	script = "# comment \\\necho a \\\n  b\\\\\necho 'c\\' \\\n\n"
+	if err := RunFromReader(context.Background(), root, strings.NewReader(script)); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a|b\\", "c\\"}; strings.Join(echoed, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got %q, want %q", echoed, want)
+	}
+
+	for _, tt := range []struct {
+		script, err string
+	}{
+		{"echo a\nbad", `line 2: prog: "bad": unknown command`},
+		{"echo a \\\nb\nbad", `line 3: prog: "bad": unknown command`},
+		{"echo \\\n'a", "line 1: unterminated ' quote"},
+		{"echo >", "line 1: missing file name after >"},
+		{"echo <a <b", "line 1: multiple input redirections"},
+		{"echo 'a", "line 1: unterminated ' quote"},
+	} {
+		err := root.RunScript(context.Background(), strings.NewReader(tt.script))
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("%q: got error %v, want %s", tt.script, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"math/rand"
+	"strconv"
+
+	"github.com/pborman/flags"
+)
+
+// Rand returns the source of random numbers for the current invocation of
+// the tree c is in.  The source is seeded with the value of the standard
+// --seed flag (see the SeedFlag field of Command), or a seed based on the
+// time if --seed was not given.  The seed is displayed at the Verbose level,
+// and returned by Seed, so a run can be reproduced exactly.  The returned
+// source is not safe for concurrent use.
+func (c *Command) Rand() *rand.Rand {
+	r := c.Root()
+	if r.rng == nil {
+		if !r.seeded {
+			r.seed = now().UnixNano()
+			r.seeded = true
+			c.Verbosef("%s: random seed %d\n", r.Name, r.seed)
+		}
+		r.rng = rand.New(rand.NewSource(r.seed))
+	}
+	return r.rng
+}
+
+// Seed returns the seed of the source returned by Rand.
+func (c *Command) Seed() int64 {
+	c.Rand()
+	return c.Root().seed
+}
+
+// resetRand discards the source of random numbers of the root command c so
+// the next invocation gets a new one.
+func (c *Command) resetRand() {
+	c.rng = nil
+	c.seeded = false
+}
+
+// seedFlagHelp returns the help for the standard --seed flag if c accepts it.
+func (c *Command) seedFlagHelp() []standardFlag {
+	if !c.acceptsSeed() {
+		return nil
+	}
+	return []standardFlag{{"--seed=N", "seed random numbers with N"}}
+}
+
+// acceptsSeed returns true if c accepts the standard --seed flag.  Only a
+// root command with SeedFlag set accepts it, and only if it does not declare
+// its own seed flag.
+func (c *Command) acceptsSeed() bool {
+	if c.parent != nil || !c.SeedFlag {
+		return false
+	}
+	return lookupFlagField(c.getFlags(), "seed") == nil
+}
+
+// addSeedFlag adds the standard --seed flag to set if c accepts it.  The
+// returned function, which is nil if the flag was not added, must be called
+// after set is parsed.
+func (c *Command) addSeedFlag(set flags.FlagSet) func() error {
+	if !c.acceptsSeed() {
+		return nil
+	}
+	var seed string
+	set.StringVar(&seed, "seed", "", "seed random numbers with N")
+	return func() error {
+		if seed == "" {
+			return nil
+		}
+		n, err := strconv.ParseInt(seed, 0, 64)
+		if err != nil {
+			return &UsageError{C: c, Err: c.errorf("invalid seed %q", seed)}
+		}
+		c.seed, c.seeded = n, true
+		return nil
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSeedFlag(t *testing.T) {
+	var stderr bytes.Buffer
+	var got []int
+	root := &Command{
+		Name:           "prog",
+		Stderr:         &stderr,
+		SeedFlag:       true,
+		VerbosityFlags: true,
+		Func: func(_ context.Context, c *Command, _ []string, _ ...any) error {
+			got = append(got, c.Rand().Int())
+			return nil
+		},
+	}
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if err := root.Run(ctx, []string{"--seed=42"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got[0] != got[1] {
+		t.Errorf("same seed gave different numbers: %d and %d", got[0], got[1])
+	}
+	if root.Seed() != 42 {
+		t.Errorf("got seed %d, want 42", root.Seed())
+	}
+
+	defer func(n func() time.Time) { now = n }(now)
+	now = func() time.Time { return time.Unix(0, 1234) }
+	if err := root.Run(ctx, []string{"-v"}); err != nil {
+		t.Fatal(err)
+	}
+	if root.Seed() != 1234 {
+		t.Errorf("got seed %d, want 1234", root.Seed())
+	}
+	if want := "prog: random seed 1234\n"; stderr.String() != want {
+		t.Errorf("got stderr %q, want %q", stderr.String(), want)
+	}
+
+	err := root.Run(ctx, []string{"--seed=x"})
+	if want := `prog: invalid seed "x"`; err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %s", err, want)
+	}
+
+	stderr.Reset()
+	Help(ctx, root, nil)
+	if !strings.Contains(stderr.String(), "--seed=N") {
+		t.Errorf("help does not show --seed:\n%s", stderr.String())
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"fmt"
+)
+
+// SelfTestCmd is a sub command that calls the SelfTest function.
+var SelfTestCmd = &Command{
+	Name:  "self-test",
+	Help:  "verify the command tree of the program",
+	Arity: "0",
+	Func:  SelfTest,
+}
+
+// SelfTest implements the self-test command.
+//
+//	Usage: self-test
+//
+// SelfTest checks the command tree the program was built with.  It reports
+// the problems found by Validate, any command that its own path does not
+// resolve to, any command whose help cannot be displayed, and any example
+// that does not run the command that lists it (see CheckExamples).  No
+// command is run.  SelfTest returns an *ExitError with a code of 1 if any
+// problems were found.  SelfTest is intended to be run by packagers after
+// installing the program.
+func SelfTest(ctx context.Context, c *Command, args []string, extra ...any) error {
+	root := c.Root()
+	errs := root.Validate()
+	var n int
+	root.Walk(func(wc *Command) error {
+		n++
+		var path []string
+		for _, pc := range wc.Path()[1:] {
+			path = append(path, pc.Name)
+		}
+		rc, _, err := root.Resolve(path)
+		switch {
+		case rc == wc:
+		case wc.DefaultSubCommand != "" && rc.parent == wc:
+		case err != nil:
+			errs = append(errs, fmt.Errorf("%s: %v", wc.Command(), err))
+			return nil
+		default:
+			errs = append(errs, fmt.Errorf("%s: resolves to %s", wc.Command(), rc.Command()))
+			return nil
+		}
+		if _, err := root.HelpText(path...); err != nil {
+			errs = append(errs, fmt.Errorf("%s: help: %v", wc.Command(), err))
+		}
+		return nil
+	})
+	errs = append(errs, root.CheckExamples(nil)...)
+	for _, err := range errs {
+		c.Printf("%v\n", err)
+	}
+	if len(errs) > 0 {
+		return &ExitError{Code: 1, Err: c.errorf("%d problems found", len(errs))}
+	}
+	c.Printf("ok, checked %d commands\n", n)
+	return nil
+}
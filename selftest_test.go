@@ -0,0 +1,67 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestSelfTest(t *testing.T) {
+	var out bytes.Buffer
+	noop := func(context.Context, *Command, []string, ...any) error { return nil }
+	root := &Command{
+		Name:   "prog",
+		Stdout: &out,
+		SubCommands: []*Command{
+			{Name: "get", Arity: "1", Func: noop, Examples: []string{"prog get x"}},
+			{Name: "remote", DefaultSubCommand: "list", SubCommands: []*Command{
+				{Name: "list", Func: noop},
+			}},
+			{Name: "topic", Description: "a help topic"},
+			HelpCmd,
+			SelfTestCmd,
+		},
+	}
+	ctx := context.Background()
+	if err := root.Run(ctx, []string{"self-test"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "ok, checked 7 commands\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	out.Reset()
+	root.SubCommands = append(root.SubCommands,
+		&Command{Name: "old", Func: noop, Examples: []string{"prog old x y"}},
+		&Command{Name: "new", Func: noop},
+	)
+	root.NormalizeArgs = func(args []string) []string {
+		if len(args) > 0 && args[0] == "old" {
+			args = append([]string{"new"}, args[1:]...)
+		}
+		return args
+	}
+	err := root.Run(ctx, []string{"self-test"})
+	if ExitCode(err) != 1 || err.Error() != "2 problems found" {
+		t.Errorf("got error %v, want 2 problems found", err)
+	}
+	want := `prog old: resolves to prog new
+prog old: example "prog old x y": runs prog new
+`
+	if got := out.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,404 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pborman/flags"
+)
+
+// A Sink writes a stream of records, such as the results of a bulk
+// operation, in some format.  A record is normally a structure, or a pointer
+// to one, whose exported fields are the fields of the record.  The name of a
+// field is taken from its json tag, if any.  A map with string keys is a
+// record whose fields are the sorted keys.  Any other value is a record with
+// a single field named value.  Flush must be called after the last record is
+// written.
+type Sink interface {
+	Write(record any) error
+	Flush() error
+}
+
+// standardSinks are the formats accepted by --output unless replaced by the
+// root command's Sinks.
+var standardSinks = map[string]func(w io.Writer) Sink{
+	"csv":   NewCSVSink,
+	"jsonl": NewJSONLSink,
+	"table": NewTableSink,
+}
+
+// NewSink returns a Sink that writes to c's Stdout in the format selected by
+// the standard --output flag, see the OutputFlag field of Command.  The
+// default format is table.  The standard --columns flag, or its synonym
+// --fields, selects the fields of each record that are written, and their
+// order.  A field of a field is selected with a dotted path, such as
+// "owner.name".  The standard --no-header flag omits the header written by
+// the csv and table formats.  The header of a table is emphasized when
+// color is enabled, see ColorFor.  The standard --query flag, which is only
+// accepted if the root command has a Querier, replaces each record with the
+// result of the query before the fields are selected.
+func (c *Command) NewSink() Sink {
+	r := c.Root()
+	format := r.output
+	if format == "" {
+		format = "table"
+	}
+	sink := r.sinks()[format](c.stdout())
+	if hs, ok := sink.(interface{ omitHeader() }); ok && r.noHeader {
+		hs.omitHeader()
+	}
+	if hs, ok := sink.(interface{ styleHeader(Styler) }); ok {
+		hs.styleHeader(c.ColorFor(c.stdout()))
+	}
+	if len(r.columns) > 0 {
+		sink = &columnSink{Sink: sink, columns: r.columns}
+	}
+	if r.query != "" {
+		sink = &querySink{Sink: sink, q: r.Querier, expr: r.query, c: c}
+	}
+	return sink
+}
+
+// sinks returns the output formats c accepts.
+func (c *Command) sinks() map[string]func(io.Writer) Sink {
+	sinks := map[string]func(io.Writer) Sink{}
+	for name, fn := range standardSinks {
+		sinks[name] = fn
+	}
+	for name, fn := range c.Sinks {
+		sinks[name] = fn
+	}
+	return sinks
+}
+
+// sinkNames returns the sorted names of the output formats c accepts.
+func (c *Command) sinkNames() []string {
+	var names []string
+	for name := range c.sinks() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// outputFlagHelp returns the help for the standard --output, --columns,
+// --no-header, and --query flags c accepts.
+func (c *Command) outputFlagHelp() []standardFlag {
+	var sfs []standardFlag
+	if c.acceptsOutput("output") {
+		sfs = append(sfs, standardFlag{"--output=FORMAT", c.sprintf("write records as FORMAT {%s}", strings.Join(c.sinkNames(), ", "))})
+	}
+	if c.acceptsOutput("columns") && c.acceptsOutput("fields") {
+		sfs = append(sfs, standardFlag{"--columns, --fields=NAMES", "only write the comma separated fields NAMES"})
+	}
+	if c.acceptsOutput("no-header") {
+		sfs = append(sfs, standardFlag{"--no-header", "do not write a header"})
+	}
+	if c.acceptsQuery() {
+		sfs = append(sfs, standardFlag{"--query=EXPR", "write the result of EXPR applied to each record"})
+	}
+	return sfs
+}
+
+// acceptsOutput returns true if c accepts the standard output flag named
+// name.  Only a root command with OutputFlag set accepts them, and only if
+// it does not declare its own flag with the same name.
+func (c *Command) acceptsOutput(name string) bool {
+	if c.parent != nil || !c.OutputFlag {
+		return false
+	}
+	return lookupFlagField(c.getFlags(), name) == nil
+}
+
+// acceptsQuery returns true if c accepts the standard --query flag.
+func (c *Command) acceptsQuery() bool {
+	return c.Querier != nil && c.acceptsOutput("query")
+}
+
+// addOutputFlag adds the standard output flags c accepts to set.  The
+// returned function, which is nil if no flag was added, must be called after
+// set is parsed.
+func (c *Command) addOutputFlag(set flags.FlagSet) func() error {
+	if c.parent != nil || !c.OutputFlag {
+		return nil
+	}
+	var output, columns, query string
+	var noHeader bool
+	if c.acceptsOutput("output") {
+		set.StringVar(&output, "output", "", "write records as FORMAT")
+	}
+	if c.acceptsOutput("columns") && c.acceptsOutput("fields") {
+		set.StringVar(&columns, "columns", "", "only write the comma separated fields NAMES")
+		set.StringVar(&columns, "fields", "", "only write the comma separated fields NAMES")
+	}
+	if c.acceptsOutput("no-header") {
+		set.BoolVar(&noHeader, "no-header", false, "do not write a header")
+	}
+	if c.acceptsQuery() {
+		set.StringVar(&query, "query", "", "write the result of EXPR applied to each record")
+	}
+	return func() error {
+		if _, ok := c.sinks()[output]; output != "" && !ok {
+			return &UsageError{C: c, Err: c.errorf("unknown output format %q {%s}", output, strings.Join(c.sinkNames(), ", "))}
+		}
+		c.output, c.noHeader, c.query, c.columns = output, noHeader, query, nil
+		if columns != "" {
+			c.columns = strings.Split(columns, ",")
+		}
+		return nil
+	}
+}
+
+// recordFields returns the names and values of the fields of record as
+// described by Sink.
+func recordFields(record any) (names []string, values []any) {
+	v := reflect.ValueOf(record)
+	if !v.IsValid() {
+		return []string{"value"}, []any{nil}
+	}
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	switch {
+	case v.Type() == reflect.TypeOf(projectedRecord{}):
+		pr := v.Interface().(projectedRecord)
+		return pr.names, pr.values
+	case v.Kind() == reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			names = append(names, name)
+			values = append(values, v.Field(i).Interface())
+		}
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		for _, k := range v.MapKeys() {
+			names = append(names, k.String())
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			values = append(values, v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key())).Interface())
+		}
+	default:
+		names, values = []string{"value"}, []any{record}
+	}
+	return names, values
+}
+
+// stringValues returns the values formatted with fmt.Sprint.  A nil value
+// is an empty string.
+func stringValues(values []any) []string {
+	s := make([]string, len(values))
+	for i, v := range values {
+		if v != nil {
+			s[i] = fmt.Sprint(v)
+		}
+	}
+	return s
+}
+
+// A projectedRecord is a record with only the selected fields of another
+// record, in the selected order.
+type projectedRecord struct {
+	names  []string
+	values []any
+}
+
+// project returns the fields of record named by columns.  A column may be a
+// dotted path, such as "owner.name", to select a field of a field.  A field
+// that record does not have is nil.
+func project(record any, columns []string) projectedRecord {
+	pr := projectedRecord{names: columns, values: make([]any, len(columns))}
+	for i, col := range columns {
+		pr.values[i] = fieldValue(record, col)
+	}
+	return pr
+}
+
+// fieldValue returns the value of the field of record named by the dotted
+// path, or nil.
+func fieldValue(record any, path string) any {
+	name, rest, dotted := strings.Cut(path, ".")
+	names, values := recordFields(record)
+	for i, n := range names {
+		if n != name {
+			continue
+		}
+		if !dotted {
+			return values[i]
+		}
+		if values[i] == nil {
+			return nil
+		}
+		return fieldValue(values[i], rest)
+	}
+	return nil
+}
+
+// MarshalJSON returns r as a JSON object with the fields in order.
+func (r projectedRecord) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, name := range r.names {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(r.values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// A columnSink writes the projection of each record to another Sink.
+type columnSink struct {
+	Sink
+	columns []string
+}
+
+func (s *columnSink) Write(record any) error {
+	return s.Sink.Write(project(record, s.columns))
+}
+
+type jsonlSink struct {
+	enc *json.Encoder
+}
+
+// NewJSONLSink returns a Sink that writes each record to w as a line of
+// JSON.
+func NewJSONLSink(w io.Writer) Sink {
+	return &jsonlSink{enc: json.NewEncoder(w)}
+}
+
+func (s *jsonlSink) Write(record any) error { return s.enc.Encode(record) }
+func (s *jsonlSink) Flush() error           { return nil }
+
+type csvSink struct {
+	w      *csv.Writer
+	header bool // the header has been written, or is omitted
+}
+
+// NewCSVSink returns a Sink that writes records to w as CSV.  The first line
+// is a header with the names of the fields of the first record.
+func NewCSVSink(w io.Writer) Sink {
+	return &csvSink{w: csv.NewWriter(w)}
+}
+
+func (s *csvSink) Write(record any) error {
+	names, values := recordFields(record)
+	if !s.header {
+		s.header = true
+		if err := s.w.Write(names); err != nil {
+			return err
+		}
+	}
+	return s.w.Write(stringValues(values))
+}
+
+func (s *csvSink) omitHeader() { s.header = true }
+
+func (s *csvSink) Flush() error {
+	s.w.Flush()
+	return s.w.Error()
+}
+
+type tableSink struct {
+	w      *tabwriter.Writer
+	out    *headerWriter
+	header bool // the header has been written, or is omitted
+}
+
+// NewTableSink returns a Sink that writes records to w as a table with a
+// column for each field.  The first line is a header with the names of the
+// fields of the first record in upper case.  As the width of the columns
+// depends on all the records nothing is written until Flush is called.
+func NewTableSink(w io.Writer) Sink {
+	out := &headerWriter{w: w}
+	return &tableSink{w: tabwriter.NewWriter(out, 0, 4, 2, ' ', 0), out: out}
+}
+
+// styleHeader causes the header to be emphasized by st.
+func (s *tableSink) styleHeader(st Styler) { s.out.style = st.Emph }
+
+// A headerWriter writes to w, styling the text of the first line with
+// style, if set.  The header of a table is styled after the table is laid
+// out so the escape sequences do not affect the width of the columns.
+type headerWriter struct {
+	w     io.Writer
+	style func(string) string
+	line  []byte // the first line so far
+	done  bool   // the first line has been written
+}
+
+func (h *headerWriter) Write(p []byte) (int, error) {
+	if h.done || h.style == nil {
+		return h.w.Write(p)
+	}
+	i := bytes.IndexByte(p, '\n')
+	if i < 0 {
+		h.line = append(h.line, p...)
+		return len(p), nil
+	}
+	h.done = true
+	line := string(append(h.line, p[:i]...))
+	if _, err := io.WriteString(h.w, h.style(line)); err != nil {
+		return 0, err
+	}
+	if _, err := h.w.Write(p[i:]); err != nil {
+		return i, err
+	}
+	return len(p), nil
+}
+
+func (s *tableSink) Write(record any) error {
+	names, values := recordFields(record)
+	if !s.header {
+		s.header = true
+		if _, err := fmt.Fprintln(s.w, strings.ToUpper(strings.Join(names, "\t"))); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(s.w, strings.Join(stringValues(values), "\t"))
+	return err
+}
+
+func (s *tableSink) omitHeader()  { s.header = true }
+func (s *tableSink) Flush() error { return s.w.Flush() }
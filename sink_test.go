@@ -0,0 +1,150 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSinks(t *testing.T) {
+	type host struct {
+		Name    string `json:"name"`
+		Up      bool   `json:"up"`
+		private int
+	}
+	records := []any{
+		host{Name: "alpha", Up: true},
+		&host{Name: "beta, gamma"},
+	}
+	var out bytes.Buffer
+	root := &Command{
+		Name:       "prog",
+		Stdout:     &out,
+		Stderr:     io.Discard,
+		OutputFlag: true,
+		Sinks: map[string]func(io.Writer) Sink{
+			"names": func(w io.Writer) Sink { return NewJSONLSink(w) },
+		},
+		Func: func(_ context.Context, c *Command, _ []string, _ ...any) error {
+			sink := c.NewSink()
+			for _, r := range records {
+				if err := sink.Write(r); err != nil {
+					return err
+				}
+			}
+			return sink.Flush()
+		},
+	}
+	ctx := context.Background()
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{nil, "NAME         UP\nalpha        true\nbeta, gamma  false\n"},
+		{[]string{"--output=csv"}, "name,up\nalpha,true\n\"beta, gamma\",false\n"},
+		{[]string{"--output=jsonl"}, `{"name":"alpha","up":true}` + "\n" + `{"name":"beta, gamma","up":false}` + "\n"},
+		{[]string{"--output=names"}, `{"name":"alpha","up":true}` + "\n" + `{"name":"beta, gamma","up":false}` + "\n"},
+	} {
+		out.Reset()
+		if err := root.Run(ctx, tt.args); err != nil {
+			t.Errorf("%q: %v", tt.args, err)
+			continue
+		}
+		if got := out.String(); got != tt.want {
+			t.Errorf("%q: got:\n%s\nwant:\n%s", tt.args, got, tt.want)
+		}
+	}
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--columns=up,name"}, "UP     NAME\ntrue   alpha\nfalse  beta, gamma\n"},
+		{[]string{"--no-header", "--output=csv"}, "alpha,true\n\"beta, gamma\",false\n"},
+		{[]string{"--output=csv", "--columns=up,x"}, "up,x\ntrue,\nfalse,\n"},
+		{[]string{"--output=jsonl", "--columns=up,name"}, `{"up":true,"name":"alpha"}` + "\n" + `{"up":false,"name":"beta, gamma"}` + "\n"},
+	} {
+		out.Reset()
+		if err := root.Run(ctx, tt.args); err != nil {
+			t.Errorf("%q: %v", tt.args, err)
+			continue
+		}
+		if got := out.String(); got != tt.want {
+			t.Errorf("%q: got:\n%s\nwant:\n%s", tt.args, got, tt.want)
+		}
+	}
+	err := root.Run(ctx, []string{"--output=xml"})
+	if want := `prog: unknown output format "xml" {csv, jsonl, names, table}`; err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %s", err, want)
+	}
+
+	records = []any{map[string]int{"b": 2, "a": 1}, 42}
+	out.Reset()
+	if err := root.Run(ctx, []string{"--output=csv"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "a,b\n1,2\n42\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	help, _ := root.HelpText()
+	for _, want := range []string{
+		"--output=FORMAT              write records as FORMAT {csv, jsonl, names, table}",
+		"--columns, --fields=NAMES    only write the comma separated fields NAMES",
+		"--no-header                  do not write a header",
+	} {
+		if !strings.Contains(help, want) {
+			t.Errorf("help does not contain %q:\n%s", want, help)
+		}
+	}
+}
+
+func TestSinkFields(t *testing.T) {
+	type owner struct {
+		Name string `json:"name"`
+		Team string `json:"team"`
+	}
+	type repo struct {
+		Name  string         `json:"name"`
+		Owner *owner         `json:"owner"`
+		Meta  map[string]any `json:"meta"`
+	}
+	records := []any{
+		repo{Name: "a", Owner: &owner{Name: "bob", Team: "x"}, Meta: map[string]any{"stars": 3}},
+		repo{Name: "b"},
+	}
+	var out bytes.Buffer
+	root := &Command{
+		Name:       "prog",
+		Stdout:     &out,
+		OutputFlag: true,
+		Func: func(_ context.Context, c *Command, _ []string, _ ...any) error {
+			sink := c.NewSink()
+			for _, r := range records {
+				if err := sink.Write(r); err != nil {
+					return err
+				}
+			}
+			return sink.Flush()
+		},
+	}
+	if err := root.Run(context.Background(), []string{"--output=csv", "--fields=name,owner.name,meta.stars,owner.x"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "name,owner.name,meta.stars,owner.x\na,bob,3,\nb,,,\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
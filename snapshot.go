@@ -0,0 +1,99 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"reflect"
+)
+
+// A FlagState is a snapshot of the flag values of a tree of commands as
+// returned by SnapshotFlags.
+type FlagState struct {
+	commands map[*Command]savedFlags
+}
+
+// savedFlags are copies of a command's Defaults and Flags.
+type savedFlags struct {
+	defaults any
+	flags    any
+}
+
+// SnapshotFlags returns the current values of the Defaults and Flags of c
+// and all its sub commands.  The values can later be restored with
+// RestoreFlags, e.g., to let a REPL temporarily change options or to isolate
+// tests from each other.  The copy is shallow, the contents of slices and
+// maps held by flags are not copied.
+func (c *Command) SnapshotFlags() FlagState {
+	fs := FlagState{commands: map[*Command]savedFlags{}}
+	var walk func(c *Command)
+	walk = func(c *Command) {
+		var sf savedFlags
+		if c.Defaults != nil {
+			sf.defaults = dupFlags(c.Defaults)
+		}
+		if c.Flags != nil {
+			sf.flags = dupFlags(c.Flags)
+		}
+		fs.commands[c] = sf
+		for _, sc := range c.SubCommands {
+			walk(sc)
+		}
+	}
+	walk(c)
+	return fs
+}
+
+// RestoreFlags sets the Defaults and Flags of the commands in fs to the
+// values they had when fs was returned by SnapshotFlags.  The values are
+// copied into the existing structures so pointers to them, or their fields,
+// remain valid.  A Flags that was nil in the snapshot is set back to nil.
+// Commands added to the tree after the snapshot was taken are not changed.
+func (c *Command) RestoreFlags(fs FlagState) {
+	var walk func(c *Command)
+	walk = func(c *Command) {
+		if sf, ok := fs.commands[c]; ok {
+			if sf.defaults != nil && c.Defaults != nil {
+				copyFlags(c.Defaults, sf.defaults)
+			}
+			switch {
+			case sf.flags == nil:
+				c.Flags = nil
+			case c.Flags == nil:
+				c.Flags = dupFlags(sf.flags)
+			default:
+				copyFlags(c.Flags, sf.flags)
+			}
+		}
+		for _, sc := range c.SubCommands {
+			walk(sc)
+		}
+	}
+	walk(c)
+}
+
+// copyFlags copies the flag fields of the structure src points to into the
+// structure dst points to.  Nothing is copied if they are not the same type.
+func copyFlags(dst, src any) {
+	dv, fields, err := flagFields(dst)
+	if err != nil {
+		return
+	}
+	sv := reflect.ValueOf(src).Elem()
+	if sv.Type() != dv.Type() {
+		return
+	}
+	for _, f := range fields {
+		dv.Field(f.index).Set(sv.Field(f.index))
+	}
+}
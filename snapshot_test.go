@@ -0,0 +1,58 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSnapshotFlags(t *testing.T) {
+	type rootFlags struct {
+		Verbose bool `flag:"-v be verbose"`
+	}
+	type subFlags struct {
+		N int `flag:"-n=N count"`
+	}
+	rf := &rootFlags{}
+	sf := &subFlags{N: 1}
+	sub := &Command{
+		Name:  "sub",
+		Flags: sf,
+		Func:  func(context.Context, *Command, []string, ...any) error { return nil },
+	}
+	root := &Command{
+		Name:        "prog",
+		Defaults:    rf,
+		SubCommands: []*Command{sub},
+	}
+	state := root.SnapshotFlags()
+	if err := root.Run(context.Background(), []string{"-v", "sub", "-n", "5"}); err != nil {
+		t.Fatal(err)
+	}
+	rf.Verbose = true
+	if !root.Flags.(*rootFlags).Verbose || sf.N != 5 {
+		t.Fatalf("flags not set by Run")
+	}
+	root.RestoreFlags(state)
+	if root.Flags != nil {
+		t.Errorf("Flags of prog not reset to nil")
+	}
+	if rf.Verbose {
+		t.Errorf("Defaults of prog not restored")
+	}
+	if sub.Flags != sf || sf.N != 1 {
+		t.Errorf("got Flags %p with N %d, want %p with N 1", sub.Flags, sf.N, sf)
+	}
+}
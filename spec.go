@@ -0,0 +1,329 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// A CommandFunc is the function run by a command, see the Func field of
+// Command.
+type CommandFunc func(ctx context.Context, c *Command, args []string, extra ...any) error
+
+// A CommandSpec describes a command, and its sub commands, as data.  See
+// LoadSpec.  Params is set by Spec, from Parameters or the arity of the
+// command, for tools that need the structure of the positional parameters,
+// such as form generators.  It is ignored by LoadSpec.
+type CommandSpec struct {
+	Name        string         `json:"name"`
+	Aliases     []string       `json:"aliases,omitempty"`
+	Help        string         `json:"help,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Parameters  string         `json:"parameters,omitempty"`
+	Params      []Param        `json:"params,omitempty"`
+	Arity       string         `json:"arity,omitempty"`
+	MinArgs     int            `json:"min_args,omitempty"`
+	MaxArgs     int            `json:"max_args,omitempty"`
+	Category    string         `json:"category,omitempty"`
+	Hidden      bool           `json:"hidden,omitempty"`
+	Examples    []string       `json:"examples,omitempty"`
+	Func        string         `json:"func,omitempty"`
+	Flags       []FlagSpec     `json:"flags,omitempty"`
+	SubCommands []*CommandSpec `json:"subcommands,omitempty"`
+}
+
+// A FlagSpec describes a flag of a CommandSpec.  Type is one of bool,
+// duration, float, int, int64, string, strings (a []string), uint, or
+// uint64.  The default type is string.  Default, if set, is the JSON encoded
+// default value of the flag.  A duration is written as a string, such as
+// "1m30s".
+type FlagSpec struct {
+	Name    string          `json:"name"`
+	Param   string          `json:"param,omitempty"`
+	Help    string          `json:"help,omitempty"`
+	Type    string          `json:"type,omitempty"`
+	Default json.RawMessage `json:"default,omitempty"`
+	Env     string          `json:"env,omitempty"`
+}
+
+// flagTypes maps the Type of a FlagSpec to the type of the flag.
+var flagTypes = map[string]reflect.Type{
+	"bool":     reflect.TypeOf(false),
+	"duration": reflect.TypeOf(time.Duration(0)),
+	"float":    reflect.TypeOf(float64(0)),
+	"int":      reflect.TypeOf(0),
+	"int64":    reflect.TypeOf(int64(0)),
+	"string":   reflect.TypeOf(""),
+	"strings":  reflect.TypeOf([]string(nil)),
+	"uint":     reflect.TypeOf(uint(0)),
+	"uint64":   reflect.TypeOf(uint64(0)),
+}
+
+// LoadSpec returns the command tree described by the JSON encoded
+// CommandSpec read from r.  The Func of each command is the function in funcs
+// named by the Func of its spec.  A command that declares flags is given a
+// flags structure, as its Defaults, that declares them.  This allows the
+// commands and flags of a program to be reviewed as data:
+//
+//	{
+//		"name": "prog",
+//		"subcommands": [{
+//			"name": "greet",
+//			"help": "greet someone",
+//			"parameters": "NAME",
+//			"arity": "1",
+//			"func": "greet",
+//			"flags": [{"name": "loud", "type": "bool", "help": "shout"}]
+//		}]
+//	}
+//
+// A Func reads the value of a flag with Lookup:
+//
+//	func greet(ctx context.Context, c *commander.Command, args []string, _ ...any) error {
+//		if c.Lookup("", "loud").(bool) { ... }
+//	}
+//
+// An error is returned if the spec cannot be decoded, has unknown fields,
+// names a function not in funcs, or declares an invalid flag.  The returned
+// tree has not been validated, see Validate.
+func LoadSpec(r io.Reader, funcs map[string]CommandFunc) (*Command, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	var spec CommandSpec
+	if err := dec.Decode(&spec); err != nil {
+		return nil, err
+	}
+	return spec.command(funcs, "")
+}
+
+// command returns the command described by s.  prefix is the path of
+// the parent of the command, used in error messages.
+func (s *CommandSpec) command(funcs map[string]CommandFunc, prefix string) (*Command, error) {
+	if s.Name == "" {
+		if prefix == "" {
+			return nil, errors.New("command missing name")
+		}
+		return nil, fmt.Errorf("%s: sub command missing name", prefix)
+	}
+	path := strings.TrimSpace(prefix + " " + s.Name)
+	c := &Command{
+		Name:        s.Name,
+		Aliases:     s.Aliases,
+		Help:        s.Help,
+		Description: s.Description,
+		Parameters:  s.Parameters,
+		Arity:       s.Arity,
+		MinArgs:     s.MinArgs,
+		MaxArgs:     s.MaxArgs,
+		Category:    s.Category,
+		Hidden:      s.Hidden,
+		Examples:    s.Examples,
+	}
+	if s.Func != "" {
+		fn, ok := funcs[s.Func]
+		if !ok {
+			return nil, fmt.Errorf("%s: unknown func %q", path, s.Func)
+		}
+		c.Func = fn
+	}
+	if len(s.Flags) > 0 {
+		defaults, err := specFlags(s.Flags)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		c.Defaults = defaults
+	}
+	for _, ss := range s.SubCommands {
+		sc, err := ss.command(funcs, path)
+		if err != nil {
+			return nil, err
+		}
+		c.SubCommands = append(c.SubCommands, sc)
+	}
+	return c, nil
+}
+
+// specFlags returns a pointer to a new flags structure that declares fs,
+// initialized to their default values.
+func specFlags(fs []FlagSpec) (any, error) {
+	fields := make([]reflect.StructField, len(fs))
+	for i, f := range fs {
+		if f.Name == "" {
+			return nil, errors.New("flag missing name")
+		}
+		typ := f.Type
+		if typ == "" {
+			typ = "string"
+		}
+		t, ok := flagTypes[typ]
+		if !ok {
+			return nil, fmt.Errorf("flag %s: unknown type %q", f.Name, f.Type)
+		}
+		tag := "--" + f.Name
+		if f.Param != "" {
+			tag += "=" + f.Param
+		}
+		if f.Help != "" {
+			tag += " " + f.Help
+		}
+		st := fmt.Sprintf("flag:%q", tag)
+		if f.Env != "" {
+			st += fmt.Sprintf(" env:%q", f.Env)
+		}
+		fields[i] = reflect.StructField{
+			Name: fmt.Sprintf("F%d", i),
+			Type: t,
+			Tag:  reflect.StructTag(st),
+		}
+	}
+	v := reflect.New(reflect.StructOf(fields))
+	for i, f := range fs {
+		if len(f.Default) == 0 {
+			continue
+		}
+		if err := specDefault(v.Elem().Field(i), f.Default); err != nil {
+			return nil, fmt.Errorf("flag %s: invalid default: %w", f.Name, err)
+		}
+	}
+	if _, _, err := flagFields(v.Interface()); err != nil {
+		return nil, err
+	}
+	return v.Interface(), nil
+}
+
+// specDefault sets v to the default value encoded in data.
+func specDefault(v reflect.Value, data json.RawMessage) error {
+	if v.Type() != reflect.TypeOf(time.Duration(0)) {
+		return json.Unmarshal(data, v.Addr().Interface())
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	v.SetInt(int64(d))
+	return nil
+}
+
+// Spec returns the JSON encoded CommandSpec describing the tree rooted at c,
+// including hidden commands and help topics.  Func is not set as the names
+// of functions are not known.  The default of a flag is the value of the
+// flag in Defaults, or Flags if there are no Defaults, and is omitted if it
+// is the zero value or the flag is secret.  The type of a flag not supported
+// by LoadSpec is its Go type, such as "net.IP".  The result is stable so it can
+// be compared with the spec of an earlier version, or passed to LoadSpec.
+// Sub commands provided by SubCommandsFunc are loaded.
+func (c *Command) Spec() ([]byte, error) {
+	spec, err := c.spec(map[*Command]bool{})
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(spec, "", "  ")
+}
+
+// spec returns the CommandSpec of c.  onPath holds the commands from the
+// root to c and is used to detect cycles.
+func (c *Command) spec(onPath map[*Command]bool) (*CommandSpec, error) {
+	if onPath[c] {
+		return nil, fmt.Errorf("sub command %s creates a cycle", c.Command())
+	}
+	onPath[c] = true
+	defer delete(onPath, c)
+
+	s := &CommandSpec{
+		Name:        c.Name,
+		Aliases:     c.Aliases,
+		Help:        c.Help,
+		Description: c.description(),
+		Parameters:  c.Parameters,
+		Params:      c.Params(),
+		Arity:       c.Arity,
+		MinArgs:     c.MinArgs,
+		MaxArgs:     c.MaxArgs,
+		Category:    c.Category,
+		Hidden:      c.Hidden,
+		Examples:    c.Examples,
+	}
+	flags, err := flagSpecs(c.Defaults, c.Flags)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", c.Command(), err)
+	}
+	s.Flags = flags
+	c.discover(context.Background())
+	for _, sc := range c.SubCommands {
+		sc.parent = c
+		ss, err := sc.spec(onPath)
+		if err != nil {
+			return nil, err
+		}
+		s.SubCommands = append(s.SubCommands, ss)
+	}
+	return s, nil
+}
+
+// flagSpecs returns the FlagSpecs of the flags declared by defaults, or by
+// flags if defaults is nil.
+func flagSpecs(defaults, flags any) ([]FlagSpec, error) {
+	if defaults == nil {
+		defaults = flags
+	}
+	if defaults == nil {
+		return nil, nil
+	}
+	v, fields, err := flagFields(defaults)
+	if err != nil {
+		return nil, err
+	}
+	var fs []FlagSpec
+	for _, f := range fields {
+		typ := f.typ.String() // not known to LoadSpec
+		for name, t := range flagTypes {
+			if t == f.typ {
+				typ = name
+				break
+			}
+		}
+		if typ == "string" {
+			typ = ""
+		}
+		spec := FlagSpec{
+			Name:  f.name,
+			Param: f.param,
+			Help:  f.help,
+			Type:  typ,
+			Env:   f.env,
+		}
+		if fv := v.Field(f.index); !fv.IsZero() && !f.secret {
+			var dv any = fv.Interface()
+			if d, ok := dv.(time.Duration); ok {
+				dv = d.String()
+			}
+			if spec.Default, err = json.Marshal(dv); err != nil {
+				return nil, fmt.Errorf("flag %s: %w", f.name, err)
+			}
+		}
+		fs = append(fs, spec)
+	}
+	return fs, nil
+}
@@ -0,0 +1,221 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadSpec(t *testing.T) {
+	const spec = `{
+	"name": "prog",
+	"subcommands": [{
+		"name": "greet",
+		"help": "greet someone",
+		"parameters": "NAME",
+		"arity": "1",
+		"func": "greet",
+		"flags": [
+			{"name": "loud", "type": "bool", "help": "shout"},
+			{"name": "greeting", "param": "WORD", "default": "hello"},
+			{"name": "wait", "type": "duration", "default": "1m30s"}
+		]
+	}, {
+		"name": "admin",
+		"category": "Admin Commands",
+		"subcommands": [{"name": "reset", "func": "greet", "aliases": ["zap"]}]
+	}]
+}`
+	var got string
+	funcs := map[string]CommandFunc{
+		"greet": func(_ context.Context, c *Command, args []string, _ ...any) error {
+			if c.Name == "reset" {
+				got = "reset"
+				return nil
+			}
+			got = fmt.Sprintf("%v %v %v %v", c.Lookup("", "greeting"), args, c.Lookup("", "loud"), c.Lookup("", "wait"))
+			return nil
+		},
+	}
+	root, err := LoadSpec(strings.NewReader(spec), funcs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := root.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := root.Run(ctx, []string{"greet", "--loud", "bob"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := fmt.Sprintf("hello [bob] true %v", 90*time.Second); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if err := root.Run(ctx, []string{"greet", "--greeting=hi", "--wait=1s", "bob"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "hi [bob] false 1s"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if err := root.Run(ctx, []string{"greet"}); err == nil {
+		t.Errorf("greet without a NAME did not fail")
+	}
+	if err := root.Run(ctx, []string{"admin", "zap"}); err != nil || got != "reset" {
+		t.Errorf("admin zap: got %q, %v", got, err)
+	}
+	help, err := root.HelpText("greet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"--loud", "--greeting=WORD", "shout"} {
+		if !strings.Contains(help, want) {
+			t.Errorf("help missing %q:\n%s", want, help)
+		}
+	}
+}
+
+func TestLoadSpecErrors(t *testing.T) {
+	funcs := map[string]CommandFunc{
+		"run": func(context.Context, *Command, []string, ...any) error { return nil },
+	}
+	for _, tt := range []struct {
+		spec string
+		err  string
+	}{
+		{`{"help": "x"}`, "command missing name"},
+		{`{"name": "prog", "nmae": "x"}`, `json: unknown field "nmae"`},
+		{`{"name": "prog", "subcommands": [{"name": "a", "func": "walk"}]}`, `prog a: unknown func "walk"`},
+		{`{"name": "prog", "subcommands": [{"help": "a"}]}`, "prog: sub command missing name"},
+		{`{"name": "prog", "func": "run", "flags": [{"name": "n", "type": "complex"}]}`, `prog: flag n: unknown type "complex"`},
+		{`{"name": "prog", "func": "run", "flags": [{"name": "n", "type": "int", "default": "x"}]}`, "prog: flag n: invalid default: json: cannot unmarshal string into Go value of type int"},
+		{`{"name": "prog", "func": "run", "flags": [{"name": "d", "type": "duration", "default": "soon"}]}`, `prog: flag d: invalid default: time: invalid duration "soon"`},
+	} {
+		_, err := LoadSpec(strings.NewReader(tt.spec), funcs)
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("%s: got error %v, want %s", tt.spec, err, tt.err)
+		}
+	}
+}
+
+func TestSpec(t *testing.T) {
+	noop := func(context.Context, *Command, []string, ...any) error { return nil }
+	root := &Command{
+		Name: "prog",
+		SubCommands: []*Command{
+			{
+				Name:        "deploy",
+				Aliases:     []string{"d"},
+				Help:        "deploy a release",
+				Description: "\n  Deploy the named release.\n",
+				Parameters:  "RELEASE",
+				Arity:       "1",
+				Examples:    []string{"prog deploy v1"},
+				Defaults: &struct {
+					Region  string        `flag:"--region=NAME deploy to NAME" env:"REGION"`
+					Timeout time.Duration `flag:"--timeout wait this long"`
+					Force   bool          `flag:"--force skip checks"`
+					Token   string        `flag:"--token=T api token" env:"TOKEN,secret"`
+				}{Region: "us", Timeout: time.Minute, Token: "xyzzy"},
+				Func: noop,
+			},
+			{Name: "debug", Parameters: "[ARG ...]", Hidden: true, Category: "Debug", Func: noop},
+		},
+	}
+	data, err := root.Spec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+  "name": "prog",
+  "subcommands": [
+    {
+      "name": "deploy",
+      "aliases": [
+        "d"
+      ],
+      "help": "deploy a release",
+      "description": "Deploy the named release.",
+      "parameters": "RELEASE",
+      "params": [
+        {
+          "name": "RELEASE"
+        }
+      ],
+      "arity": "1",
+      "examples": [
+        "prog deploy v1"
+      ],
+      "flags": [
+        {
+          "name": "region",
+          "param": "NAME",
+          "help": "deploy to NAME",
+          "default": "us",
+          "env": "REGION"
+        },
+        {
+          "name": "timeout",
+          "help": "wait this long",
+          "type": "duration",
+          "default": "1m0s"
+        },
+        {
+          "name": "force",
+          "help": "skip checks",
+          "type": "bool"
+        },
+        {
+          "name": "token",
+          "param": "T",
+          "help": "api token",
+          "env": "TOKEN"
+        }
+      ]
+    },
+    {
+      "name": "debug",
+      "parameters": "[ARG ...]",
+      "params": [
+        {
+          "name": "ARG",
+          "optional": true,
+          "repeated": true
+        }
+      ],
+      "category": "Debug",
+      "hidden": true
+    }
+  ]
+}`
+	if got := string(data); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	// The spec survives a round trip through LoadSpec.
+	loaded, err := LoadSpec(strings.NewReader(string(data)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := loaded.Spec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != want {
+		t.Errorf("round trip got:\n%s", again)
+	}
+}
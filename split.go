@@ -14,7 +14,10 @@
 package commander
 
 import (
+	"errors"
+	"fmt"
 	"strings"
+	"unicode"
 )
 
 // The following are options to the SplitCommand function.  They determine how
@@ -58,7 +61,11 @@ func SplitCommand(args []string, delim string, options int) [][]string {
 			}
 			if (options & TrailingDelim) != 0 {
 				if strings.HasSuffix(arg, delim) {
-					words = append(words, strings.TrimSuffix(arg, delim), delim)
+					// Don't turn ";;" into an empty word.
+					if arg = strings.TrimSuffix(arg, delim); arg != "" {
+						words = append(words, arg)
+					}
+					words = append(words, delim)
 					continue
 				}
 			}
@@ -82,3 +89,75 @@ func SplitCommand(args []string, delim string, options int) [][]string {
 	}
 	return cmds
 }
+
+// SplitLine splits line into words using shell-like quoting.  Words are
+// separated by white space.  Within single quotes every character is taken
+// literally.  Within double quotes, or outside of quotes, a backslash causes
+// the following character to be taken literally.  Quotes may appear anywhere
+// in a word, e.g., --name="Bob Smith".  An error is returned if line has an
+// unterminated quote or ends with a backslash.
+func SplitLine(line string) ([]string, error) {
+	ws, err := splitLine(line)
+	if err != nil {
+		return nil, err
+	}
+	var words []string
+	for _, w := range ws {
+		words = append(words, w.text)
+	}
+	return words, nil
+}
+
+// A lineWord is a word returned by splitLine.
+type lineWord struct {
+	text   string
+	quoted bool // some part of the word was quoted or escaped
+}
+
+// splitLine implements SplitLine.  It also reports which words contain
+// quoting so callers can tell "<" from a literal '<'.
+func splitLine(line string) ([]lineWord, error) {
+	var words []lineWord
+	var word strings.Builder
+	inWord := false
+	quoted := false
+	var quote rune
+	escaped := false
+	for _, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inWord, quoted = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord, quoted = true, true
+		case unicode.IsSpace(r):
+			if inWord {
+				words = append(words, lineWord{word.String(), quoted})
+				word.Reset()
+				inWord, quoted = false, false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	switch {
+	case escaped:
+		return nil, errors.New("line ends with a backslash")
+	case quote != 0:
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	case inWord:
+		words = append(words, lineWord{word.String(), quoted})
+	}
+	return words, nil
+}
@@ -15,7 +15,10 @@ package commander
 
 import (
 	"fmt"
+	"reflect"
+	"strings"
 	"testing"
+	"unicode/utf8"
 )
 
 func TestSplit(t *testing.T) {
@@ -52,4 +55,101 @@ func TestSplit(t *testing.T) {
 			t.Errorf("%s: got\n%s\nwant:\n%s", tt.name, gots, wants)
 		}
 	}
+	// A doubled delimiter must not produce an empty command name.
+	got := fmt.Sprintf("%q", SplitCommand([]string{";;", "a"}, ";", PreceedingDelim|TrailingDelim))
+	if want := `[["a"]]`; got != want {
+		t.Errorf("doubled delimiter: got %s, want %s", got, want)
+	}
+}
+
+func FuzzSplitCommand(f *testing.F) {
+	f.Add("a ;b c; d;e ;f;g; ; h", ";", TrailingDelim)
+	f.Add(";; a", ";", PreceedingDelim|TrailingDelim)
+	f.Add("a  b", " ", AnyDelim)
+	f.Add("x&&y", "&", StrictDelim)
+	f.Fuzz(func(t *testing.T, line, delim string, options int) {
+		// The concatenation invariant only holds for delimiters that
+		// cannot overlap with themselves.
+		if utf8.RuneCountInString(delim) != 1 {
+			return
+		}
+		options &= TrailingDelim | PreceedingDelim | AnyDelim
+		args := strings.Split(line, "\x00")
+		hasEmpty := false
+		for _, arg := range args {
+			hasEmpty = hasEmpty || arg == ""
+		}
+
+		cmds := SplitCommand(args, delim, options)
+		var words []string
+		for _, cmd := range cmds {
+			if len(cmd) == 0 {
+				t.Fatalf("%q: empty command in %q", args, cmds)
+			}
+			for _, word := range cmd {
+				if word == delim {
+					t.Fatalf("%q: delimiter in command %q", args, cmd)
+				}
+				if word == "" && !hasEmpty {
+					t.Fatalf("%q: empty word in command %q", args, cmd)
+				}
+			}
+			words = append(words, cmd...)
+		}
+		strip := func(s []string) string {
+			return strings.ReplaceAll(strings.Join(s, ""), delim, "")
+		}
+		if got, want := strip(words), strip(args); got != want {
+			t.Fatalf("%q: concatenation is %q, want %q", args, got, want)
+		}
+		switch options {
+		case StrictDelim:
+			var want []string
+			for _, arg := range args {
+				if arg != delim {
+					want = append(want, arg)
+				}
+			}
+			if fmt.Sprintf("%q", words) != fmt.Sprintf("%q", want) {
+				t.Fatalf("%q: strict split got %q, want %q", args, words, want)
+			}
+		case AnyDelim:
+			if got, want := strings.Join(words, ""), strip(args); got != want {
+				t.Fatalf("%q: any split got %q, want %q", args, got, want)
+			}
+		}
+	})
+}
+
+func TestSplitLine(t *testing.T) {
+	for _, tt := range []struct {
+		line  string
+		words []string
+		err   string
+	}{
+		{line: ""},
+		{line: "  a  b\tc ", words: []string{"a", "b", "c"}},
+		{line: `a "b c" d`, words: []string{"a", "b c", "d"}},
+		{line: `--name="Bob Smith"`, words: []string{"--name=Bob Smith"}},
+		{line: `'a\b' "a\"b" a\ b`, words: []string{`a\b`, `a"b`, "a b"}},
+		{line: `a "" b`, words: []string{"a", "", "b"}},
+		{line: `a "b`, err: `unterminated " quote`},
+		{line: `a 'b`, err: `unterminated ' quote`},
+		{line: `a b\`, err: "line ends with a backslash"},
+	} {
+		words, err := SplitLine(tt.line)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("SplitLine(%q) got error %v, want %s", tt.line, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("SplitLine(%q): %v", tt.line, err)
+			continue
+		}
+		if !reflect.DeepEqual(words, tt.words) {
+			t.Errorf("SplitLine(%q) got %q, want %q", tt.line, words, tt.words)
+		}
+	}
 }
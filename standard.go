@@ -0,0 +1,95 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"io"
+
+	"github.com/pborman/flags"
+)
+
+// A standardFlag describes a flag commander adds to the root command, such
+// as --quiet, for help.
+type standardFlag struct {
+	opt  string // the flag as displayed by help, e.g., "-q, --quiet"
+	help string
+}
+
+// standardFlags returns the standard flags c accepts.
+func (c *Command) standardFlags() []standardFlag {
+	sfs := append(c.versionFlagHelp(), c.verbosityFlagHelp()...)
+	sfs = append(sfs, c.nonInteractiveFlagHelp()...)
+	sfs = append(sfs, c.seedFlagHelp()...)
+	sfs = append(sfs, c.outputFlagHelp()...)
+	sfs = append(sfs, c.parallelFlagHelp()...)
+	return append(sfs, c.colorFlagHelp()...)
+}
+
+// addStandardFlags adds the standard flags c accepts to set.  The returned
+// function must be called after set is parsed.
+func (c *Command) addStandardFlags(set flags.FlagSet) func() error {
+	setVersion := c.addVersionFlags(set)
+	setLevel := c.addVerbosityFlags(set)
+	setNonInteractive := c.addNonInteractiveFlag(set)
+	setSeed := c.addSeedFlag(set)
+	setOutput := c.addOutputFlag(set)
+	setParallel := c.addParallelFlag(set)
+	setColor := c.addColorFlags(set)
+	return func() error {
+		if setVersion != nil {
+			setVersion()
+		}
+		if setLevel != nil {
+			setLevel()
+		}
+		if setNonInteractive != nil {
+			setNonInteractive()
+		}
+		if setSeed != nil {
+			if err := setSeed(); err != nil {
+				return err
+			}
+		}
+		if setOutput != nil {
+			if err := setOutput(); err != nil {
+				return err
+			}
+		}
+		if setParallel != nil {
+			if err := setParallel(); err != nil {
+				return err
+			}
+		}
+		if setColor != nil {
+			return setColor()
+		}
+		return nil
+	}
+}
+
+// printStandardFlags writes the help for the standard flags c accepts, if
+// any, to w.
+func (c *Command) printStandardFlags(w io.Writer) {
+	sfs := c.standardFlags()
+	width := 0
+	for _, sf := range sfs {
+		if len(sf.opt) > width {
+			width = len(sf.opt)
+		}
+	}
+	// The layout matches flagHelp as displayed by Help.
+	for _, sf := range sfs {
+		c.fprintf(w, "     %-*s    %s\n", width, sf.opt, c.sprintf(sf.help))
+	}
+}
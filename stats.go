@@ -0,0 +1,186 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// CommandStats are the usage statistics of a single command recorded in the
+// StatsFile of the root command.
+type CommandStats struct {
+	Command  string        // Full name of the command, e.g., "prog sub"
+	Runs     int           // Number of times the command was run
+	Failures int           // Number of runs that returned an error
+	Total    time.Duration // Total time spent running the command
+}
+
+// Average returns the average duration of a run of the command.
+func (s CommandStats) Average() time.Duration {
+	if s.Runs == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Runs)
+}
+
+// UsageStats returns the usage statistics recorded in the StatsFile of the
+// root command of c, most used command first.  No statistics are returned
+// if there is no StatsFile or it does not exist yet.
+func (c *Command) UsageStats() ([]CommandStats, error) {
+	path := c.Root().StatsFile
+	if path == "" {
+		return nil, nil
+	}
+	stats, err := readStats(path)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Runs != stats[j].Runs {
+			return stats[i].Runs > stats[j].Runs
+		}
+		return stats[i].Command < stats[j].Command
+	})
+	return stats, nil
+}
+
+// readStats reads the statistics file path.  Each line of the file has the
+// number of runs, the number of failures, the total duration, and the name
+// of a command, separated by tabs.  Lines starting with # are ignored.  A
+// file that does not exist has no statistics.
+func readStats(path string) ([]CommandStats, error) {
+	fd, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+	var stats []CommandStats
+	s := bufio.NewScanner(fd)
+	for n := 1; s.Scan(); n++ {
+		line := s.Text()
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		fields := strings.SplitN(line, "\t", 4)
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("%s:%d: invalid line", path, n)
+		}
+		var cs CommandStats
+		if cs.Runs, err = strconv.Atoi(fields[0]); err == nil {
+			if cs.Failures, err = strconv.Atoi(fields[1]); err == nil {
+				cs.Total, err = time.ParseDuration(fields[2])
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, n, err)
+		}
+		cs.Command = fields[3]
+		stats = append(stats, cs)
+	}
+	return stats, s.Err()
+}
+
+// writeStats atomically replaces the statistics file path with stats.
+func writeStats(path string, stats []CommandStats) error {
+	var b strings.Builder
+	b.WriteString("# runs\tfailures\ttotal\tcommand\n")
+	for _, cs := range stats {
+		fmt.Fprintf(&b, "%d\t%d\t%v\t%s\n", cs.Runs, cs.Failures, cs.Total, cs.Command)
+	}
+	fd, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(fd.Name())
+	if _, err := fd.WriteString(b.String()); err != nil {
+		fd.Close()
+		return err
+	}
+	if err := fd.Close(); err != nil {
+		return err
+	}
+	return os.Rename(fd.Name(), path)
+}
+
+// recordStats is called by the root command when it finishes to add the run
+// to its StatsFile.  Errors updating the file are ignored.
+func (c *Command) recordStats(start time.Time, err *error) {
+	d := now().Sub(start)
+	name := c.ranCommand(*err).Command()
+	stats, rerr := readStats(c.StatsFile)
+	if rerr != nil {
+		return
+	}
+	i := -1
+	for j, cs := range stats {
+		if cs.Command == name {
+			i = j
+			break
+		}
+	}
+	if i < 0 {
+		i = len(stats)
+		stats = append(stats, CommandStats{Command: name})
+	}
+	stats[i].Runs++
+	stats[i].Total += d
+	if *err != nil {
+		stats[i].Failures++
+	}
+	writeStats(c.StatsFile, stats)
+}
+
+// StatsCmd is a sub command that calls the Stats function.
+var StatsCmd = &Command{
+	Name:  "stats",
+	Help:  "display how often each command has been used",
+	Arity: "0",
+	Func:  Stats,
+}
+
+// Stats implements the stats command.
+//
+//	Usage: stats
+//
+// Stats displays the usage statistics recorded in the StatsFile of the root
+// command: the number of runs and failures of each command along with the
+// average duration of a run.
+func Stats(ctx context.Context, c *Command, args []string, extra ...any) error {
+	if c.Root().StatsFile == "" {
+		return c.errorf("usage statistics are not recorded")
+	}
+	stats, err := c.UsageStats()
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(c.stdout(), 0, 4, 2, ' ', 0)
+	c.fprintf(tw, "COMMAND\tRUNS\tFAILURES\tAVERAGE\n")
+	for _, cs := range stats {
+		c.fprintf(tw, "%s\t%d\t%d\t%v\n", cs.Command, cs.Runs, cs.Failures, cs.Average().Round(time.Millisecond))
+	}
+	return tw.Flush()
+}
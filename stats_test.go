@@ -0,0 +1,78 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	defer func(n func() time.Time) { now = n }(now)
+	start := time.Now()
+	now = func() time.Time { start = start.Add(time.Second); return start }
+
+	var out bytes.Buffer
+	root := &Command{
+		Name:      "prog",
+		Stdout:    &out,
+		StatsFile: filepath.Join(t.TempDir(), "stats"),
+		SubCommands: []*Command{
+			{Name: "ok", Func: func(context.Context, *Command, []string, ...any) error { return nil }},
+			{Name: "fail", Func: func(context.Context, *Command, []string, ...any) error { return errors.New("failed") }},
+			StatsCmd,
+		},
+	}
+	ctx := context.Background()
+	root.Run(ctx, []string{"ok"})
+	root.Run(ctx, []string{"fail"})
+	root.Run(ctx, []string{"ok"})
+
+	stats, err := root.UsageStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []CommandStats{
+		{Command: "prog ok", Runs: 2, Total: 2 * time.Second},
+		{Command: "prog fail", Runs: 1, Failures: 1, Total: time.Second},
+	}
+	if len(stats) != len(want) {
+		t.Fatalf("got %v, want %v", stats, want)
+	}
+	for i, s := range stats {
+		if s != want[i] {
+			t.Errorf("stats %d: got %v, want %v", i, s, want[i])
+		}
+	}
+
+	if err := root.Run(ctx, []string{"stats"}); err != nil {
+		t.Fatal(err)
+	}
+	wantOut := `COMMAND    RUNS  FAILURES  AVERAGE
+prog ok    2     0         1s
+prog fail  1     1         1s
+`
+	if got := out.String(); got != wantOut {
+		t.Errorf("got:\n%s\nwant:\n%s", got, wantOut)
+	}
+
+	root.StatsFile = ""
+	if err := root.Run(ctx, []string{"stats"}); err == nil {
+		t.Errorf("stats without a StatsFile did not fail")
+	}
+}
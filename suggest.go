@@ -0,0 +1,131 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// defaultSuggestionDistance is used when SuggestionsMinimumDistance is 0.
+const defaultSuggestionDistance = 2
+
+// suggestions returns the names of the sub commands of c, other than hidden
+// ones, that name might have been meant to be.  A sub command is suggested if
+// name is within SuggestionsMinimumDistance edits of its name or one of its
+// aliases, or is a prefix of its name.  So short names are not matched by
+// nearly any other short name, the edits may also be no more than half the
+// length of name.  A sub command is always suggested for the names in its
+// SuggestFor.  Matching ignores case.  The closest matches are returned
+// first.
+func (c *Command) suggestions(name string) []string {
+	max := c.Root().SuggestionsMinimumDistance
+	switch {
+	case max < 0:
+		return nil
+	case max == 0:
+		max = defaultSuggestionDistance
+	}
+	name = strings.ToLower(name)
+	type suggestion struct {
+		name string
+		d    int
+	}
+	var found []suggestion
+	for _, sc := range c.SubCommands {
+		if sc.hidden() {
+			continue
+		}
+		best := -1
+		for _, n := range append([]string{sc.Name}, sc.Aliases...) {
+			n = strings.ToLower(n)
+			d := levenshtein(name, n)
+			if (d <= max && d <= utf8.RuneCountInString(name)/2 || strings.HasPrefix(n, name)) && (best < 0 || d < best) {
+				best = d
+			}
+		}
+		for _, n := range sc.SuggestFor {
+			if strings.ToLower(n) == name {
+				best = 0
+			}
+		}
+		if best >= 0 {
+			found = append(found, suggestion{sc.Name, best})
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].d != found[j].d {
+			return found[i].d < found[j].d
+		}
+		return found[i].name < found[j].name
+	})
+	names := make([]string, len(found))
+	for i, s := range found {
+		names[i] = s.name
+	}
+	return names
+}
+
+// unknownCommand returns the error for the unknown sub command name of c,
+// including any suggestions.
+func (c *Command) unknownCommand(name string) error {
+	names := c.suggestions(name)
+	switch len(names) {
+	case 0:
+		return c.errorf("%q: unknown command", name)
+	case 1:
+		return c.errorf("%q: unknown command, did you mean %q?", name, names[0])
+	}
+	q := make([]string, len(names))
+	for i, n := range names {
+		q[i] = strconv.Quote(n)
+	}
+	return c.errorf("%q: unknown command, did you mean one of %s?", name, strings.Join(q, ", "))
+}
+
+// levenshtein returns the edit distance between a and b: the number of
+// single character insertions, deletions, and substitutions needed to turn a
+// into b.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,77 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pborman/check"
+)
+
+func TestLevenshtein(t *testing.T) {
+	for _, tt := range []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"status", "status", 0},
+		{"stauts", "status", 2},
+		{"kitten", "sitting", 3},
+		{"héllo", "hello", 1},
+	} {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) got %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSuggestions(t *testing.T) {
+	noop := func(context.Context, *Command, []string, ...any) error { return nil }
+	root := &Command{
+		Name: "prog",
+		SubCommands: []*Command{
+			{Name: "status", Func: noop},
+			{Name: "stats", Func: noop},
+			{Name: "remove", Aliases: []string{"delete"}, SuggestFor: []string{"rm", "erase"}, Func: noop},
+			{Name: "secret", Hidden: true, Func: noop},
+		},
+	}
+	ctx := context.Background()
+	for _, tt := range []struct {
+		arg string
+		err string
+	}{
+		{"stauts", `prog: "stauts": unknown command, did you mean one of "stats", "status"?`},
+		{"statsu", `prog: "statsu": unknown command, did you mean one of "stats", "status"?`},
+		{"stattus", `prog: "stattus": unknown command, did you mean one of "status", "stats"?`},
+		{"stat", `prog: "stat": unknown command, did you mean one of "stats", "status"?`},
+		{"delte", `prog: "delte": unknown command, did you mean "remove"?`},
+		{"REMOVE", `prog: "REMOVE": unknown command, did you mean "remove"?`},
+		{"secrte", `prog: "secrte": unknown command`},
+		{"xyz", `prog: "xyz": unknown command`},
+		{"rm", `prog: "rm": unknown command, did you mean "remove"?`},
+		{"Erase", `prog: "Erase": unknown command, did you mean "remove"?`},
+	} {
+		if s := check.Error(root.RunSubcommands(ctx, []string{tt.arg}), tt.err); s != "" {
+			t.Errorf("%s: %s", tt.arg, s)
+		}
+	}
+	root.SuggestionsMinimumDistance = -1
+	if s := check.Error(root.RunSubcommands(ctx, []string{"stauts"}), `prog: "stauts": unknown command`); s != "" {
+		t.Error(s)
+	}
+}
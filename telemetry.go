@@ -0,0 +1,124 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// telemetryKey is the configuration key that records consent to telemetry.
+const telemetryKey = "commander.telemetry"
+
+func init() {
+	reservedKeys[telemetryKey] = func(v string) error {
+		if v != "on" && v != "off" {
+			return fmt.Errorf("invalid value %q: must be on or off", v)
+		}
+		return nil
+	}
+}
+
+// A TelemetryReport describes a single run of a command.
+type TelemetryReport struct {
+	Command    string        // Full name of the command, e.g., "prog sub"
+	Duration   time.Duration // How long the command ran
+	ErrorClass string        // "" on success, otherwise the class of the error (see Classify)
+}
+
+// A TelemetrySender sends telemetry reports, e.g., to a collection server.
+type TelemetrySender interface {
+	Send(context.Context, *TelemetryReport) error
+}
+
+// The TelemetrySenderFunc type is an adapter to allow the use of ordinary
+// functions as a TelemetrySender.
+type TelemetrySenderFunc func(context.Context, *TelemetryReport) error
+
+// Send calls f(ctx, r).
+func (f TelemetrySenderFunc) Send(ctx context.Context, r *TelemetryReport) error {
+	return f(ctx, r)
+}
+
+// TelemetryEnabled returns true if the user has consented to telemetry for
+// the tree c is in.  Consent is recorded in the configuration file of the
+// root command, there is no consent without a configuration file.
+func (c *Command) TelemetryEnabled() bool {
+	e, ok := c.Root().config.Lookup(telemetryKey)
+	return ok && e.Value == "on"
+}
+
+// sendTelemetry is called by the root command when it finishes.  Errors
+// sending the report are ignored.
+func (c *Command) sendTelemetry(ctx context.Context, start time.Time, err *error) {
+	if !c.TelemetryEnabled() {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	c.Telemetry.Send(ctx, &TelemetryReport{
+		Command:    c.ranCommand(*err).Command(),
+		Duration:   now().Sub(start),
+		ErrorClass: string(Classify(*err)),
+	})
+}
+
+// ranCommand returns the command the root command c ran: the command whose
+// Func was called, the command with a usage error, or c itself.
+func (c *Command) ranCommand(err error) *Command {
+	if c.ran != nil {
+		return c.ran
+	}
+	var ue *UsageError
+	if errors.As(err, &ue) {
+		return ue.C
+	}
+	return c
+}
+
+// TelemetryCmd is a sub command that lets the user turn telemetry on or off.
+// With no sub command it displays whether telemetry is on or off.
+var TelemetryCmd = &Command{
+	Name: "telemetry",
+	Help: "turn telemetry on or off",
+	Description: `
+Telemetry reports the name of each command run, how long it took,
+and the kind of error, if any.  No arguments or flag values are
+reported.  Telemetry is off unless turned on.
+`,
+	SubCommands: []*Command{
+		{Name: "on", Help: "turn telemetry on", MaxArgs: NoArgs, Func: setTelemetry},
+		{Name: "off", Help: "turn telemetry off", MaxArgs: NoArgs, Func: setTelemetry},
+	},
+	Func: func(ctx context.Context, c *Command, _ []string, _ ...any) error {
+		state := "off"
+		if c.TelemetryEnabled() {
+			state = "on"
+		}
+		c.fprintf(c.stdout(), "telemetry is %s\n", state)
+		return nil
+	},
+}
+
+func setTelemetry(ctx context.Context, c *Command, _ []string, _ ...any) error {
+	cfg, path, err := c.readConfig()
+	if err != nil {
+		return err
+	}
+	cfg.Set(telemetryKey, c.Name)
+	return cfg.WriteFile(path)
+}
@@ -0,0 +1,84 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTelemetry(t *testing.T) {
+	defer func(n func() time.Time) { now = n }(now)
+	start := time.Now()
+	now = func() time.Time { start = start.Add(time.Second); return start }
+
+	var reports []TelemetryReport
+	var out bytes.Buffer
+	root, _ := configTree()
+	root.ConfigFile = filepath.Join(t.TempDir(), "config")
+	root.Stdout = &out
+	root.SubCommands = append(root.SubCommands, TelemetryCmd, &Command{
+		Name: "fail",
+		Func: func(context.Context, *Command, []string, ...any) error {
+			return errors.New("failed")
+		},
+	})
+	root.Telemetry = TelemetrySenderFunc(func(_ context.Context, r *TelemetryReport) error {
+		reports = append(reports, *r)
+		return nil
+	})
+	ctx := context.Background()
+
+	root.Run(ctx, []string{"sub"})
+	root.Run(ctx, []string{"telemetry"})
+	if len(reports) != 0 {
+		t.Errorf("got reports without consent: %v", reports)
+	}
+	if got, want := out.String(), "telemetry is off\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if err := root.Run(ctx, []string{"telemetry", "on"}); err != nil {
+		t.Fatal(err)
+	}
+	reports = nil
+	root.Run(ctx, []string{"sub"})
+	root.Run(ctx, []string{"fail"})
+	root.Run(ctx, []string{"sub", "--bad"})
+	want := []TelemetryReport{
+		{Command: "root sub", Duration: time.Second},
+		{Command: "root fail", Duration: time.Second, ErrorClass: "error"},
+		{Command: "root sub", Duration: time.Second, ErrorClass: "usage"},
+	}
+	if len(reports) != len(want) {
+		t.Fatalf("got reports %v, want %v", reports, want)
+	}
+	for i, r := range reports {
+		if r != want[i] {
+			t.Errorf("report %d: got %v, want %v", i, r, want[i])
+		}
+	}
+	if err := root.Run(ctx, []string{"config", "set", telemetryKey, "maybe"}); err == nil {
+		t.Errorf("invalid telemetry setting did not fail")
+	}
+	root.Run(ctx, []string{"telemetry", "off"})
+	reports = nil
+	root.Run(ctx, []string{"sub"})
+	if len(reports) != 0 {
+		t.Errorf("got reports after turning off: %v", reports)
+	}
+}
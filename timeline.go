@@ -0,0 +1,81 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"text/tabwriter"
+	"time"
+)
+
+// A phase is a step in running a command, such as parsing its flags or
+// calling its Func, and how long it took.
+type phase struct {
+	cmd  *Command
+	name string
+	d    time.Duration
+}
+
+// startPhase starts timing the phase name of c and returns the function that
+// ends it.  The phase is added to the timeline of c's root command.  Phases
+// are only timed while the root command is running.
+func (c *Command) startPhase(name string) func() {
+	if !c.Root().timing {
+		return func() {}
+	}
+	start := now()
+	return func() {
+		r := c.Root()
+		r.timeline = append(r.timeline, phase{cmd: c, name: name, d: now().Sub(start)})
+	}
+}
+
+// startTimeline starts recording the phases of a run of the root command c
+// and returns when the run started.  The level is not known until c's flags
+// have been parsed, so the phases are recorded whenever the level is Debug or
+// may be set to Debug by the standard verbosity flags.
+func (c *Command) startTimeline() time.Time {
+	c.timeline = nil
+	c.timing = c.Level() >= Debug || len(c.verbosityFlagNames()) > 0
+	if !c.timing {
+		return time.Time{}
+	}
+	return now()
+}
+
+// endTimeline stops recording the phases of the run of c that started at
+// start and, if the level is Debug, displays them.
+func (c *Command) endTimeline(start time.Time) {
+	if c.timing && c.Level() >= Debug {
+		c.printTimeline(start)
+	}
+	c.timeline, c.timing = nil, false
+}
+
+// printTimeline displays the phases recorded while running c, and the total
+// time since start, on c's Stderr.  This shows if time is spent in commander
+// itself or in the Func of a command.  Phases are listed in the order they
+// ended, so a command's cleanup follows the phases of its sub command.
+func (c *Command) printTimeline(start time.Time) {
+	total := now().Sub(start)
+	timeline := c.timeline
+	c.timeline = nil
+	w := c.stderr()
+	c.fprintf(w, "timeline:\n")
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, p := range timeline {
+		c.fprintf(tw, "  %s\t%s\t%v\n", p.cmd.Command(), c.sprintf(p.name), p.d)
+	}
+	c.fprintf(tw, "  %s\t%s\t%v\n", c.Command(), c.sprintf("total"), total)
+	tw.Flush()
+}
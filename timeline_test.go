@@ -0,0 +1,94 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTimeline(t *testing.T) {
+	defer func(n func() time.Time) { now = n }(now)
+	start := time.Now()
+	now = func() time.Time { start = start.Add(time.Millisecond); return start }
+
+	var buf bytes.Buffer
+	root := &Command{
+		Name:   "prog",
+		Stderr: &buf,
+		SubCommands: []*Command{{
+			Name: "sub",
+			Func: func(context.Context, *Command, []string, ...any) error {
+				return errors.New("failed")
+			},
+		}},
+		OnError: func(*Command, []string, []any, error) error { return nil },
+	}
+	ctx := context.Background()
+
+	root.Run(ctx, []string{"sub"})
+	if buf.Len() != 0 {
+		t.Errorf("timeline displayed at normal level:\n%s", buf.String())
+	}
+
+	root.SetLevel(Debug)
+	root.Run(ctx, []string{"sub"})
+	want := `timeline:
+  prog      parse     1ms
+  prog sub  parse     1ms
+  prog sub  checks    1ms
+  prog sub  func      1ms
+  prog sub  cleanup   1ms
+  prog sub  on error  1ms
+  prog      cleanup   1ms
+  prog      total     15ms
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestTimelineFlags(t *testing.T) {
+	var buf bytes.Buffer
+	root := &Command{
+		Name:           "prog",
+		Stderr:         &buf,
+		VerbosityFlags: true,
+		SubCommands: []*Command{{
+			Name: "sub",
+			Func: func(context.Context, *Command, []string, ...any) error { return nil },
+		}},
+	}
+	ctx := context.Background()
+
+	root.Run(ctx, []string{"--debug", "sub"})
+	if !bytes.Contains(buf.Bytes(), []byte("timeline:")) {
+		t.Errorf("--debug did not display the timeline:\n%s", buf.String())
+	}
+	if lvl := root.Level(); lvl != Debug {
+		t.Errorf("--debug: level is %v, want %v", lvl, Debug)
+	}
+
+	buf.Reset()
+	root.Run(ctx, []string{"sub"})
+	if buf.Len() != 0 {
+		t.Errorf("timeline displayed without --debug:\n%s", buf.String())
+	}
+	if lvl := root.Level(); lvl != Normal {
+		t.Errorf("level is %v, want %v", lvl, Normal)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/pborman/indent"
+)
+
+// isTopic returns true if c is a help topic rather than a command.  See the
+// package documentation.
+func (c *Command) isTopic() bool {
+	return c.Description != "" && c.Func == nil && c.SubCommands == nil &&
+		c.SubCommandsFunc == nil && c.Dispatch == nil
+}
+
+// topics returns the help topics of c that are not hidden, sorted by name.
+func (c *Command) topics() []*Command {
+	var topics []*Command
+	for _, sc := range c.SubCommands {
+		if sc.isTopic() && !sc.hidden() {
+			topics = append(topics, sc)
+		}
+	}
+	sort.Slice(topics, func(i, j int) bool { return topics[i].Name < topics[j].Name })
+	return topics
+}
+
+// printTopics writes the name and Help of each help topic of c to w.
+func (c *Command) printTopics(w io.Writer) {
+	topics := c.topics()
+	if len(topics) == 0 {
+		return
+	}
+	c.heading(w, "\nAdditional help topics:\n")
+	for _, tc := range topics {
+		c.fprintf(w, "\n  %s\n", tc.Name)
+		if tc.Help != "" {
+			c.fprintf(w, "%s\n", indent.String("    ", tc.Help))
+		}
+	}
+}
+
+// writeTopic writes the help topic c to w.
+func (c *Command) writeTopic(w io.Writer) {
+	c.fprintf(w, "%s\n", strings.TrimSpace(c.Description))
+}
@@ -0,0 +1,78 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestTopics(t *testing.T) {
+	var out bytes.Buffer
+	noop := func(context.Context, *Command, []string, ...any) error { return nil }
+	root := &Command{
+		Name:   "prog",
+		Stderr: &out,
+		SubCommands: []*Command{
+			{Name: "run", Help: "run things", Func: noop},
+			{Name: "filters", Help: "filter syntax", Description: "Filters are written as\n\n    key=value\n"},
+			{Name: "environment", Help: "environment variables", Description: "PROG_HOME is the home directory."},
+			HelpCmd,
+		},
+	}
+	ctx := context.Background()
+	if err := root.Run(ctx, []string{"help"}); err != nil {
+		t.Fatal(err)
+	}
+	want := `
+Additional help topics:
+
+  environment
+    environment variables
+
+  filters
+    filter syntax
+`
+	if got := out.String(); !strings.HasSuffix(got, want) {
+		t.Errorf("got:\n%s\nwant suffix:\n%s", got, want)
+	}
+	if strings.Contains(strings.TrimSuffix(out.String(), want), "environment") {
+		t.Errorf("topic listed as a sub command:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := root.Run(ctx, []string{"help", "filters"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "Filters are written as\n\n    key=value\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	out.Reset()
+	err := root.Run(ctx, []string{"environment"})
+	if want := "prog: environment is a help topic, not a command"; err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %s", err, want)
+	}
+
+	out.Reset()
+	root.PrintUsage(&out)
+	if want := "\nAdditional help topics:\n   environment  environment variables\n   filters  filter syntax\n"; !strings.HasSuffix(out.String(), want) {
+		t.Errorf("got usage:\n%s\nwant suffix:\n%s", out.String(), want)
+	}
+	if errs := root.Validate(); len(errs) != 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+}
@@ -0,0 +1,377 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+//go:build !commander_nonet
+
+package commander
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Release describes a released version of a program.
+type Release struct {
+	Version   string `json:"version"`
+	URL       string `json:"url"`                 // https URL of the binary
+	SHA256    string `json:"sha256"`              // hex encoded SHA-256 of the binary
+	Signature string `json:"signature,omitempty"` // base64 ed25519 signature of the binary
+}
+
+// An Updater finds and installs new releases of the running program.
+//
+// By default the latest release is described by a JSON encoded Release
+// fetched from URL.  The strings {channel}, {os}, and {arch} in URL are
+// replaced by the release channel, runtime.GOOS, and runtime.GOARCH.  Set
+// Fetch to use a different source of releases, such as GitHub releases.
+//
+// The downloaded binary must match the Release's SHA256.  If PublicKey is
+// set the Release must also have a valid Signature.
+type Updater struct {
+	URL       string            // URL of the latest Release
+	Version   string            // Version of the running program
+	Channel   string            // Default release channel ("stable" if not set)
+	PublicKey ed25519.PublicKey // If set, releases must be signed
+	Client    *http.Client      // Defaults to a client with DefaultUpdateTimeout
+
+	// Fetch, if not nil, is used to find the latest release on channel
+	// rather than fetching URL.
+	Fetch func(ctx context.Context, channel string) (*Release, error)
+
+	// CheckInterval is how often UpdateNotice looks for a newer release.
+	// The default is once a day.  CacheFile is where the result is
+	// recorded between runs.  The default is update.json in the
+	// directory named for the program in os.UserCacheDir.
+	CheckInterval time.Duration
+	CacheFile     string
+
+	cmd *Command // the command returned by NewUpdateCmd
+}
+
+// An updateCache is the contents of an Updater's CacheFile.
+type updateCache struct {
+	Checked time.Time `json:"checked"`           // when the last check was made
+	Version string    `json:"version,omitempty"` // the latest version found
+}
+
+// DefaultUpdateTimeout is how long an Updater without a Client waits for a
+// release or binary to be fetched.
+const DefaultUpdateTimeout = 5 * time.Minute
+
+// maxUpdateSize is the largest release or binary an Updater will fetch.
+const maxUpdateSize = 256 << 20
+
+// defaultUpdateClient is used by an Updater without a Client.
+var defaultUpdateClient = &http.Client{Timeout: DefaultUpdateTimeout}
+
+// Tests can override this
+var executable = os.Executable
+
+func (u *Updater) client() *http.Client {
+	if u.Client != nil {
+		return u.Client
+	}
+	return defaultUpdateClient
+}
+
+// get fetches the https URL rawurl.
+func (u *Updater) get(ctx context.Context, rawurl string) ([]byte, error) {
+	pu, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	if pu.Scheme != "https" {
+		return nil, fmt.Errorf("%s: updates must use https", rawurl)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawurl, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := u.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", rawurl, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxUpdateSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxUpdateSize {
+		return nil, fmt.Errorf("%s: larger than %d bytes", rawurl, maxUpdateSize)
+	}
+	return data, nil
+}
+
+// Latest returns the latest release on channel.  The Updater's Channel is
+// used if channel is empty.
+func (u *Updater) Latest(ctx context.Context, channel string) (*Release, error) {
+	if channel == "" {
+		channel = u.Channel
+	}
+	if channel == "" {
+		channel = "stable"
+	}
+	if u.Fetch != nil {
+		return u.Fetch(ctx, channel)
+	}
+	rawurl := strings.NewReplacer(
+		"{channel}", url.PathEscape(channel),
+		"{os}", runtime.GOOS,
+		"{arch}", runtime.GOARCH,
+	).Replace(u.URL)
+	data, err := u.get(ctx, rawurl)
+	if err != nil {
+		return nil, err
+	}
+	var r Release
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("%s: %v", rawurl, err)
+	}
+	return &r, nil
+}
+
+// Newer returns true if r is newer than the Updater's Version.
+func (u *Updater) Newer(r *Release) bool {
+	return compareVersions(r.Version, u.Version) > 0
+}
+
+// Install downloads and verifies the binary for r and replaces the running
+// program with it.
+func (u *Updater) Install(ctx context.Context, r *Release) error {
+	data, err := u.get(ctx, r.URL)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	if want, err := hex.DecodeString(r.SHA256); err != nil || !bytes.Equal(sum[:], want) {
+		return fmt.Errorf("%s: checksum mismatch", r.URL)
+	}
+	if u.PublicKey != nil {
+		sig, err := base64.StdEncoding.DecodeString(r.Signature)
+		if err != nil || !ed25519.Verify(u.PublicKey, data, sig) {
+			return fmt.Errorf("%s: invalid signature", r.URL)
+		}
+	}
+	exe, err := executable()
+	if err != nil {
+		return err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return err
+	}
+	fi, err := os.Stat(exe)
+	if err != nil {
+		return err
+	}
+	fd, err := os.CreateTemp(filepath.Dir(exe), filepath.Base(exe)+".new*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(fd.Name())
+	if _, err := fd.Write(data); err != nil {
+		fd.Close()
+		return err
+	}
+	if err := fd.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(fd.Name(), fi.Mode().Perm()); err != nil {
+		return err
+	}
+	// Some systems do not permit replacing a running binary, but do
+	// permit renaming it.
+	old := exe + ".old"
+	if err := os.Rename(exe, old); err != nil {
+		return err
+	}
+	if err := os.Rename(fd.Name(), exe); err != nil {
+		os.Rename(old, exe)
+		return err
+	}
+	os.Remove(old)
+	return nil
+}
+
+// UpdateNotice implements UpdateNotifier.  Set the UpdateNotifier of the
+// root command to u to have the program tell the user when a newer release
+// is available:
+//
+//	a newer version of prog is available: 1.1.0 (running 1.0.0)
+//
+// The latest release is looked for at most once per CheckInterval, the
+// result is remembered in CacheFile.  If the command returned by
+// NewUpdateCmd(u) is in the tree the notice says to run it.  No notice is
+// given when that command is the one that ran.
+func (u *Updater) UpdateNotice(ctx context.Context, root *Command) string {
+	if u.cmd != nil && root.ran == u.cmd {
+		return ""
+	}
+	file := u.CacheFile
+	if file == "" {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			return ""
+		}
+		file = filepath.Join(dir, root.Name, "update.json")
+	}
+	interval := u.CheckInterval
+	if interval <= 0 {
+		interval = 24 * time.Hour
+	}
+	var cache updateCache
+	if data, err := os.ReadFile(file); err == nil {
+		json.Unmarshal(data, &cache)
+	}
+	if now().Sub(cache.Checked) >= interval {
+		ctx, cancel := context.WithTimeout(ctx, updateNoticeTimeout)
+		r, err := u.Latest(ctx, "")
+		cancel()
+		if err == nil {
+			cache.Version = r.Version
+		}
+		cache.Checked = now()
+		if data, err := json.Marshal(&cache); err == nil {
+			os.MkdirAll(filepath.Dir(file), 0777)
+			os.WriteFile(file, data, 0666)
+		}
+	}
+	if cache.Version == "" || compareVersions(cache.Version, u.Version) <= 0 {
+		return ""
+	}
+	notice := root.sprintf("a newer version of %s is available: %s (running %s)", root.Name, cache.Version, u.Version)
+	if u.cmd != nil {
+		root.Walk(func(c *Command) error {
+			if c == u.cmd {
+				notice += root.sprintf(", run \"%s\" to update", c.Command())
+			}
+			return nil
+		})
+	}
+	return notice
+}
+
+// compareVersions compares two dotted version strings, such as v1.2.10,
+// numerically.  A version may have a pre-release suffix, such as 1.2.0-rc1,
+// which ranks below the version without it, as in semantic versioning.  It
+// returns -1, 0, or 1.
+func compareVersions(a, b string) int {
+	a, apre, ahas := strings.Cut(strings.TrimPrefix(a, "v"), "-")
+	b, bpre, bhas := strings.Cut(strings.TrimPrefix(b, "v"), "-")
+	if c := compareDotted(a, b); c != 0 {
+		return c
+	}
+	switch {
+	case ahas && !bhas:
+		return -1
+	case !ahas && bhas:
+		return 1
+	}
+	return compareDotted(apre, bpre)
+}
+
+// compareDotted compares the dot separated fields of a and b in order.
+// Numeric fields are compared numerically and others as strings, a numeric
+// field ranking below one that is not.  A missing field ranks below any
+// other.  It returns -1, 0, or 1.
+func compareDotted(a, b string) int {
+	as := strings.Split(a, ".")
+	bs := strings.Split(b, ".")
+	for len(as) > 0 && len(bs) > 0 {
+		ap, bp := as[0], bs[0]
+		as, bs = as[1:], bs[1:]
+		an, aerr := strconv.Atoi(ap)
+		bn, berr := strconv.Atoi(bp)
+		switch {
+		case aerr == nil && berr == nil:
+			if an != bn {
+				if an < bn {
+					return -1
+				}
+				return 1
+			}
+		case aerr == nil:
+			return -1
+		case berr == nil:
+			return 1
+		case ap != bp:
+			if ap < bp {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case len(as) < len(bs):
+		return -1
+	case len(as) > len(bs):
+		return 1
+	}
+	return 0
+}
+
+type updateFlags struct {
+	Check   bool   `flag:"--check         only check for a newer version"`
+	Channel string `flag:"--channel=NAME  use the release channel NAME"`
+}
+
+// NewUpdateCmd returns a sub command that updates the running program to the
+// latest release found by u.  UpdateNotice refers the user to the returned
+// command.
+func NewUpdateCmd(u *Updater) *Command {
+	u.cmd = &Command{
+		Name:     "update",
+		Help:     "update to the latest release",
+		MaxArgs:  NoArgs,
+		Defaults: &updateFlags{},
+		Func: func(ctx context.Context, c *Command, _ []string, _ ...any) error {
+			opts := c.Flags.(*updateFlags)
+			r, err := u.Latest(ctx, opts.Channel)
+			if err != nil {
+				return err
+			}
+			w := c.stdout()
+			if !u.Newer(r) {
+				c.fprintf(w, "%s is up to date\n", u.Version)
+				return nil
+			}
+			if opts.Check {
+				c.fprintf(w, "%s is available (running %s)\n", r.Version, u.Version)
+				return nil
+			}
+			if err := u.Install(ctx, r); err != nil {
+				return err
+			}
+			c.fprintf(w, "updated %s to %s\n", u.Version, r.Version)
+			return nil
+		},
+	}
+	return u.cmd
+}
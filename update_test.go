@@ -0,0 +1,230 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+//go:build !commander_nonet
+
+package commander
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompareVersions(t *testing.T) {
+	for _, tt := range []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"v1.2.10", "1.2.9", 1},
+		{"1.2", "1.2.1", -1},
+		{"2.0", "10.0", -1},
+		{"1.0-rc1", "1.0-rc2", -1},
+		{"1.0.0-rc1", "1.0.0", -1},
+		{"1.0.0", "1.0.0-rc1", 1},
+		{"v1.0.0-rc1", "1.0.0-rc1", 0},
+		{"1.0.10-rc1", "1.0.9", 1},
+		{"1.0.9", "1.0.10-rc1", -1},
+		{"1.0.0-rc.2", "1.0.0-rc.10", -1},
+		{"1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{"1.0.0-alpha.1", "1.0.0-beta", -1},
+		{"1.0.0-1", "1.0.0-alpha", -1},
+	} {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) got %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	binary := []byte("new binary")
+	sum := sha256.Sum256(binary)
+	release := Release{
+		Version:   "1.1.0",
+		SHA256:    hex.EncodeToString(sum[:]),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(priv, binary)),
+	}
+	var srv *httptest.Server
+	srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/beta/release.json":
+			r := release
+			r.URL = srv.URL + "/binary"
+			json.NewEncoder(w).Encode(r)
+		case "/binary":
+			w.Write(binary)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	exe := filepath.Join(t.TempDir(), "prog")
+	os.WriteFile(exe, []byte("old binary"), 0755)
+	defer func(e func() (string, error)) { executable = e }(executable)
+	executable = func() (string, error) { return exe, nil }
+
+	u := &Updater{
+		URL:       srv.URL + "/{channel}/release.json",
+		Version:   "1.0.0",
+		Channel:   "beta",
+		PublicKey: pub,
+		Client:    srv.Client(),
+	}
+	var out bytes.Buffer
+	root := &Command{
+		Name:        "prog",
+		Stdout:      &out,
+		SubCommands: []*Command{NewUpdateCmd(u)},
+	}
+	ctx := context.Background()
+
+	if err := root.Run(ctx, []string{"update", "--check"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "1.1.0 is available (running 1.0.0)\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if err := root.Run(ctx, []string{"update", "--channel=stable"}); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("got error %v, want 404", err)
+	}
+
+	release.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(priv, []byte("other")))
+	if err := root.Run(ctx, []string{"update"}); err == nil || !strings.Contains(err.Error(), "invalid signature") {
+		t.Errorf("got error %v, want invalid signature", err)
+	}
+	release.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(priv, binary))
+	release.SHA256 = strings.Repeat("0", 64)
+	if err := root.Run(ctx, []string{"update"}); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("got error %v, want checksum mismatch", err)
+	}
+	release.SHA256 = hex.EncodeToString(sum[:])
+
+	out.Reset()
+	if err := root.Run(ctx, []string{"update"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "updated 1.0.0 to 1.1.0\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	data, _ := os.ReadFile(exe)
+	if !bytes.Equal(data, binary) {
+		t.Errorf("binary is %q, want %q", data, binary)
+	}
+	if fi, err := os.Stat(exe); err != nil || fi.Mode().Perm() != 0755 {
+		t.Errorf("binary mode is %v, %v", fi.Mode(), err)
+	}
+
+	u.Version = "1.1.0"
+	out.Reset()
+	root.Run(ctx, []string{"update"})
+	if got, want := out.String(), "1.1.0 is up to date\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestUpdateNotice(t *testing.T) {
+	defer func(n func() time.Time) { now = n }(now)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := start
+	now = func() time.Time { return clock }
+
+	var fetches int
+	latest := "1.1.0"
+	u := &Updater{
+		Version:   "1.0.0",
+		CacheFile: filepath.Join(t.TempDir(), "update.json"),
+		Fetch: func(context.Context, string) (*Release, error) {
+			fetches++
+			return &Release{Version: latest}, nil
+		},
+	}
+	var stderr bytes.Buffer
+	noop := func(context.Context, *Command, []string, ...any) error { return nil }
+	root := &Command{
+		Name:           "prog",
+		Stdout:         io.Discard,
+		Stderr:         &stderr,
+		UpdateNotifier: u,
+		SubCommands:    []*Command{{Name: "run", Func: noop}, NewUpdateCmd(u)},
+	}
+	ctx := context.Background()
+	run := func(args ...string) string {
+		stderr.Reset()
+		if err := root.Run(ctx, args); err != nil {
+			t.Fatal(err)
+		}
+		return stderr.String()
+	}
+
+	want := "a newer version of prog is available: 1.1.0 (running 1.0.0), run \"prog update\" to update\n"
+	if got := run("run"); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := run("update", "--check"); got != "" {
+		t.Errorf("update: got notice %q", got)
+	}
+	latest = "1.0.0"
+	clock = start.Add(time.Hour)
+	if got := run("run"); got != want {
+		t.Errorf("cached: got %q, want %q", got, want)
+	}
+	if fetches != 2 {
+		t.Errorf("got %d fetches, want 2", fetches)
+	}
+	clock = start.Add(25 * time.Hour)
+	if got := run("run"); got != "" {
+		t.Errorf("up to date: got notice %q", got)
+	}
+	if fetches != 3 {
+		t.Errorf("got %d fetches, want 3", fetches)
+	}
+}
+
+// A noticeFunc is an UpdateNotifier.
+type noticeFunc func(ctx context.Context, root *Command) string
+
+func (f noticeFunc) UpdateNotice(ctx context.Context, root *Command) string { return f(ctx, root) }
+
+func TestUpdateNoticeTimeout(t *testing.T) {
+	var deadline bool
+	root := &Command{
+		Name:   "prog",
+		Stderr: io.Discard,
+		Func:   func(context.Context, *Command, []string, ...any) error { return nil },
+		UpdateNotifier: noticeFunc(func(ctx context.Context, _ *Command) string {
+			_, deadline = ctx.Deadline()
+			return ""
+		}),
+	}
+	if err := root.Run(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if !deadline {
+		t.Errorf("UpdateNotice called without a deadline")
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/pborman/flags"
+)
+
+// Validate checks the declaration of c and all of its sub commands and
+// returns an error for each problem found.  Validate reports:
+//
+//   - a command with neither Func, SubCommands, nor Dispatch that is not a
+//     help topic
+//   - a Flags or Defaults that is not a valid flags structure
+//   - an invalid Arity, or a MinArgs greater than MaxArgs
+//   - a name or alias used by more than one sub command of a command
+//   - a DefaultSubCommand that does not name a sub command
+//   - a command that is its own sub command, directly or indirectly
+//
+// Validate also reports flag structures that are shared in a way that lets
+// the flags of one command change the flags of another:
+//
+//   - a command with both Defaults and Flags set to the same structure
+//   - a command without Defaults whose Flags structure is also the Flags or
+//     Defaults of another command
+//
+// Sharing Defaults between commands is safe as each command parses into its
+// own copy.  A command listed more than once in the tree is only checked
+// once.  Validate is intended to be called from tests, or from TestMain:
+//
+//	func TestValidate(t *testing.T) {
+//		for _, err := range cmd.Validate() {
+//			t.Error(err)
+//		}
+//	}
+func (c *Command) Validate() []error {
+	type use struct {
+		c     *Command
+		field string
+	}
+	var errs []error
+	var cmds []*Command // in the order they were walked
+	uses := map[any][]use{}
+	c.Walk(func(vc *Command) error {
+		cmds = append(cmds, vc)
+		if isPtr(vc.Defaults) && vc.Defaults == vc.Flags {
+			errs = append(errs, fmt.Errorf("%s: Defaults and Flags are the same structure", vc.Command()))
+		} else {
+			for _, u := range []struct {
+				field string
+				opts  any
+			}{{"Defaults", vc.Defaults}, {"Flags", vc.Flags}} {
+				if isPtr(u.opts) {
+					uses[u.opts] = append(uses[u.opts], use{vc, u.field})
+				}
+			}
+		}
+		if vc.Func == nil && len(vc.SubCommands) == 0 && vc.Dispatch == nil && !vc.isTopic() {
+			errs = append(errs, fmt.Errorf("%s: has neither Func nor SubCommands", vc.Command()))
+		}
+		if err := checkFlags(vc.Name, vc.getFlags()); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", vc.Command(), err))
+		}
+		if min, max, err := vc.arity(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", vc.Command(), err))
+		} else if max >= 0 && min > max {
+			errs = append(errs, fmt.Errorf("%s: MinArgs %d is greater than MaxArgs %d", vc.Command(), min, max))
+		}
+		names := map[string]*Command{}
+		for _, sc := range vc.SubCommands {
+			for _, name := range append([]string{sc.Name}, sc.Aliases...) {
+				switch prev := names[name]; {
+				case prev == nil:
+					names[name] = sc
+				case prev == sc && name == sc.Name:
+					errs = append(errs, fmt.Errorf("%s: sub command %s is listed more than once", vc.Command(), name))
+				case prev != sc:
+					errs = append(errs, fmt.Errorf("%s: %s names both sub command %s and %s", vc.Command(), name, prev.Name, sc.Name))
+				}
+			}
+		}
+		if vc.DefaultSubCommand != "" && vc.findSub(vc.DefaultSubCommand) == nil {
+			errs = append(errs, fmt.Errorf("%s: DefaultSubCommand %s is not a sub command", vc.Command(), vc.DefaultSubCommand))
+		}
+		return nil
+	})
+
+	// Walk only visits each command once so cycles are found separately.
+	onPath := map[*Command]bool{}
+	done := map[*Command]bool{}
+	var visit func(*Command)
+	visit = func(vc *Command) {
+		if done[vc] {
+			return
+		}
+		onPath[vc] = true
+		for _, sc := range vc.SubCommands {
+			if onPath[sc] {
+				errs = append(errs, fmt.Errorf("%s: sub command %s creates a cycle", vc.Command(), sc.Name))
+				continue
+			}
+			visit(sc)
+		}
+		onPath[vc] = false
+		done[vc] = true
+	}
+	visit(c)
+
+	// Each shared structure is reported once, by the first command that
+	// parses directly into it.
+	reported := map[any]bool{}
+	for _, vc := range cmds {
+		if vc.Defaults != nil || !isPtr(vc.Flags) || reported[vc.Flags] {
+			continue
+		}
+		reported[vc.Flags] = true
+		for _, u := range uses[vc.Flags] {
+			if u.c != vc {
+				errs = append(errs, fmt.Errorf("%s: Flags is also the %s of %s", vc.Command(), u.field, u.c.Command()))
+			}
+		}
+	}
+	return errs
+}
+
+// checkFlags returns an error if opts is set but is not a valid flags
+// structure.
+func checkFlags(name string, opts any) error {
+	if opts == nil {
+		return nil
+	}
+	if _, _, err := flagFields(opts); err != nil {
+		return err
+	}
+	return registerFlags(dupFlags(opts), flags.NewFlagSet(name))
+}
+
+// isPtr returns true if opts is a non-nil pointer.
+func isPtr(opts any) bool {
+	return opts != nil && reflect.ValueOf(opts).Kind() == reflect.Ptr
+}
@@ -0,0 +1,110 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	type options struct {
+		Name string `flag:"--name=NAME a name"`
+	}
+	noop := func(context.Context, *Command, []string, ...any) error { return nil }
+	shared := &options{}
+	defaults := &options{}
+	common := &Command{Name: "common", Flags: &options{}, Func: noop}
+	root := &Command{
+		Name:     "prog",
+		Defaults: defaults,
+		SubCommands: []*Command{
+			{Name: "a", Flags: shared, Func: noop},
+			{Name: "b", Flags: shared, Func: noop},
+			{Name: "c", Defaults: shared, Func: noop},
+			{Name: "d", Defaults: defaults, Func: noop},
+			{Name: "e", Flags: defaults, Func: noop},
+			{Name: "same", Defaults: &options{}, Func: noop},
+			common,
+			{Name: "sub", SubCommands: []*Command{common}},
+			HelpCmd,
+		},
+	}
+	same := root.SubCommands[5]
+	same.Flags = same.Defaults
+
+	var got []string
+	for _, err := range root.Validate() {
+		got = append(got, err.Error())
+	}
+	want := []string{
+		"prog same: Defaults and Flags are the same structure",
+		"prog a: Flags is also the Flags of prog b",
+		"prog a: Flags is also the Defaults of prog c",
+		"prog e: Flags is also the Defaults of prog",
+		"prog e: Flags is also the Defaults of prog d",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if errs := (&Command{Name: "prog", Defaults: &options{}, Func: noop}).Validate(); len(errs) != 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+}
+
+func TestValidateTree(t *testing.T) {
+	type badTag struct {
+		Name string `flag:"--name=NAME --other a name"`
+	}
+	noop := func(context.Context, *Command, []string, ...any) error { return nil }
+	loop := &Command{Name: "loop"}
+	dup := &Command{Name: "dup", Func: noop}
+	root := &Command{
+		Name: "prog",
+		SubCommands: []*Command{
+			{Name: "empty"},
+			{Name: "args", MinArgs: 3, MaxArgs: 1, Func: noop},
+			{Name: "arity", Arity: "x", Func: noop},
+			{Name: "noargs", MinArgs: 1, MaxArgs: NoArgs, Func: noop},
+			{Name: "notptr", Flags: struct{}{}, Func: noop},
+			{Name: "tag", Flags: &badTag{}, Func: noop},
+			{Name: "list", Aliases: []string{"ls"}, Func: noop},
+			{Name: "ls", Func: noop},
+			dup,
+			dup,
+			loop,
+		},
+	}
+	loop.SubCommands = []*Command{{Name: "inner", SubCommands: []*Command{root}}}
+
+	var got []string
+	for _, err := range root.Validate() {
+		got = append(got, err.Error())
+	}
+	want := []string{
+		"prog: ls names both sub command list and ls",
+		"prog: sub command dup is listed more than once",
+		"prog empty: has neither Func nor SubCommands",
+		"prog args: MinArgs 3 is greater than MaxArgs 1",
+		`prog arity: invalid arity "x"`,
+		"prog noargs: MinArgs 1 is greater than MaxArgs 0",
+		"prog notptr: struct {} is not a pointer to a struct",
+		`prog tag: flag tag has too many names: "--name=NAME --other a name"`,
+		"prog loop inner: sub command prog creates a cycle",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
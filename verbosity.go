@@ -0,0 +1,193 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pborman/flags"
+)
+
+// A Level is the amount of output a program displays.  The level is
+// normally set by the standard -q/--quiet, -v/--verbose, and --debug flags
+// (see the VerbosityFlags field of Command) and is used by the Printf family
+// of Command methods.
+type Level int
+
+const (
+	Quiet   Level = -1 // only errors are displayed
+	Normal  Level = 0  // the default
+	Verbose Level = 1  // additional information is displayed
+	Debug   Level = 2  // debugging information, including timelines, is displayed
+)
+
+// The standard verbosity flags.
+var verbosityFlags = []struct {
+	short, long string
+	level       Level
+	help        string
+}{
+	{"q", "quiet", Quiet, "only display errors"},
+	{"v", "verbose", Verbose, "display additional output"},
+	{"", "debug", Debug, "display debugging information"},
+}
+
+// A levelFlag is the flag.Value of a standard verbosity flag.  Setting it
+// sets the level, so when several verbosity flags are given the last one
+// on the command line wins.
+type levelFlag struct {
+	level *Level // the level being set
+	value Level  // the level the flag selects
+}
+
+func (f *levelFlag) Set(s string) error {
+	on, err := strconv.ParseBool(s)
+	switch {
+	case err != nil:
+		return err
+	case on:
+		*f.level = f.value
+	case *f.level == f.value:
+		*f.level = Normal
+	}
+	return nil
+}
+
+func (f *levelFlag) String() string   { return "false" }
+func (f *levelFlag) IsBoolFlag() bool { return true }
+
+// Level returns the verbosity level of the current invocation of the tree
+// c is in.
+func (c *Command) Level() Level {
+	return c.Root().level
+}
+
+// SetLevel sets the verbosity level of the tree c is in.  The standard
+// flags, when enabled, override the level each time the tree is run.
+func (c *Command) SetLevel(l Level) {
+	c.Root().level = l
+}
+
+// resetLevel sets the level of the root command c back to Normal if c
+// accepts the standard verbosity flags, so the level of a run is only set by
+// the flags given to it, not those given to a previous run.
+func (c *Command) resetLevel() {
+	if len(c.verbosityFlagNames()) > 0 {
+		c.level = Normal
+	}
+}
+
+// ShowProgress returns true if progress indicators, such as spinners and
+// progress bars, should be displayed.  They are not displayed in quiet mode.
+func (c *Command) ShowProgress() bool {
+	return c.Level() > Quiet
+}
+
+// Printf displays normal output on c's Stdout.  Nothing is displayed in
+// quiet mode.  If QuietBrokenPipe is set and Stdout is a broken pipe then
+// Printf calls Exit(0).
+func (c *Command) Printf(format string, a ...any) {
+	if c.Level() >= Normal {
+		c.checkPipe(c.fprintf(c.stdout(), format, a...))
+	}
+}
+
+// Verbosef displays additional information on c's Stderr if the level is
+// Verbose or higher.
+func (c *Command) Verbosef(format string, a ...any) {
+	if c.Level() >= Verbose {
+		c.fprintf(c.stderr(), format, a...)
+	}
+}
+
+// Debugf displays debugging information on c's Stderr if the level is Debug.
+func (c *Command) Debugf(format string, a ...any) {
+	if c.Level() >= Debug {
+		c.fprintf(c.stderr(), format, a...)
+	}
+}
+
+// Eprintf displays an error message on c's Stderr.  Errors are displayed
+// at every level, including Quiet.
+func (c *Command) Eprintf(format string, a ...any) {
+	c.fprintf(c.stderr(), format, a...)
+}
+
+// verbosityFlagNames returns the names of the standard verbosity flags c
+// accepts.  Only a root command with VerbosityFlags set accepts them, and
+// only if it does not declare flags with the same names.
+func (c *Command) verbosityFlagNames() map[string]bool {
+	if c.parent != nil || !c.VerbosityFlags {
+		return nil
+	}
+	declared := map[string]bool{}
+	if opts := c.getFlags(); opts != nil {
+		if _, fields, err := flagFields(opts); err == nil {
+			for _, f := range fields {
+				declared[f.name] = true
+			}
+		}
+	}
+	names := map[string]bool{}
+	for _, vf := range verbosityFlags {
+		for _, name := range []string{vf.short, vf.long} {
+			if name != "" && !declared[name] {
+				names[name] = true
+			}
+		}
+	}
+	return names
+}
+
+// addVerbosityFlags adds the standard verbosity flags c accepts to set.  The
+// returned function, which is nil if no flags were added, must be called
+// after set is parsed to set the level.
+func (c *Command) addVerbosityFlags(set flags.FlagSet) func() {
+	names := c.verbosityFlagNames()
+	if len(names) == 0 {
+		return nil
+	}
+	level := Normal
+	for _, vf := range verbosityFlags {
+		for _, name := range []string{vf.short, vf.long} {
+			if names[name] {
+				setVar(set, &levelFlag{level: &level, value: vf.level}, name, vf.help)
+			}
+		}
+	}
+	return func() {
+		c.level = level
+	}
+}
+
+// verbosityFlagHelp returns the help for the standard verbosity flags c
+// accepts.
+func (c *Command) verbosityFlagHelp() []standardFlag {
+	accepted := c.verbosityFlagNames()
+	var help []standardFlag
+	for _, vf := range verbosityFlags {
+		var names []string
+		if accepted[vf.short] {
+			names = append(names, "-"+vf.short)
+		}
+		if accepted[vf.long] {
+			names = append(names, "--"+vf.long)
+		}
+		if len(names) > 0 {
+			help = append(help, standardFlag{strings.Join(names, ", "), vf.help})
+		}
+	}
+	return help
+}
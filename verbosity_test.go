@@ -0,0 +1,148 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestVerbosityFlags(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	var progress bool
+	sub := &Command{
+		Name: "sub",
+		Func: func(_ context.Context, c *Command, _ []string, _ ...any) error {
+			c.Printf("normal\n")
+			c.Verbosef("verbose\n")
+			c.Debugf("debug\n")
+			c.Eprintf("error\n")
+			progress = c.ShowProgress()
+			return nil
+		},
+	}
+	root := &Command{
+		Name:           "prog",
+		Stdout:         &stdout,
+		Stderr:         &stderr,
+		VerbosityFlags: true,
+		SubCommands:    []*Command{sub, HelpCmd},
+	}
+	for _, tt := range []struct {
+		args           []string
+		stdout, stderr string
+		progress       bool
+	}{
+		{[]string{"sub"}, "normal\n", "error\n", true},
+		{[]string{"-q", "sub"}, "", "error\n", false},
+		{[]string{"--quiet", "sub"}, "", "error\n", false},
+		{[]string{"-v", "sub"}, "normal\n", "verbose\nerror\n", true},
+		{[]string{"sub"}, "normal\n", "error\n", true},
+	} {
+		stdout.Reset()
+		stderr.Reset()
+		if err := root.Run(context.Background(), tt.args); err != nil {
+			t.Errorf("%q: %v", tt.args, err)
+			continue
+		}
+		if got := stdout.String(); got != tt.stdout {
+			t.Errorf("%q: got stdout %q, want %q", tt.args, got, tt.stdout)
+		}
+		if got := stderr.String(); got != tt.stderr {
+			t.Errorf("%q: got stderr %q, want %q", tt.args, got, tt.stderr)
+		}
+		if progress != tt.progress {
+			t.Errorf("%q: got ShowProgress %v, want %v", tt.args, progress, tt.progress)
+		}
+	}
+
+	// The last verbosity flag given wins.
+	var level Level
+	sub.Func = func(_ context.Context, c *Command, _ []string, _ ...any) error {
+		level = c.Level()
+		return nil
+	}
+	for _, tt := range []struct {
+		args []string
+		want Level
+	}{
+		{[]string{"-q", "-v", "sub"}, Verbose},
+		{[]string{"-v", "-q", "sub"}, Quiet},
+		{[]string{"--debug", "sub"}, Debug},
+		{[]string{"--debug", "-v", "sub"}, Verbose},
+		{[]string{"-v", "--verbose=false", "sub"}, Normal},
+	} {
+		stderr.Reset()
+		if err := root.Run(context.Background(), tt.args); err != nil {
+			t.Errorf("%q: %v", tt.args, err)
+		}
+		if level != tt.want {
+			t.Errorf("%q: got level %d, want %d", tt.args, level, tt.want)
+		}
+	}
+	sub.Func = func(_ context.Context, c *Command, _ []string, _ ...any) error {
+		c.Printf("normal\n")
+		c.Verbosef("verbose\n")
+		c.Debugf("debug\n")
+		c.Eprintf("error\n")
+		return nil
+	}
+
+	root.SetLevel(Debug)
+	stderr.Reset()
+	sub.Func(context.Background(), sub, nil)
+	if got, want := stderr.String(), "verbose\ndebug\nerror\n"; got != want {
+		t.Errorf("at Debug got stderr %q, want %q", got, want)
+	}
+
+	if c, _, err := root.Resolve([]string{"-q", "sub"}); err != nil || c != sub {
+		t.Errorf("Resolve got %v, %v, want sub", c, err)
+	}
+
+	stderr.Reset()
+	if err := root.Run(context.Background(), []string{"help", "sub"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Global options (prog):\n", "-q, --quiet", "-v, --verbose"} {
+		if !strings.Contains(stderr.String(), want) {
+			t.Errorf("help does not contain %q:\n%s", want, stderr.String())
+		}
+	}
+}
+
+func TestVerbosityFlagsDeclared(t *testing.T) {
+	var stderr bytes.Buffer
+	root := &Command{
+		Name:           "prog",
+		Stderr:         &stderr,
+		VerbosityFlags: true,
+		Defaults: &struct {
+			V bool `flag:"-v show the version"`
+		}{},
+		Func: func(_ context.Context, c *Command, _ []string, _ ...any) error { return nil },
+	}
+	if err := root.Run(context.Background(), []string{"-v"}); err != nil {
+		t.Fatal(err)
+	}
+	if root.Level() != Normal || !root.Lookup("", "v").(bool) {
+		t.Errorf("-v did not set the declared flag")
+	}
+	Help(context.Background(), root, nil)
+	got := stderr.String()
+	if !strings.Contains(got, "--verbose") || strings.Contains(got, "-v, --verbose") {
+		t.Errorf("help for standard flags wrong:\n%s", got)
+	}
+}
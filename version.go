@@ -0,0 +1,152 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"runtime/debug"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pborman/flags"
+)
+
+// readBuildInfo can be overridden by tests.
+var readBuildInfo = debug.ReadBuildInfo
+
+// VersionCmd is a sub command that calls the Version function.
+var VersionCmd = &Command{
+	Name:  "version",
+	Help:  "display the version and build information",
+	Arity: "0",
+	Func:  Version,
+}
+
+// Version implements the version command.
+//
+//	Usage: version
+//
+// Version displays the version of the program, the VCS revision and time it
+// was built from, the Go version used, and the module path, as recorded in
+// the binary's build information.  The Version field of the root command,
+// if set, takes precedence over the module version.  The lines returned by
+// the root command's VersionInfo, if set, are displayed last.
+func Version(ctx context.Context, c *Command, args []string, extra ...any) error {
+	r := c.Root()
+	var info []debug.BuildSetting
+	add := func(key, value string) {
+		if value != "" {
+			info = append(info, debug.BuildSetting{Key: key, Value: value})
+		}
+	}
+	version := r.Version
+	if bi, ok := readBuildInfo(); ok {
+		if version == "" {
+			version = bi.Main.Version
+		}
+		var revision, modified, built string
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				revision = s.Value
+			case "vcs.modified":
+				modified = s.Value
+			case "vcs.time":
+				built = s.Value
+			}
+		}
+		if revision != "" && modified == "true" {
+			revision = c.sprintf("%s (modified)", revision)
+		}
+		add(c.sprintf("version"), version)
+		add(c.sprintf("revision"), revision)
+		add(c.sprintf("built"), built)
+		add(c.sprintf("go"), bi.GoVersion)
+		add(c.sprintf("module"), bi.Main.Path)
+	} else {
+		add(c.sprintf("version"), version)
+	}
+	if r.VersionInfo != nil {
+		info = append(info, r.VersionInfo()...)
+	}
+	w := c.stdout()
+	c.fprintf(w, "%s\n", r.Name)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, s := range info {
+		c.fprintf(tw, "  %s:\t%s\n", s.Key, s.Value)
+	}
+	return tw.Flush()
+}
+
+// versionFlagNames returns the names of the standard version flags c
+// accepts.  Only a root command with a Version accepts them, and only if it
+// does not declare flags with the same names.
+func (c *Command) versionFlagNames() []string {
+	if c.parent != nil || c.Version == "" {
+		return nil
+	}
+	var names []string
+	for _, name := range []string{"V", "version"} {
+		if lookupFlagField(c.getFlags(), name) == nil {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// versionFlagHelp returns the help for the standard version flags c
+// accepts.
+func (c *Command) versionFlagHelp() []standardFlag {
+	var opts []string
+	for _, name := range c.versionFlagNames() {
+		if len(name) == 1 {
+			opts = append(opts, "-"+name)
+		} else {
+			opts = append(opts, "--"+name)
+		}
+	}
+	if len(opts) == 0 {
+		return nil
+	}
+	return []standardFlag{{strings.Join(opts, ", "), "display the version and exit"}}
+}
+
+// addVersionFlags adds the standard version flags c accepts to set.  The
+// returned function, which is nil if no flags were added, must be called
+// after set is parsed.
+func (c *Command) addVersionFlags(set flags.FlagSet) func() {
+	names := c.versionFlagNames()
+	if len(names) == 0 {
+		return nil
+	}
+	var show bool
+	for _, name := range names {
+		set.BoolVar(&show, name, false, "display the version and exit")
+	}
+	return func() {
+		c.showVersion = show
+	}
+}
+
+// printVersion displays the name and Version of c on c's Stdout if the
+// version was requested with a standard version flag.  It returns true if
+// the version was displayed.
+func (c *Command) printVersion() bool {
+	if !c.showVersion {
+		return false
+	}
+	c.showVersion = false
+	c.fprintf(c.stdout(), "%s %s\n", c.Name, c.Version)
+	return true
+}
@@ -0,0 +1,126 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"bytes"
+	"context"
+	"runtime/debug"
+	"strings"
+	"testing"
+)
+
+func TestVersion(t *testing.T) {
+	var out, errs bytes.Buffer
+	ran := false
+	root := &Command{
+		Name:    "prog",
+		Version: "1.2.3",
+		Stdout:  &out,
+		Stderr:  &errs,
+		MinArgs: 1,
+		Func: func(context.Context, *Command, []string, ...any) error {
+			ran = true
+			return nil
+		},
+	}
+	ctx := context.Background()
+	for _, flag := range []string{"-V", "--version"} {
+		out.Reset()
+		if err := root.Run(ctx, []string{flag}); err != nil {
+			t.Errorf("%s: %v", flag, err)
+		}
+		if got, want := out.String(), "prog 1.2.3\n"; got != want {
+			t.Errorf("%s: got %q, want %q", flag, got, want)
+		}
+		if ran {
+			t.Errorf("%s: Func was called", flag)
+		}
+	}
+	out.Reset()
+	if err := root.Run(ctx, []string{"arg"}); err != nil {
+		t.Fatal(err)
+	}
+	if !ran || out.Len() != 0 {
+		t.Errorf("got ran %v, output %q, want true, \"\"", ran, out.String())
+	}
+
+	errs.Reset()
+	Help(ctx, root, nil)
+	if got := errs.String(); !strings.Contains(got, "-V, --version    display the version and exit") {
+		t.Errorf("help does not list version flags:\n%s", got)
+	}
+
+	// A declared -V flag takes precedence.
+	root.Flags = &struct {
+		Verbose bool `flag:"-V be verbose"`
+	}{}
+	out.Reset()
+	if err := root.Run(ctx, []string{"-V", "arg"}); err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("declared -V displayed the version: %q", out.String())
+	}
+}
+
+func TestVersionCmd(t *testing.T) {
+	defer func(f func() (*debug.BuildInfo, bool)) { readBuildInfo = f }(readBuildInfo)
+	readBuildInfo = func() (*debug.BuildInfo, bool) {
+		return &debug.BuildInfo{
+			GoVersion: "go1.21.0",
+			Main:      debug.Module{Path: "example.com/prog", Version: "v0.1.0"},
+			Settings: []debug.BuildSetting{
+				{Key: "vcs.revision", Value: "abc123"},
+				{Key: "vcs.modified", Value: "true"},
+				{Key: "vcs.time", Value: "2023-06-01T12:00:00Z"},
+			},
+		}, true
+	}
+	var out bytes.Buffer
+	root := &Command{
+		Name:        "prog",
+		Stdout:      &out,
+		SubCommands: []*Command{VersionCmd},
+		VersionInfo: func() []debug.BuildSetting {
+			return []debug.BuildSetting{{Key: "host", Value: "builder"}}
+		},
+	}
+	ctx := context.Background()
+	if err := root.Run(ctx, []string{"version"}); err != nil {
+		t.Fatal(err)
+	}
+	want := `prog
+  version:   v0.1.0
+  revision:  abc123 (modified)
+  built:     2023-06-01T12:00:00Z
+  go:        go1.21.0
+  module:    example.com/prog
+  host:      builder
+`
+	if got := out.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	out.Reset()
+	root.Version = "1.2.3"
+	readBuildInfo = func() (*debug.BuildInfo, bool) { return nil, false }
+	root.VersionInfo = nil
+	if err := root.Run(ctx, []string{"version"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "prog\n  version:  1.2.3\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"errors"
+)
+
+// SkipSubCommands is used as a return value from the function passed to Walk
+// to indicate that the sub commands of the command are to be skipped.  It is
+// not returned as an error by Walk.
+var SkipSubCommands = errors.New("skip sub commands")
+
+// Walk calls fn for c and then for each of its sub commands, depth first, in
+// the order they are listed.  Hidden commands are included.  Sub commands
+// provided by SubCommandsFunc are loaded before fn is called.  A command
+// listed more than once in the tree, or that is its own sub command, is only
+// visited the first time.  Walk sets the parent of each command it visits so
+// fn may call methods, such as Command, that depend on it.
+//
+// If fn returns SkipSubCommands the sub commands of the command are not
+// visited.  Walk stops and returns any other error returned by fn.
+func (c *Command) Walk(fn func(*Command) error) error {
+	seen := map[*Command]bool{}
+	var walk func(*Command) error
+	walk = func(wc *Command) error {
+		if seen[wc] {
+			return nil
+		}
+		seen[wc] = true
+		wc.discover(context.Background())
+		switch err := fn(wc); err {
+		case nil:
+		case SkipSubCommands:
+			return nil
+		default:
+			return err
+		}
+		for _, sc := range wc.SubCommands {
+			if !seen[sc] {
+				sc.parent = wc
+			}
+			if err := walk(sc); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk(c)
+}
@@ -0,0 +1,78 @@
+// Copyright 2023 Paul Borman
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+
+package commander
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	shared := &Command{Name: "shared"}
+	root := &Command{
+		Name: "prog",
+		SubCommands: []*Command{
+			{Name: "a", SubCommands: []*Command{{Name: "a1"}, shared}},
+			{Name: "b", Hidden: true, SubCommands: []*Command{{Name: "b1"}}},
+			{Name: "c", SubCommandsFunc: func(context.Context) []*Command {
+				return []*Command{{Name: "c1"}, shared}
+			}},
+		},
+	}
+	walk := func(fn func(*Command) error) (string, error) {
+		var names []string
+		err := root.Walk(func(c *Command) error {
+			names = append(names, c.Command())
+			return fn(c)
+		})
+		return strings.Join(names, ","), err
+	}
+
+	got, err := walk(func(*Command) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "prog,prog a,prog a a1,prog a shared,prog b,prog b b1,prog c,prog c c1"; got != want {
+		t.Errorf("got %s\nwant %s", got, want)
+	}
+
+	got, err = walk(func(c *Command) error {
+		if c.Name == "a" || c.Name == "b" {
+			return SkipSubCommands
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "prog,prog a,prog b,prog c,prog c c1,prog c shared"; got != want {
+		t.Errorf("skip: got %s\nwant %s", got, want)
+	}
+
+	stop := errors.New("stop")
+	got, err = walk(func(c *Command) error {
+		if c.Name == "a1" {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Errorf("got error %v, want %v", err, stop)
+	}
+	if want := "prog,prog a,prog a a1"; got != want {
+		t.Errorf("stop: got %s\nwant %s", got, want)
+	}
+}